@@ -2,12 +2,19 @@ package main
 
 import (
 	"log"
+	"os"
 
+	"coderero.dev/iot/smaas-server/internal/config"
 	"coderero.dev/iot/smaas-server/internal/server"
 )
 
 func main() {
-	server := server.NewServer(1883)
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := server.NewServer(cfg)
 	if err := server.Start(); err != nil {
 		log.Fatal(err)
 	}
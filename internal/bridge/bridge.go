@@ -0,0 +1,440 @@
+package bridge
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+const (
+	// ClientId is the local client the upstream messages are published
+	// with, its messages are never mirrored back.
+	ClientId = "smaas-bridge"
+
+	DefaultBufferSize = 10000
+
+	publishTimeout = 10 * time.Second
+	// retryInterval between attempts to send the buffer, next to the
+	// attempts on every new message and reconnect
+	retryInterval = 5 * time.Second
+	// echoWindow is how long a forwarded message is expected back from the
+	// upstream
+	echoWindow = time.Minute
+)
+
+var errTimeout = errors.New("upstream did not acknowledge in time")
+
+type Options struct {
+	// URL of the upstream broker, e.g. tcp://central:1883 or
+	// ssl://central:8883.
+	URL      string
+	ClientId string
+	Username string
+	Password string
+	Rules    []Rule
+	// BufferSize messages are kept while the upstream is down, beyond that
+	// the oldest are dropped.
+	BufferSize int
+	Logger     func() *slog.Logger
+}
+
+// Status is the state of the bridge.
+type Status struct {
+	Upstream    string `json:"upstream"`
+	Connected   bool   `json:"connected"`
+	ConnectedAt string `json:"connected_at"`
+	LastError   string `json:"last_error"`
+	Forwarded   uint64 `json:"forwarded"`
+	Received    uint64 `json:"received"`
+	Buffered    int    `json:"buffered"`
+	Dropped     uint64 `json:"dropped"`
+}
+
+type message struct {
+	seq     uint64
+	topic   string
+	payload []byte
+	qos     byte
+	retain  bool
+	// echo is set when an incoming rule mirrors the topic back
+	echo bool
+}
+
+// echo counts the forwarded messages of a topic and payload that the
+// upstream has not sent back yet.
+type echo struct {
+	count int
+	at    time.Time
+}
+
+// Bridge connects to an upstream broker and mirrors the topics of its rules.
+// It is a hook of the local broker, which hands it the local messages to
+// mirror, and buffers them while the upstream is down.
+type Bridge struct {
+	mqtt.HookBase
+	options Options
+	server  *mqtt.Server
+	local   *mqtt.Client
+	client  paho.Client
+
+	mu       sync.Mutex
+	buffer   []message
+	seq      uint64
+	dropping bool
+	// echoes are the forwarded messages the upstream sends back through the
+	// incoming rules, as MQTT 3.1.1 has no no-local option, by echoKey
+	echoes map[string]*echo
+	// status
+	connectedAt time.Time
+	lastError   string
+
+	started atomic.Bool
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	forwarded atomic.Uint64
+	received  atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func New(server *mqtt.Server, options Options) *Bridge {
+	if options.BufferSize <= 0 {
+		options.BufferSize = DefaultBufferSize
+	}
+	if options.ClientId == "" {
+		options.ClientId = ClientId
+	}
+
+	b := &Bridge{
+		options: options,
+		server:  server,
+		local:   server.NewClient(nil, mqtt.LocalListener, ClientId, true),
+		echoes:  map[string]*echo{},
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	clientOptions := paho.NewClientOptions().
+		AddBroker(options.URL).
+		SetClientID(options.ClientId).
+		SetUsername(options.Username).
+		SetPassword(options.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5 * time.Second).
+		SetMaxReconnectInterval(time.Minute).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(b.onConnectionLost)
+	b.client = paho.NewClient(clientOptions)
+
+	return b
+}
+
+func (b *Bridge) ID() string {
+	return "smaas-bridge"
+}
+
+func (b *Bridge) Provides(p byte) bool {
+	return bytes.Contains([]byte{
+		mqtt.OnStarted,
+		mqtt.OnPublished,
+	}, []byte{p})
+}
+
+// OnStarted connects to the upstream broker in the background once the
+// local broker is serving, it keeps retrying until the bridge is stopped.
+func (b *Bridge) OnStarted() {
+	if b.started.Swap(true) {
+		return
+	}
+
+	b.client.Connect()
+	go b.run()
+}
+
+// Stop disconnects from the upstream broker when the local broker is
+// closed. Messages still buffered are lost.
+func (b *Bridge) Stop() error {
+	if !b.started.Load() {
+		return nil
+	}
+
+	close(b.stop)
+	<-b.done
+	b.client.Disconnect(250)
+
+	if buffered := b.Status().Buffered; buffered > 0 {
+		b.options.Logger().Warn("bridge stopped with buffered messages", slog.Int("buffered", buffered))
+	}
+	return nil
+}
+
+func (b *Bridge) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		Upstream:  b.options.URL,
+		Connected: b.client.IsConnectionOpen(),
+		LastError: b.lastError,
+		Forwarded: b.forwarded.Load(),
+		Received:  b.received.Load(),
+		Buffered:  len(b.buffer),
+		Dropped:   b.dropped.Load(),
+	}
+	if status.Connected {
+		status.ConnectedAt = b.connectedAt.UTC().Format(time.RFC3339)
+	}
+	return status
+}
+
+// OnPublished buffers the local messages of the outgoing rules.
+func (b *Bridge) OnPublished(cl *mqtt.Client, pk packets.Packet) {
+	// ignored messages were acknowledged but dropped, e.g. by the rate
+	// limiter
+	if cl.ID == ClientId || pk.Ignore {
+		return
+	}
+
+	for _, rule := range b.options.Rules {
+		if !rule.out() {
+			continue
+		}
+		topic, ok := rule.remote(pk.TopicName)
+		if !ok {
+			continue
+		}
+
+		b.enqueue(message{
+			topic:   topic,
+			payload: pk.Payload,
+			qos:     rule.QoS,
+			retain:  pk.FixedHeader.Retain,
+			echo:    b.mirroredIn(topic),
+		})
+		return
+	}
+}
+
+// mirroredIn reports whether an incoming rule mirrors the upstream topic.
+func (b *Bridge) mirroredIn(topic string) bool {
+	for _, rule := range b.options.Rules {
+		if _, ok := rule.local(topic); ok && rule.in() {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bridge) enqueue(m message) {
+	b.mu.Lock()
+	b.seq++
+	m.seq = b.seq
+	b.buffer = append(b.buffer, m)
+	if len(b.buffer) > b.options.BufferSize {
+		b.buffer = b.buffer[1:]
+		b.dropped.Add(1)
+		if !b.dropping {
+			b.dropping = true
+			b.options.Logger().Warn("bridge buffer is full, dropping messages", slog.Int("buffer_size", b.options.BufferSize))
+		}
+	}
+	b.mu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bridge) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-b.wake:
+			b.flush()
+		case <-ticker.C:
+			b.flush()
+			b.expireEchoes()
+		}
+	}
+}
+
+// flush sends the buffered messages in order while the upstream is
+// connected. A message that fails is kept for the next attempt.
+func (b *Bridge) flush() {
+	for b.client.IsConnectionOpen() {
+		b.mu.Lock()
+		if len(b.buffer) == 0 {
+			b.dropping = false
+			b.mu.Unlock()
+			return
+		}
+		m := b.buffer[0]
+		b.mu.Unlock()
+
+		// expected before the publish, the echo can arrive before the
+		// acknowledgement
+		if m.echo {
+			b.expectEcho(m.topic, m.payload)
+		}
+		token := b.client.Publish(m.topic, m.qos, m.retain, m.payload)
+		if !token.WaitTimeout(publishTimeout) {
+			b.setError(errTimeout)
+			return
+		}
+		if err := token.Error(); err != nil {
+			// expected again when the message is sent again
+			if m.echo {
+				b.isEcho(m.topic, m.payload)
+			}
+			b.setError(err)
+			return
+		}
+
+		b.mu.Lock()
+		// the message may have been dropped from a full buffer meanwhile
+		if len(b.buffer) > 0 && b.buffer[0].seq == m.seq {
+			b.buffer = b.buffer[1:]
+		}
+		b.mu.Unlock()
+		b.forwarded.Add(1)
+	}
+}
+
+func (b *Bridge) onConnect(client paho.Client) {
+	b.mu.Lock()
+	b.connectedAt = time.Now()
+	b.mu.Unlock()
+
+	b.options.Logger().Info("bridge connected", slog.String("upstream", b.options.URL))
+
+	for _, rule := range b.options.Rules {
+		if !rule.in() {
+			continue
+		}
+
+		rule := rule
+		token := client.Subscribe(rule.RemotePrefix+rule.Pattern, rule.QoS, func(_ paho.Client, m paho.Message) {
+			b.receive(rule, m)
+		})
+		if !token.WaitTimeout(publishTimeout) {
+			b.setError(errTimeout)
+		} else if err := token.Error(); err != nil {
+			b.setError(err)
+		}
+	}
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bridge) onConnectionLost(_ paho.Client, err error) {
+	b.setError(err)
+	b.options.Logger().Warn("bridge disconnected", slog.String("upstream", b.options.URL), slog.String("error", err.Error()))
+}
+
+// receive publishes an upstream message to the local broker, unless it is
+// the echo of a forwarded message.
+func (b *Bridge) receive(rule Rule, m paho.Message) {
+	topic, ok := rule.local(m.Topic())
+	if !ok || b.isEcho(m.Topic(), m.Payload()) {
+		return
+	}
+
+	pk := packets.Packet{
+		FixedHeader: packets.FixedHeader{
+			Type:   packets.Publish,
+			Qos:    m.Qos(),
+			Retain: m.Retained(),
+		},
+		TopicName: topic,
+		Payload:   m.Payload(),
+	}
+	// the broker never acknowledges the messages of an inline client, but
+	// still rejects messages with a QoS and without a packet id
+	if pk.FixedHeader.Qos > 0 {
+		pk.PacketID = 1
+	}
+
+	err := b.server.InjectPacket(b.local, pk)
+	if err != nil {
+		b.options.Logger().Error("failed to publish bridged message", slog.String("topic", topic), slog.String("error", err.Error()))
+		return
+	}
+	b.received.Add(1)
+}
+
+func echoKey(topic string, payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return topic + "\x00" + string(sum[:])
+}
+
+func (b *Bridge) expectEcho(topic string, payload []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := echoKey(topic, payload)
+	e, ok := b.echoes[key]
+	if !ok {
+		e = &echo{}
+		b.echoes[key] = e
+	}
+	e.count++
+	e.at = time.Now()
+}
+
+// isEcho reports whether the upstream message is a forwarded message coming
+// back, and stops expecting it.
+func (b *Bridge) isEcho(topic string, payload []byte) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := echoKey(topic, payload)
+	e, ok := b.echoes[key]
+	if !ok {
+		return false
+	}
+	e.count--
+	if e.count <= 0 {
+		delete(b.echoes, key)
+	}
+	return true
+}
+
+// expireEchoes stops expecting the echoes the upstream did not send, e.g.
+// as its ACL does not allow the subscription.
+func (b *Bridge) expireEchoes() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, e := range b.echoes {
+		if time.Since(e.at) > echoWindow {
+			delete(b.echoes, key)
+		}
+	}
+}
+
+func (b *Bridge) setError(err error) {
+	b.mu.Lock()
+	b.lastError = err.Error()
+	b.mu.Unlock()
+}
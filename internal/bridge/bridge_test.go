@@ -0,0 +1,338 @@
+package bridge
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// received collects the messages of an inline subscription.
+type received struct {
+	mu       sync.Mutex
+	messages []packets.Packet
+}
+
+func (r *received) handler(_ *mqtt.Client, _ packets.Subscription, pk packets.Packet) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, pk)
+}
+
+func (r *received) topics() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	topics := make([]string, 0, len(r.messages))
+	for _, pk := range r.messages {
+		topics = append(topics, pk.TopicName+" "+string(pk.Payload))
+	}
+	return topics
+}
+
+func newBroker(t *testing.T) *mqtt.Server {
+	t.Helper()
+
+	server := mqtt.New(&mqtt.Options{InlineClient: true, Logger: discard})
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server
+}
+
+// startUpstream serves an upstream broker on the address.
+func startUpstream(t *testing.T, address string) *mqtt.Server {
+	t.Helper()
+
+	upstream := newBroker(t)
+	if err := upstream.AddListener(listeners.NewTCP(listeners.Config{Type: "tcp", ID: "tcp", Address: address})); err != nil {
+		t.Fatal(err)
+	}
+	if err := upstream.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	return upstream
+}
+
+// startLocal serves a local broker bridged to the upstream address.
+func startLocal(t *testing.T, address string, rules string) (*mqtt.Server, *Bridge) {
+	t.Helper()
+
+	parsed, err := ParseRules(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := newBroker(t)
+	bridge := New(local, Options{
+		URL:    "tcp://" + address,
+		Rules:  parsed,
+		Logger: func() *slog.Logger { return discard },
+	})
+	if err := local.AddHook(bridge, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	return local, bridge
+}
+
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func subscribe(t *testing.T, server *mqtt.Server, filter string) *received {
+	t.Helper()
+
+	r := new(received)
+	if err := server.Subscribe(filter, 1, r.handler); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func waitFor(t *testing.T, timeout time.Duration, what string, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func waitConnected(t *testing.T, bridge *Bridge) {
+	t.Helper()
+	waitFor(t, 15*time.Second, "the bridge to connect", func() bool { return bridge.Status().Connected })
+}
+
+func TestBridgeOut(t *testing.T) {
+	address := freeAddress(t)
+	upstream := startUpstream(t, address)
+	remote := subscribe(t, upstream, "#")
+
+	local, bridge := startLocal(t, address, `arduino/+/climate out 1 "" homes/home1/`)
+	waitConnected(t, bridge)
+
+	if err := local.Publish("arduino/d1/climate", []byte("21.5"), false, 1); err != nil {
+		t.Fatal(err)
+	}
+	// not matched by the rule
+	if err := local.Publish("arduino/d1/ldr", []byte("300"), false, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, 5*time.Second, "the forwarded message", func() bool { return len(remote.topics()) > 0 })
+	time.Sleep(100 * time.Millisecond)
+
+	if got := remote.topics(); len(got) != 1 || got[0] != "homes/home1/arduino/d1/climate 21.5" {
+		t.Fatalf("upstream received %q, want the remapped climate message only", got)
+	}
+	if forwarded := bridge.Status().Forwarded; forwarded != 1 {
+		t.Fatalf("forwarded = %d, want 1", forwarded)
+	}
+}
+
+func TestBridgeIn(t *testing.T) {
+	address := freeAddress(t)
+	upstream := startUpstream(t, address)
+
+	local, bridge := startLocal(t, address, `arduino/+/command in 1 "" homes/home1/; # in 0 mirror/ other/`)
+	commands := subscribe(t, local, "arduino/+/command")
+	mirrored := subscribe(t, local, "mirror/#")
+	waitConnected(t, bridge)
+	// the subscriptions are made once connected
+	time.Sleep(100 * time.Millisecond)
+
+	if err := upstream.Publish("homes/home1/arduino/d1/command", []byte("reboot"), false, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := upstream.Publish("homes/home2/arduino/d2/command", []byte("reboot"), false, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := upstream.Publish("other/a/b", []byte("x"), false, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, 5*time.Second, "the incoming messages", func() bool {
+		return len(commands.topics()) > 0 && len(mirrored.topics()) > 0
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	if got := commands.topics(); len(got) != 1 || got[0] != "arduino/d1/command reboot" {
+		t.Fatalf("local received %q, want the command of home1 only", got)
+	}
+	if got := mirrored.topics(); len(got) != 1 || got[0] != "mirror/a/b x" {
+		t.Fatalf("local received %q, want the remapped message", got)
+	}
+	if received := bridge.Status().Received; received != 2 {
+		t.Fatalf("received = %d, want 2", received)
+	}
+}
+
+func TestBridgeBothDropsEchoes(t *testing.T) {
+	address := freeAddress(t)
+	upstream := startUpstream(t, address)
+	remote := subscribe(t, upstream, "homes/#")
+
+	local, bridge := startLocal(t, address, `state/# both 1 "" homes/home1/`)
+	states := subscribe(t, local, "state/#")
+	waitConnected(t, bridge)
+	time.Sleep(100 * time.Millisecond)
+
+	if err := local.Publish("state/d1/relay/1", []byte("on"), false, 1); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 5*time.Second, "the forwarded message", func() bool { return len(remote.topics()) > 0 })
+
+	if err := upstream.Publish("homes/home1/state/d1/relay/2", []byte("off"), false, 1); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 5*time.Second, "the incoming message", func() bool { return len(states.topics()) > 1 })
+	time.Sleep(200 * time.Millisecond)
+
+	want := []string{"state/d1/relay/1 on", "state/d1/relay/2 off"}
+	if got := states.topics(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("local received %q, want %q without the echo", got, want)
+	}
+	// the incoming message is not mirrored back out
+	if got := remote.topics(); len(got) != 2 {
+		t.Fatalf("upstream received %q, want the forwarded and the published message", got)
+	}
+}
+
+func TestBridgeBuffersWhileUpstreamIsDown(t *testing.T) {
+	address := freeAddress(t)
+
+	local, bridge := startLocal(t, address, `arduino/# out 1 "" homes/home1/`)
+	for _, payload := range []string{"1", "2", "3"} {
+		if err := local.Publish("arduino/d1/climate", []byte(payload), false, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, 5*time.Second, "the buffered messages", func() bool { return bridge.Status().Buffered == 3 })
+	if bridge.Status().Connected {
+		t.Fatal("bridge is connected without an upstream")
+	}
+
+	upstream := startUpstream(t, address)
+	remote := subscribe(t, upstream, "#")
+	waitConnected(t, bridge)
+	waitFor(t, 10*time.Second, "the buffer to be sent", func() bool { return len(remote.topics()) == 3 })
+
+	want := []string{"homes/home1/arduino/d1/climate 1", "homes/home1/arduino/d1/climate 2", "homes/home1/arduino/d1/climate 3"}
+	got := remote.topics()
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("upstream received %q, want %q in order", got, want)
+		}
+	}
+	if status := bridge.Status(); status.Buffered != 0 || status.Forwarded != 3 {
+		t.Fatalf("buffered = %d, forwarded = %d, want 0 and 3", status.Buffered, status.Forwarded)
+	}
+}
+
+func TestBridgeDropsOldestWhenBufferIsFull(t *testing.T) {
+	local := newBroker(t)
+	parsed, err := ParseRules(`# out 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bridge := New(local, Options{
+		URL:        "tcp://" + freeAddress(t),
+		Rules:      parsed,
+		BufferSize: 2,
+		Logger:     func() *slog.Logger { return discard },
+	})
+	if err := local.AddHook(bridge, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bridge is not started, so nothing is sent
+	for _, payload := range []string{"1", "2", "3"} {
+		bridge.OnPublished(&mqtt.Client{ID: "device"}, packets.Packet{TopicName: "a", Payload: []byte(payload)})
+	}
+
+	if status := bridge.Status(); status.Buffered != 2 || status.Dropped != 1 {
+		t.Fatalf("buffered = %d, dropped = %d, want 2 and 1", status.Buffered, status.Dropped)
+	}
+	if first := string(bridge.buffer[0].payload); first != "2" {
+		t.Fatalf("first buffered message is %q, want the oldest dropped", first)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		rules string
+		want  []Rule
+		err   bool
+	}{
+		{rules: "", want: nil},
+		{
+			rules: `arduino/+/climate out 1 "" homes/home1/; state/# both 0 local/`,
+			want: []Rule{
+				{Pattern: "arduino/+/climate", Direction: Out, QoS: 1, RemotePrefix: "homes/home1/"},
+				{Pattern: "state/#", Direction: Both, QoS: 0, LocalPrefix: "local/"},
+			},
+		},
+		{rules: "a sideways 1", err: true},
+		{rules: "a in 3", err: true},
+		{rules: "a in", err: true},
+		{rules: "a in 1 b c d", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseRules(tt.rules)
+		if (err != nil) != tt.err {
+			t.Errorf("ParseRules(%q) error = %v, want error %v", tt.rules, err, tt.err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParseRules(%q) = %+v, want %+v", tt.rules, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParseRules(%q)[%d] = %+v, want %+v", tt.rules, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRuleTopics(t *testing.T) {
+	rule := Rule{Pattern: "arduino/+/climate", Direction: Both, LocalPrefix: "site/", RemotePrefix: "homes/home1/"}
+
+	if topic, ok := rule.remote("site/arduino/d1/climate"); !ok || topic != "homes/home1/arduino/d1/climate" {
+		t.Errorf("remote = %q, %v", topic, ok)
+	}
+	if _, ok := rule.remote("arduino/d1/climate"); ok {
+		t.Error("remote matched a topic without the local prefix")
+	}
+	if topic, ok := rule.local("homes/home1/arduino/d1/climate"); !ok || topic != "site/arduino/d1/climate" {
+		t.Errorf("local = %q, %v", topic, ok)
+	}
+	if _, ok := rule.local("homes/home2/arduino/d1/climate"); ok {
+		t.Error("local matched a topic of another remote prefix")
+	}
+}
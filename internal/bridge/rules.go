@@ -0,0 +1,106 @@
+package bridge
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"coderero.dev/iot/smaas-server/internal/hooks"
+)
+
+type Direction string
+
+const (
+	// Out mirrors local messages to the upstream broker.
+	Out Direction = "out"
+	// In mirrors upstream messages to the local broker.
+	In Direction = "in"
+	// Both mirrors the messages in both directions.
+	Both Direction = "both"
+)
+
+// Rule mirrors the topics matching Pattern, like the topic option of a
+// mosquitto bridge: the local topic is LocalPrefix followed by the topic and
+// the upstream topic is RemotePrefix followed by the topic.
+type Rule struct {
+	Pattern      string
+	Direction    Direction
+	QoS          byte
+	LocalPrefix  string
+	RemotePrefix string
+}
+
+func (r Rule) out() bool {
+	return r.Direction == Out || r.Direction == Both
+}
+
+func (r Rule) in() bool {
+	return r.Direction == In || r.Direction == Both
+}
+
+// remote returns the upstream topic of a local topic, or false when the rule
+// does not mirror it.
+func (r Rule) remote(topic string) (string, bool) {
+	if !hooks.MatchFilter(r.LocalPrefix+r.Pattern, topic) {
+		return "", false
+	}
+	return r.RemotePrefix + strings.TrimPrefix(topic, r.LocalPrefix), true
+}
+
+// local returns the local topic of an upstream topic, or false when the rule
+// does not mirror it.
+func (r Rule) local(topic string) (string, bool) {
+	if !hooks.MatchFilter(r.RemotePrefix+r.Pattern, topic) {
+		return "", false
+	}
+	return r.LocalPrefix + strings.TrimPrefix(topic, r.RemotePrefix), true
+}
+
+// ParseRules parses rules separated by semicolons, each written as
+// "{pattern} {in|out|both} {qos} [{local prefix} [{remote prefix}]]", e.g.
+// "arduino/+/climate out 1 \"\" homes/home1/". Empty prefixes are written
+// as "".
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, line := range strings.Split(s, ";") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 3 || len(fields) > 5 {
+			return nil, fmt.Errorf("invalid bridge rule %q", strings.TrimSpace(line))
+		}
+
+		rule := Rule{
+			Pattern:   fields[0],
+			Direction: Direction(fields[1]),
+		}
+		if rule.Direction != Out && rule.Direction != In && rule.Direction != Both {
+			return nil, fmt.Errorf("invalid direction %q of bridge rule %q", fields[1], strings.TrimSpace(line))
+		}
+
+		qos, err := strconv.Atoi(fields[2])
+		if err != nil || qos < 0 || qos > 2 {
+			return nil, fmt.Errorf("invalid qos %q of bridge rule %q", fields[2], strings.TrimSpace(line))
+		}
+		rule.QoS = byte(qos)
+
+		if len(fields) > 3 {
+			rule.LocalPrefix = unquote(fields[3])
+		}
+		if len(fields) > 4 {
+			rule.RemotePrefix = unquote(fields[4])
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+func unquote(s string) string {
+	if s == `""` {
+		return ""
+	}
+	return s
+}
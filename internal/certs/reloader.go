@@ -0,0 +1,147 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a certificate and client CA that are read again when
+// their files change, so renewed certificates are used without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	// logger is called for every message as the app logger is only set up
+	// once the app is bootstrapped
+	logger func() *slog.Logger
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+	modTime time.Time
+}
+
+// NewReloader loads the certificate and key. An empty caFile disables
+// client certificates.
+func NewReloader(certFile string, keyFile string, caFile string, logger func() *slog.Logger) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		logger:   logger,
+	}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// TLSConfig returns the config of the listener. Clients may present a
+// certificate signed by the client CA, those without one still log in with
+// a password.
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.reload()
+
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+			}
+			if r.pool != nil {
+				config.ClientCAs = r.pool
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+			return config, nil
+		},
+	}
+}
+
+// reload loads the files again when one of them changed. A broken renewal
+// keeps the previous certificate.
+func (r *Reloader) reload() {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		r.logger().Error("failed to stat certificates", slog.String("error", err.Error()))
+		return
+	}
+
+	r.mu.Lock()
+	changed := modTime.After(r.modTime)
+	r.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := r.load(); err != nil {
+		r.logger().Error("failed to reload certificates", slog.String("error", err.Error()))
+		return
+	}
+
+	r.logger().Info("reloaded certificates", slog.String("cert", r.certFile))
+}
+
+func (r *Reloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.pool = pool
+	r.modTime = modTime
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	if latest.IsZero() {
+		return latest, errors.New("no certificate files")
+	}
+	return latest, nil
+}
@@ -0,0 +1,57 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const BrokerStateCollectionName = "broker_state"
+
+// BrokerState persists the sessions, subscriptions, retained and inflight
+// messages of the MQTT broker across restarts. value holds the entry as
+// encoded by the broker storage types.
+type BrokerState struct {
+	ID      string         `json:"id"`
+	Key     string         `json:"key"`
+	Kind    string         `json:"kind"`
+	Value   map[string]any `json:"value"`
+	Updated string         `json:"updated"`
+}
+
+func (*BrokerState) Name() string {
+	return BrokerStateCollectionName
+}
+
+func (*BrokerState) Schema() *core.Collection {
+	collection := core.NewBaseCollection(BrokerStateCollectionName, BrokerStateCollectionName)
+	// only the broker and superusers read the broker state
+	collection.ListRule = nil
+	collection.ViewRule = nil
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	collection.Fields.Add(
+		&core.TextField{
+			Name:     "key",
+			Required: true,
+		},
+		&core.TextField{
+			Name:     "kind",
+			Required: true,
+		},
+		&core.JSONField{
+			Name:    "value",
+			MaxSize: 1 << 20,
+		},
+		&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		},
+	)
+
+	collection.AddIndex("idx_broker_state_key", true, "key", "")
+	collection.AddIndex("idx_broker_state_kind", false, "kind", "")
+
+	return collection
+}
@@ -0,0 +1,102 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const DeviceCommandsCollectionName = "device_commands"
+
+const (
+	CommandReboot       = "reboot"
+	CommandIdentify     = "identify"
+	CommandHealthReport = "health_report"
+	CommandConfigDump   = "config_dump"
+	CommandSetLogLevel  = "set_log_level"
+)
+
+const (
+	CommandSent         = "sent"
+	CommandAcknowledged = "acknowledged"
+	CommandSucceeded    = "succeeded"
+	CommandFailed       = "failed"
+	CommandTimedOut     = "timed_out"
+)
+
+type DeviceCommands struct {
+	ID        string         `json:"id"`
+	Device    string         `json:"device"`
+	User      string         `json:"user"`
+	Command   string         `json:"command"`
+	Params    map[string]any `json:"params"`
+	Status    string         `json:"status"`
+	Result    map[string]any `json:"result"`
+	Error     string         `json:"error"`
+	Timestamp string         `json:"timestamp"`
+	Updated   string         `json:"updated"`
+}
+
+func (*DeviceCommands) Name() string {
+	return DeviceCommandsCollectionName
+}
+
+func (*DeviceCommands) Schema() *core.Collection {
+	collection := core.NewBaseCollection(DeviceCommandsCollectionName, DeviceCommandsCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	// commands are sent through the commands endpoint
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		// the user who sent the command
+		&core.RelationField{
+			CollectionId: "_pb_users_auth_",
+			Name:         "user",
+			MaxSelect:    1,
+		},
+		&core.SelectField{
+			Name:      "command",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{CommandReboot, CommandIdentify, CommandHealthReport, CommandConfigDump, CommandSetLogLevel},
+		},
+		&core.JSONField{
+			Name: "params",
+		},
+		&core.SelectField{
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{CommandSent, CommandAcknowledged, CommandSucceeded, CommandFailed, CommandTimedOut},
+		},
+		&core.JSONField{
+			Name: "result",
+		},
+		&core.TextField{
+			Name: "error",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+		&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		},
+	)
+
+	collection.AddIndex("idx_device_commands_device", false, "device, timestamp", "")
+
+	return collection
+}
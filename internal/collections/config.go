@@ -17,6 +17,7 @@ type ClimateConfig struct {
 	SensorId   string `json:"sensor_id"`
 	Lable      string `json:"lable"`
 	Device     string `json:"device"`
+	Room       string `json:"room"`
 	Dht22Port  int    `json:"dht22_port"`
 	AQIPort    int    `json:"aqi_port"`
 	HasBuzzer  bool   `json:"has_buzzer"`
@@ -30,15 +31,11 @@ func (*ClimateConfig) Name() string {
 
 func (*ClimateConfig) Schema() *core.Collection {
 	collection := core.NewBaseCollection(ClimateConfigCollectionName, ClimateConfigCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.user = @request.auth.id")
-	collection.UpdateRule = types.Pointer(`
-        @request.auth.id != '' &&
-		@request.auth.id = device.user.id &&
-        (@request.body.user:isset = false || @request.body.user = @request.auth.id)
-    `)
-	collection.DeleteRule = types.Pointer(`@request.auth.id != '' && @request.auth.id = device.user.id`)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.CreateRule = types.Pointer(dataCreateRule())
+	collection.UpdateRule = types.Pointer(dataUpdateRule() + " && " + roomRule())
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
 
 	collection.Fields.Add(
 		&core.RelationField{
@@ -59,6 +56,11 @@ func (*ClimateConfig) Schema() *core.Collection {
 			Name:     "lable",
 			Required: true,
 		},
+		&core.RelationField{
+			CollectionId: RoomsCollectionName,
+			Name:         "room",
+			MaxSelect:    1,
+		},
 		&core.NumberField{
 			Name:    "dht22_port",
 			OnlyInt: true,
@@ -87,6 +89,7 @@ type LDRConfig struct {
 	SensorId string `json:"sensor_id"`
 	Lable    string `json:"lable"`
 	Device   string `json:"device"`
+	Room     string `json:"room"`
 	Port     int    `json:"port"`
 }
 
@@ -96,15 +99,11 @@ func (*LDRConfig) Name() string {
 
 func (*LDRConfig) Schema() *core.Collection {
 	collection := core.NewBaseCollection(LDRConfigCollectionName, LDRConfigCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.user = @request.auth.id")
-	collection.UpdateRule = types.Pointer(`
-        @request.auth.id != '' &&
-		@request.auth.id = device.user.id &&
-        (@request.body.user:isset = false || @request.body.user = @request.auth.id)
-    `)
-	collection.DeleteRule = types.Pointer(`@request.auth.id != '' && @request.auth.id = device.user.id`)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.CreateRule = types.Pointer(dataCreateRule())
+	collection.UpdateRule = types.Pointer(dataUpdateRule() + " && " + roomRule())
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
 
 	collection.Fields.Add(
 		&core.RelationField{
@@ -125,6 +124,11 @@ func (*LDRConfig) Schema() *core.Collection {
 			Name:     "lable",
 			Required: true,
 		},
+		&core.RelationField{
+			CollectionId: RoomsCollectionName,
+			Name:         "room",
+			MaxSelect:    1,
+		},
 		&core.NumberField{
 			Name:    "port",
 			OnlyInt: true,
@@ -139,6 +143,7 @@ type MotionConfig struct {
 	SensorId  string `json:"sensor_id"`
 	Lable     string `json:"lable"`
 	Device    string `json:"device"`
+	Room      string `json:"room"`
 	Port      int    `json:"port"`
 	RelayType int    `json:"relay_type"`
 	RelayPort int    `json:"relay_port"`
@@ -150,15 +155,11 @@ func (*MotionConfig) Name() string {
 
 func (*MotionConfig) Schema() *core.Collection {
 	collection := core.NewBaseCollection(MotionConfigCollectionName, MotionConfigCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.user = @request.auth.id")
-	collection.UpdateRule = types.Pointer(`
-        @request.auth.id != '' &&
-		@request.auth.id = device.user.id &&
-        (@request.body.user:isset = false || @request.body.user = @request.auth.id)
-    `)
-	collection.DeleteRule = types.Pointer(`@request.auth.id != '' && @request.auth.id = device.user.id`)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.CreateRule = types.Pointer(dataCreateRule())
+	collection.UpdateRule = types.Pointer(dataUpdateRule() + " && " + roomRule())
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
 
 	collection.Fields.Add(
 		&core.RelationField{
@@ -179,6 +180,11 @@ func (*MotionConfig) Schema() *core.Collection {
 			Name:     "lable",
 			Required: true,
 		},
+		&core.RelationField{
+			CollectionId: RoomsCollectionName,
+			Name:         "room",
+			MaxSelect:    1,
+		},
 		&core.NumberField{
 			Name:    "port",
 			OnlyInt: true,
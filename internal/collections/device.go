@@ -13,14 +13,34 @@ const (
 	MotionCollectionName          = "motion"
 	RelayCollectionName           = "relay"
 	UserPortLablesCollectionName  = "user_port_lables"
+	RelayEventsCollectionName     = "relay_events"
+)
+
+const (
+	PayloadProtobuf = "protobuf"
+	PayloadJSON     = "json"
 )
 
 type Devices struct {
-	ID           string `json:"id"`
-	User         string `json:"user"`
-	DeviceName   string `json:"device_name"`
-	DeviceStatus string `json:"device_status"`
-	Timestamp    string `json:"timestamp"`
+	ID              string         `json:"id"`
+	User            string         `json:"user"`
+	DeviceName      string         `json:"device_name"`
+	DeviceStatus    string         `json:"device_status"`
+	Room            string         `json:"room"`
+	Hardware        string         `json:"hardware"`
+	FirmwareVersion string         `json:"firmware_version"`
+	Health          map[string]any `json:"health"`
+	LastSeen        string         `json:"last_seen"`
+	DeletedAt       string         `json:"deleted_at"`
+	ResetNonce      string         `json:"reset_nonce"`
+	ResetAcked      string         `json:"reset_acknowledged"`
+	ProtocolVersion int            `json:"protocol_version"`
+	Capabilities    map[string]any `json:"capabilities"`
+	PayloadFormat   string         `json:"payload_format"`
+	RateLimitCount  int            `json:"rate_limit_incidents"`
+	RateLimitedAt   string         `json:"rate_limited_at"`
+	RateLimitReason string         `json:"rate_limit_reason"`
+	Timestamp       string         `json:"timestamp"`
 }
 
 func (*Devices) Name() string {
@@ -29,16 +49,11 @@ func (*Devices) Name() string {
 
 func (*Devices) Schema() *core.Collection {
 	collection := core.NewBaseCollection(DevicesCollectionName, DevicesCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = user")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = user")
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = user")
-	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.user = @request.auth.id")
-	collection.UpdateRule = types.Pointer(`
-        @request.auth.id != '' &&
-		@request.auth.id = user &&
-        (@request.body.user:isset = false || @request.body.user = @request.auth.id)
-    `)
-	collection.DeleteRule = types.Pointer(`@request.auth.id != '' && @request.auth.id = user`)
+	collection.ListRule = types.Pointer(deviceRule("", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("", viewRoles))
+	collection.CreateRule = types.Pointer(deviceCreateRule())
+	collection.UpdateRule = types.Pointer(deviceUpdateRule())
+	collection.DeleteRule = types.Pointer(deviceRule("", ownerRoles))
 
 	collection.Fields.Add(
 		&core.RelationField{
@@ -57,6 +72,64 @@ func (*Devices) Schema() *core.Collection {
 			Name:     "device_status",
 			Required: true,
 		},
+		&core.RelationField{
+			CollectionId: RoomsCollectionName,
+			Name:         "room",
+			MaxSelect:    1,
+		},
+		// the board model, firmware is only rolled out to matching devices
+		&core.TextField{
+			Name: "hardware",
+		},
+		// reported by the device after a successful update
+		&core.TextField{
+			Name: "firmware_version",
+		},
+		// the latest heartbeat of the device
+		&core.JSONField{
+			Name: "health",
+		},
+		&core.DateField{
+			Name: "last_seen",
+		},
+		// set while the device is decommissioned and can still be restored
+		&core.DateField{
+			Name: "deleted_at",
+		},
+		// the nonce of the factory reset command, echoed by the device
+		&core.TextField{
+			Name:   "reset_nonce",
+			Hidden: true,
+		},
+		&core.DateField{
+			Name: "reset_acknowledged",
+		},
+		// announced by the device on connect, 0 for boards that never did
+		&core.NumberField{
+			Name:    "protocol_version",
+			OnlyInt: true,
+		},
+		// the sensors, relay modules and features the device supports
+		&core.JSONField{
+			Name: "capabilities",
+		},
+		// the encoding of the device messages, protobuf when empty
+		&core.SelectField{
+			Name:      "payload_format",
+			MaxSelect: 1,
+			Values:    []string{PayloadProtobuf, PayloadJSON},
+		},
+		// the messages of the device dropped by the broker rate limits
+		&core.NumberField{
+			Name:    "rate_limit_incidents",
+			OnlyInt: true,
+		},
+		&core.DateField{
+			Name: "rate_limited_at",
+		},
+		&core.TextField{
+			Name: "rate_limit_reason",
+		},
 		&core.AutodateField{
 			Name:     "timestamp",
 			OnCreate: true,
@@ -80,15 +153,11 @@ func (*WifiCredentials) Name() string {
 
 func (*WifiCredentials) Schema() *core.Collection {
 	collection := core.NewBaseCollection(WifiCredentialsCollectionName, WifiCredentialsCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.user = @request.auth.id")
-	collection.UpdateRule = types.Pointer(`
-		@request.auth.id != '' &&
-		@request.auth.id = device.user &&
-		(@request.body.user:isset = false || @request.body.user = @request.auth.id)
-	`)
-	collection.DeleteRule = types.Pointer(`@request.auth.id != '' && @request.auth.id = device.user.id`)
+	collection.ListRule = types.Pointer(deviceRule("device.", manageRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", manageRoles))
+	collection.CreateRule = types.Pointer(dataCreateRule())
+	collection.UpdateRule = types.Pointer(dataUpdateRule())
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
 
 	collection.Fields.Add(
 		&core.RelationField{
@@ -128,15 +197,11 @@ func (*Climate) Name() string {
 
 func (*Climate) Schema() *core.Collection {
 	collection := core.NewBaseCollection(ClimateCollectionName, ClimateCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.user = @request.auth.id")
-	collection.UpdateRule = types.Pointer(`
-        @request.auth.id != '' &&
-		@request.auth.id = device.user &&
-		(@request.body.user:isset = false || @request.body.user = @request.auth.id)
-    `)
-	collection.DeleteRule = types.Pointer(`@request.auth.id != '' && @request.auth.id = device.user.id`)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.CreateRule = types.Pointer(dataCreateRule())
+	collection.UpdateRule = types.Pointer(dataUpdateRule())
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
 
 	collection.Fields.Add(
 		&core.RelationField{
@@ -188,15 +253,11 @@ func (*LDR) Name() string {
 
 func (*LDR) Schema() *core.Collection {
 	collection := core.NewBaseCollection(LDRCollectionName, LDRCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.user = @request.auth.id")
-	collection.UpdateRule = types.Pointer(`
-        @request.auth.id != '' &&
-		@request.auth.id = device.user &&
-		(@request.body.user:isset = false || @request.body.user = @request.auth.id)
-    `)
-	collection.DeleteRule = types.Pointer(`@request.auth.id != '' && @request.auth.id = device.user.id`)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.CreateRule = types.Pointer(dataCreateRule())
+	collection.UpdateRule = types.Pointer(dataUpdateRule())
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
 
 	collection.Fields.Add(
 		&core.RelationField{
@@ -265,13 +326,17 @@ func (*Relay) Schema() *core.Collection {
 }
 
 type UserPortLables struct {
-	ID        string `json:"id"`
-	Device    string `json:"device"`
-	Relay     string `json:"relay"`
-	Port      int    `json:"port"`
-	State     bool   `json:"state"`
-	Lable     string `json:"lable"`
-	Timestamp string `json:"timestamp"`
+	ID        string  `json:"id"`
+	Device    string  `json:"device"`
+	Relay     string  `json:"relay"`
+	Port      int     `json:"port"`
+	State     bool    `json:"state"`
+	Lable     string  `json:"lable"`
+	Watts     float64 `json:"watts"`
+	Runtime   int     `json:"runtime"`
+	LastOn    string  `json:"last_on"`
+	Room      string  `json:"room"`
+	Timestamp string  `json:"timestamp"`
 }
 
 func (*UserPortLables) Name() string {
@@ -280,10 +345,10 @@ func (*UserPortLables) Name() string {
 
 func (*UserPortLables) Schema() *core.Collection {
 	collection := core.NewBaseCollection(UserPortLablesCollectionName, UserPortLablesCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
 	collection.CreateRule = types.Pointer("@request.auth.isAdmin = true")
-	collection.UpdateRule = types.Pointer("@request.auth.isAdmin = true")
+	collection.UpdateRule = types.Pointer(portUpdateRule())
 	collection.DeleteRule = types.Pointer("@request.auth.isAdmin = true")
 
 	collection.Fields.Add(
@@ -316,6 +381,24 @@ func (*UserPortLables) Schema() *core.Collection {
 			Name:     "lable",
 			Required: true,
 		},
+		// rated power of the appliance connected to the port
+		&core.NumberField{
+			Name: "watts",
+			Min:  types.Pointer(0.0),
+		},
+		// accumulated on-time in seconds
+		&core.NumberField{
+			Name:    "runtime",
+			OnlyInt: true,
+		},
+		&core.DateField{
+			Name: "last_on",
+		},
+		&core.RelationField{
+			CollectionId: RoomsCollectionName,
+			Name:         "room",
+			MaxSelect:    1,
+		},
 		&core.AutodateField{
 			Name:     "timestamp",
 			OnCreate: true,
@@ -324,3 +407,61 @@ func (*UserPortLables) Schema() *core.Collection {
 
 	return collection
 }
+
+type RelayEvents struct {
+	ID        string `json:"id"`
+	Device    string `json:"device"`
+	Port      string `json:"port"`
+	State     bool   `json:"state"`
+	Origin    string `json:"origin"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (*RelayEvents) Name() string {
+	return RelayEventsCollectionName
+}
+
+func (*RelayEvents) Schema() *core.Collection {
+	collection := core.NewBaseCollection(RelayEventsCollectionName, RelayEventsCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.CreateRule = types.Pointer("@request.auth.isAdmin = true")
+	collection.UpdateRule = types.Pointer("@request.auth.isAdmin = true")
+	collection.DeleteRule = types.Pointer("@request.auth.isAdmin = true")
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.RelationField{
+			CollectionId:  UserPortLablesCollectionName,
+			Name:          "port",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.BoolField{
+			Name: "state",
+		},
+		&core.SelectField{
+			Name:      "origin",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{"user", "device"},
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	collection.AddIndex("idx_relay_events_port", false, "port, timestamp", "")
+
+	return collection
+}
@@ -0,0 +1,232 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	FirmwareCollectionName    = "firmware"
+	OtaRolloutsCollectionName = "ota_rollouts"
+	OtaUpdatesCollectionName  = "ota_updates"
+)
+
+const (
+	RolloutDraft     = "draft"
+	RolloutRunning   = "running"
+	RolloutPaused    = "paused"
+	RolloutHalted    = "halted"
+	RolloutCompleted = "completed"
+)
+
+const (
+	OtaPending     = "pending"
+	OtaDownloading = "downloading"
+	OtaInstalling  = "installing"
+	OtaSucceeded   = "succeeded"
+	OtaFailed      = "failed"
+	OtaCancelled   = "cancelled"
+)
+
+type Firmware struct {
+	ID        string `json:"id"`
+	Version   string `json:"version"`
+	Hardware  string `json:"hardware"`
+	File      string `json:"file"`
+	Size      int    `json:"size"`
+	SHA256    string `json:"sha256"`
+	Notes     string `json:"notes"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (*Firmware) Name() string {
+	return FirmwareCollectionName
+}
+
+func (*Firmware) Schema() *core.Collection {
+	collection := core.NewBaseCollection(FirmwareCollectionName, FirmwareCollectionName)
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	// firmware is uploaded by superusers only
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	collection.Fields.Add(
+		&core.TextField{
+			Name:     "version",
+			Required: true,
+		},
+		&core.TextField{
+			Name:     "hardware",
+			Required: true,
+		},
+		// the boards download the file without a token
+		&core.FileField{
+			Name:      "file",
+			Required:  true,
+			MaxSelect: 1,
+			MaxSize:   8 << 20,
+		},
+		&core.NumberField{
+			Name:    "size",
+			OnlyInt: true,
+		},
+		&core.TextField{
+			Name:    "sha256",
+			Pattern: "^[a-f0-9]{64}$",
+		},
+		&core.TextField{
+			Name: "notes",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	collection.AddIndex("idx_firmware_version", true, "hardware, version", "")
+
+	return collection
+}
+
+// OtaRollouts send a firmware to the listed devices, or when none are listed
+// to a percentage of the devices with the hardware of the firmware.
+type OtaRollouts struct {
+	ID               string   `json:"id"`
+	Firmware         string   `json:"firmware"`
+	Devices          []string `json:"devices"`
+	Percentage       int      `json:"percentage"`
+	FailureThreshold int      `json:"failure_threshold"`
+	Status           string   `json:"status"`
+	Timestamp        string   `json:"timestamp"`
+}
+
+func (*OtaRollouts) Name() string {
+	return OtaRolloutsCollectionName
+}
+
+func (*OtaRollouts) Schema() *core.Collection {
+	collection := core.NewBaseCollection(OtaRolloutsCollectionName, OtaRolloutsCollectionName)
+	// rollouts are managed by superusers only
+	collection.ListRule = nil
+	collection.ViewRule = nil
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  FirmwareCollectionName,
+			Name:          "firmware",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.RelationField{
+			CollectionId: DevicesCollectionName,
+			Name:         "devices",
+			MaxSelect:    1000,
+		},
+		&core.NumberField{
+			Name:    "percentage",
+			OnlyInt: true,
+			Min:     types.Pointer(0.0),
+			Max:     types.Pointer(100.0),
+		},
+		// percentage of failed updates among the succeeded and failed ones
+		// above which the rollout is halted, 20 when 0 on create
+		&core.NumberField{
+			Name:    "failure_threshold",
+			OnlyInt: true,
+			Min:     types.Pointer(0.0),
+			Max:     types.Pointer(100.0),
+		},
+		&core.SelectField{
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{RolloutDraft, RolloutRunning, RolloutPaused, RolloutHalted, RolloutCompleted},
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	return collection
+}
+
+// OtaUpdates track the update of a single device of a rollout.
+type OtaUpdates struct {
+	ID        string `json:"id"`
+	Rollout   string `json:"rollout"`
+	Device    string `json:"device"`
+	Status    string `json:"status"`
+	Progress  int    `json:"progress"`
+	Error     string `json:"error"`
+	Timestamp string `json:"timestamp"`
+	Updated   string `json:"updated"`
+}
+
+func (*OtaUpdates) Name() string {
+	return OtaUpdatesCollectionName
+}
+
+func (*OtaUpdates) Schema() *core.Collection {
+	collection := core.NewBaseCollection(OtaUpdatesCollectionName, OtaUpdatesCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	// updates are reported by the devices
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  OtaRolloutsCollectionName,
+			Name:          "rollout",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.SelectField{
+			Name:      "status",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{OtaPending, OtaDownloading, OtaInstalling, OtaSucceeded, OtaFailed, OtaCancelled},
+		},
+		&core.NumberField{
+			Name:    "progress",
+			OnlyInt: true,
+			Min:     types.Pointer(0.0),
+			Max:     types.Pointer(100.0),
+		},
+		&core.TextField{
+			Name: "error",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+		&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		},
+	)
+
+	collection.AddIndex("idx_ota_updates_device", true, "rollout, device", "")
+
+	return collection
+}
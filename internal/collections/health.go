@@ -0,0 +1,165 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	DeviceHealthCollectionName = "device_health"
+	DeviceAlertsCollectionName = "device_alerts"
+)
+
+const (
+	AlertLowRSSI         = "low_rssi"
+	AlertLowMemory       = "low_memory"
+	AlertFrequentReboots = "frequent_reboots"
+)
+
+// DeviceHealth is the history of the heartbeats of the devices, the latest
+// one is also kept on the device record.
+type DeviceHealth struct {
+	ID               string `json:"id"`
+	Device           string `json:"device"`
+	Uptime           int    `json:"uptime"`
+	FreeHeap         int    `json:"free_heap"`
+	RSSI             int    `json:"rssi"`
+	FirmwareVersion  string `json:"firmware_version"`
+	ResetReason      string `json:"reset_reason"`
+	MessagesSent     int    `json:"messages_sent"`
+	MessagesReceived int    `json:"messages_received"`
+	Reconnects       int    `json:"reconnects"`
+	Rebooted         bool   `json:"rebooted"`
+	Timestamp        string `json:"timestamp"`
+}
+
+func (*DeviceHealth) Name() string {
+	return DeviceHealthCollectionName
+}
+
+func (*DeviceHealth) Schema() *core.Collection {
+	collection := core.NewBaseCollection(DeviceHealthCollectionName, DeviceHealthCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	// heartbeats are only written by the server
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		// seconds since the board booted
+		&core.NumberField{
+			Name:    "uptime",
+			OnlyInt: true,
+		},
+		// bytes
+		&core.NumberField{
+			Name:    "free_heap",
+			OnlyInt: true,
+		},
+		// dBm
+		&core.NumberField{
+			Name:    "rssi",
+			OnlyInt: true,
+		},
+		&core.TextField{
+			Name: "firmware_version",
+		},
+		&core.TextField{
+			Name: "reset_reason",
+		},
+		&core.NumberField{
+			Name:    "messages_sent",
+			OnlyInt: true,
+		},
+		&core.NumberField{
+			Name:    "messages_received",
+			OnlyInt: true,
+		},
+		&core.NumberField{
+			Name:    "reconnects",
+			OnlyInt: true,
+		},
+		// the uptime went back since the previous heartbeat
+		&core.BoolField{
+			Name: "rebooted",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	collection.AddIndex("idx_device_health_device", false, "device, timestamp", "")
+
+	return collection
+}
+
+// DeviceAlerts are raised when a device is struggling. An alert is resolved
+// once the device recovers, a new one is raised when it struggles again.
+type DeviceAlerts struct {
+	ID        string  `json:"id"`
+	Device    string  `json:"device"`
+	Kind      string  `json:"kind"`
+	Message   string  `json:"message"`
+	Value     float64 `json:"value"`
+	Resolved  string  `json:"resolved"`
+	Timestamp string  `json:"timestamp"`
+}
+
+func (*DeviceAlerts) Name() string {
+	return DeviceAlertsCollectionName
+}
+
+func (*DeviceAlerts) Schema() *core.Collection {
+	collection := core.NewBaseCollection(DeviceAlertsCollectionName, DeviceAlertsCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	// alerts are only raised by the server
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.SelectField{
+			Name:      "kind",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{AlertLowRSSI, AlertLowMemory, AlertFrequentReboots},
+		},
+		&core.TextField{
+			Name:     "message",
+			Required: true,
+		},
+		&core.NumberField{
+			Name: "value",
+		},
+		&core.DateField{
+			Name: "resolved",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	collection.AddIndex("idx_device_alerts_device", false, "device, kind, resolved", "")
+
+	return collection
+}
@@ -0,0 +1,93 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	HomesCollectionName = "homes"
+	RoomsCollectionName = "rooms"
+)
+
+type Homes struct {
+	ID        string `json:"id"`
+	Owner     string `json:"owner"`
+	HomeName  string `json:"name"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (*Homes) Name() string {
+	return HomesCollectionName
+}
+
+func (*Homes) Schema() *core.Collection {
+	collection := core.NewBaseCollection(HomesCollectionName, HomesCollectionName)
+	collection.ListRule = types.Pointer(homeRule("", viewRoles))
+	collection.ViewRule = types.Pointer(homeRule("", viewRoles))
+	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.owner = @request.auth.id")
+	collection.UpdateRule = types.Pointer(homeRule("", manageRoles) + " && (@request.body.owner:isset = false || @request.body.owner = owner)")
+	collection.DeleteRule = types.Pointer(homeRule("", ownerRoles))
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  "_pb_users_auth_",
+			Name:          "owner",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.TextField{
+			Name:     "name",
+			Required: true,
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	return collection
+}
+
+type Rooms struct {
+	ID        string `json:"id"`
+	Home      string `json:"home"`
+	RoomName  string `json:"name"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (*Rooms) Name() string {
+	return RoomsCollectionName
+}
+
+func (*Rooms) Schema() *core.Collection {
+	collection := core.NewBaseCollection(RoomsCollectionName, RoomsCollectionName)
+	collection.ListRule = types.Pointer(homeRule("home.", viewRoles))
+	collection.ViewRule = types.Pointer(homeRule("home.", viewRoles))
+	collection.CreateRule = types.Pointer(homeRule("@request.body.home.", manageRoles))
+	collection.UpdateRule = types.Pointer(homeRule("home.", manageRoles) + " && (@request.body.home:isset = false || (" + homeRule("@request.body.home.", manageRoles) + "))")
+	collection.DeleteRule = types.Pointer(homeRule("home.", manageRoles))
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  HomesCollectionName,
+			Name:          "home",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.TextField{
+			Name:     "name",
+			Required: true,
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	return collection
+}
@@ -0,0 +1,173 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	MembershipsCollectionName = "memberships"
+	InvitationsCollectionName = "invitations"
+)
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationDeclined = "declined"
+)
+
+// Memberships grant a user a role on either a device or a home. A home
+// membership applies to every device placed in the home.
+type Memberships struct {
+	ID        string `json:"id"`
+	User      string `json:"user"`
+	Device    string `json:"device"`
+	Home      string `json:"home"`
+	Role      string `json:"role"`
+	Expires   string `json:"expires"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (*Memberships) Name() string {
+	return MembershipsCollectionName
+}
+
+func (*Memberships) Schema() *core.Collection {
+	collection := core.NewBaseCollection(MembershipsCollectionName, MembershipsCollectionName)
+	collection.ListRule = types.Pointer("@request.auth.id = user || " + targetRule("", manageRoles))
+	collection.ViewRule = types.Pointer("@request.auth.id = user || " + targetRule("", manageRoles))
+	// memberships are only created by accepting an invitation
+	collection.CreateRule = nil
+	collection.UpdateRule = types.Pointer(targetRule("", ownerRoles) + ` &&
+		@request.body.user:isset = false &&
+		@request.body.device:isset = false &&
+		@request.body.home:isset = false
+	`)
+	// users may leave, admins remove members and guests, owners anyone
+	collection.DeleteRule = types.Pointer(`@request.auth.id = user ||
+		(` + targetRule("", manageRoles) + ` && role != '` + RoleOwner + `' && role != '` + RoleAdmin + `') ||
+		` + targetRule("", ownerRoles))
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  "_pb_users_auth_",
+			Name:          "user",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			MaxSelect:     1,
+		},
+		&core.RelationField{
+			CollectionId:  HomesCollectionName,
+			Name:          "home",
+			CascadeDelete: true,
+			MaxSelect:     1,
+		},
+		&core.SelectField{
+			Name:      "role",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{RoleOwner, RoleAdmin, RoleMember, RoleGuest},
+		},
+		&core.DateField{
+			Name: "expires",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	collection.AddIndex("idx_memberships_target", true, "user, device, home", "")
+
+	return collection
+}
+
+type Invitations struct {
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	Device        string `json:"device"`
+	Home          string `json:"home"`
+	Role          string `json:"role"`
+	Status        string `json:"status"`
+	InvitedBy     string `json:"invited_by"`
+	Expires       string `json:"expires"`
+	AccessExpires string `json:"access_expires"`
+	Timestamp     string `json:"timestamp"`
+}
+
+func (*Invitations) Name() string {
+	return InvitationsCollectionName
+}
+
+func (*Invitations) Schema() *core.Collection {
+	collection := core.NewBaseCollection(InvitationsCollectionName, InvitationsCollectionName)
+	collection.ListRule = types.Pointer("(email = @request.auth.email && @request.auth.verified = true) || invited_by = @request.auth.id || " + targetRule("", manageRoles))
+	collection.ViewRule = types.Pointer("(email = @request.auth.email && @request.auth.verified = true) || invited_by = @request.auth.id || " + targetRule("", manageRoles))
+	collection.CreateRule = types.Pointer(`
+		@request.body.invited_by = @request.auth.id &&
+		@request.body.status:isset = false &&
+		(@request.body.role != 'owner' || ` + targetRule("@request.body.", ownerRoles) + `) &&
+		` + targetRule("@request.body.", manageRoles))
+	// invitations are answered through the accept and decline endpoints
+	collection.UpdateRule = nil
+	collection.DeleteRule = types.Pointer("invited_by = @request.auth.id || " + targetRule("", manageRoles))
+
+	collection.Fields.Add(
+		&core.EmailField{
+			Name:     "email",
+			Required: true,
+		},
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			MaxSelect:     1,
+		},
+		&core.RelationField{
+			CollectionId:  HomesCollectionName,
+			Name:          "home",
+			CascadeDelete: true,
+			MaxSelect:     1,
+		},
+		&core.SelectField{
+			Name:      "role",
+			Required:  true,
+			MaxSelect: 1,
+			Values:    []string{RoleOwner, RoleAdmin, RoleMember, RoleGuest},
+		},
+		&core.SelectField{
+			Name:      "status",
+			MaxSelect: 1,
+			Values:    []string{InvitationPending, InvitationAccepted, InvitationDeclined},
+		},
+		&core.RelationField{
+			CollectionId:  "_pb_users_auth_",
+			Name:          "invited_by",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		// until when the invitation can be accepted
+		&core.DateField{
+			Name: "expires",
+		},
+		// until when the granted membership is valid, required for guests
+		&core.DateField{
+			Name: "access_expires",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	return collection
+}
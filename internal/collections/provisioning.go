@@ -0,0 +1,72 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const ProvisioningCollectionName = "provisioning"
+
+// Provisioning holds the boards made at the factory. The claim code is printed
+// on the board and binds it to the user claiming it, the password is the MQTT
+// password issued to the board once it is claimed.
+type Provisioning struct {
+	ID          string `json:"id"`
+	Serial      string `json:"serial"`
+	ClaimCode   string `json:"claim_code"`
+	Device      string `json:"device"`
+	Password    string `json:"password"`
+	AnnouncedAt string `json:"announced_at"`
+	ClaimedAt   string `json:"claimed_at"`
+	Timestamp   string `json:"timestamp"`
+}
+
+func (*Provisioning) Name() string {
+	return ProvisioningCollectionName
+}
+
+func (*Provisioning) Schema() *core.Collection {
+	collection := core.NewBaseCollection(ProvisioningCollectionName, ProvisioningCollectionName)
+	// boards are generated from the command line and claimed through
+	// the claim endpoint only
+	collection.ListRule = nil
+	collection.ViewRule = nil
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	collection.Fields.Add(
+		&core.TextField{
+			Name:     "serial",
+			Required: true,
+		},
+		&core.PasswordField{
+			Name:     "claim_code",
+			Required: true,
+			Hidden:   true,
+		},
+		&core.RelationField{
+			CollectionId: DevicesCollectionName,
+			Name:         "device",
+			MaxSelect:    1,
+		},
+		&core.PasswordField{
+			Name:   "password",
+			Hidden: true,
+		},
+		&core.DateField{
+			Name: "announced_at",
+		},
+		&core.DateField{
+			Name: "claimed_at",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	collection.AddIndex("idx_provisioning_serial", true, "serial", "")
+	collection.AddIndex("idx_provisioning_device", false, "device", "")
+
+	return collection
+}
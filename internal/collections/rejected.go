@@ -0,0 +1,99 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	RejectedMessagesCollectionName      = "rejected_messages"
+	RejectedMessageCountsCollectionName = "rejected_message_counts"
+)
+
+type RejectedMessages struct {
+	ID        string `json:"id"`
+	Device    string `json:"device"`
+	SensorID  int    `json:"sensor_id"`
+	Topic     string `json:"topic"`
+	Payload   string `json:"payload"`
+	Reason    string `json:"reason"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (*RejectedMessages) Name() string {
+	return RejectedMessagesCollectionName
+}
+
+func (*RejectedMessages) Schema() *core.Collection {
+	collection := core.NewBaseCollection(RejectedMessagesCollectionName, RejectedMessagesCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.CreateRule = types.Pointer("@request.auth.isAdmin = true")
+	collection.UpdateRule = types.Pointer("@request.auth.isAdmin = true")
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
+
+	collection.Fields.Add(
+		// not required, messages published for unknown devices are kept too
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			MaxSelect:     1,
+		},
+		&core.NumberField{
+			Name:    "sensor_id",
+			OnlyInt: true,
+		},
+		&core.TextField{
+			Name:     "topic",
+			Required: true,
+		},
+		// base64 encoded raw payload
+		&core.TextField{
+			Name: "payload",
+		},
+		&core.TextField{
+			Name:     "reason",
+			Required: true,
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	collection.AddIndex("idx_rejected_messages_device", false, "device, sensor_id", "")
+
+	return collection
+}
+
+type RejectedMessageCounts struct {
+	ID           string `json:"id"`
+	Device       string `json:"device"`
+	SensorID     int    `json:"sensor_id"`
+	Total        int    `json:"total"`
+	LastRejected string `json:"last_rejected"`
+}
+
+func (*RejectedMessageCounts) Name() string {
+	return RejectedMessageCountsCollectionName
+}
+
+func (*RejectedMessageCounts) Schema() *core.Collection {
+	collection := core.NewViewCollection(RejectedMessageCountsCollectionName, RejectedMessageCountsCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewQuery = `
+		SELECT
+			(ROW_NUMBER() OVER()) as id,
+			device,
+			sensor_id,
+			COUNT(*) as total,
+			MAX(timestamp) as last_rejected
+		FROM rejected_messages
+		WHERE device != ''
+		GROUP BY device, sensor_id
+	`
+
+	return collection
+}
@@ -0,0 +1,173 @@
+package collections
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleGuest  = "guest"
+)
+
+var (
+	// viewRoles may see device data and switch relay ports.
+	viewRoles = []string{RoleOwner, RoleAdmin, RoleMember, RoleGuest}
+	// manageRoles may change configuration and invite other users.
+	manageRoles = []string{RoleOwner, RoleAdmin}
+	// ownerRoles may delete devices and homes and change memberships.
+	ownerRoles = []string{RoleOwner}
+)
+
+// memberRule matches an unexpired membership of the authenticated user with
+// one of the roles. field is the membership relation ("device" or "home") and
+// target the expression holding the id of the device or home. An empty target,
+// e.g. a device without a room, never matches the memberships of the other
+// kind whose relation is empty as well.
+func memberRule(field string, target string, roles []string) string {
+	alias := "@collection." + MembershipsCollectionName + ":" + field
+
+	matches := make([]string, len(roles))
+	for i, role := range roles {
+		matches[i] = fmt.Sprintf("%s.role ?= '%s'", alias, role)
+	}
+
+	return fmt.Sprintf(
+		"(%[3]s != '' && %[1]s.user ?= @request.auth.id && %[1]s.%[2]s ?= %[3]s && (%[4]s) && (%[1]s.expires ?= '' || %[1]s.expires ?> @now))",
+		alias, field, target, strings.Join(matches, " || "),
+	)
+}
+
+// deviceRule matches the device owner, the owner of the home the device is
+// placed in and the members of the device or of its home with one of the
+// roles. device is the path to the device record: "" for the devices
+// collection, "device." for device data and "@request.body.device." for the
+// device in a create or update request.
+func deviceRule(device string, roles []string) string {
+	id := "id"
+	if device != "" {
+		id = strings.TrimSuffix(device, ".")
+	}
+
+	return fmt.Sprintf(`@request.auth.id != '' && (
+		@request.auth.id = %[1]suser ||
+		@request.auth.id = %[1]sroom.home.owner ||
+		%[2]s ||
+		%[3]s
+	)`, device, memberRule("device", id, roles), memberRule("home", device+"room.home", roles))
+}
+
+// homeRule matches the home owner and the members of the home with one of
+// the roles. home is the path to the home record, "" for the homes collection.
+func homeRule(home string, roles []string) string {
+	id := "id"
+	if home != "" {
+		id = strings.TrimSuffix(home, ".")
+	}
+
+	return fmt.Sprintf(
+		"@request.auth.id != '' && (@request.auth.id = %sowner || %s)",
+		home, memberRule("home", id, roles),
+	)
+}
+
+// dataCreateRule allows creating device data for devices the user manages.
+func dataCreateRule() string {
+	return deviceRule("@request.body.device.", manageRoles)
+}
+
+// dataUpdateRule allows changing device data of devices the user manages,
+// without moving it to a device the user does not manage.
+func dataUpdateRule() string {
+	return deviceRule("device.", manageRoles) + ` &&
+		(@request.body.device:isset = false || (` + deviceRule("@request.body.device.", manageRoles) + `))`
+}
+
+// roomRule allows assigning a record only to a room of a home the user manages.
+func roomRule() string {
+	return "(@request.body.room:isset = false || @request.body.room = '' || (" + homeRule("@request.body.room.home.", manageRoles) + "))"
+}
+
+// deviceServerFields are the device fields only the server sets.
+var deviceServerFields = []string{
+	"firmware_version",
+	"health",
+	"last_seen",
+	"deleted_at",
+	"reset_nonce",
+	"reset_acknowledged",
+	"protocol_version",
+	"capabilities",
+	"rate_limit_incidents",
+	"rate_limited_at",
+	"rate_limit_reason",
+}
+
+// unsetRule matches requests that do not set any of the fields.
+func unsetRule(fields []string) string {
+	rules := make([]string, len(fields))
+	for i, field := range fields {
+		rules[i] = "@request.body." + field + ":isset = false"
+	}
+	return strings.Join(rules, " &&\n\t\t")
+}
+
+// deviceCreateRule lets users create their own devices, in the rooms of
+// the homes they manage.
+func deviceCreateRule() string {
+	return `@request.auth.id != '' &&
+		@request.body.user = @request.auth.id &&
+		` + unsetRule(deviceServerFields) + ` &&
+		` + roomRule()
+}
+
+func deviceUpdateRule() string {
+	return deviceRule("", manageRoles) + ` &&
+		(@request.body.user:isset = false || @request.body.user = user) &&
+		` + unsetRule(deviceServerFields) + ` &&
+		` + roomRule()
+}
+
+// portUpdateRule lets every member switch relay ports, while renaming and
+// placing them is left to managers and the wiring and accounting fields stay
+// server managed.
+func portUpdateRule() string {
+	return deviceRule("device.", viewRoles) + ` &&
+		@request.body.device:isset = false &&
+		@request.body.relay:isset = false &&
+		@request.body.port:isset = false &&
+		@request.body.runtime:isset = false &&
+		@request.body.last_on:isset = false &&
+		(
+			(@request.body.lable:isset = false && @request.body.watts:isset = false && @request.body.room:isset = false) ||
+			(` + deviceRule("device.", manageRoles) + `)
+		) &&
+		` + roomRule()
+}
+
+// targetRule matches the users with one of the roles on the device or home
+// a membership or an invitation points to. prefix is "" for stored records
+// and "@request.body." for create requests.
+func targetRule(prefix string, roles []string) string {
+	return fmt.Sprintf(
+		"((%[1]sdevice != '' && %[2]s) || (%[1]shome != '' && %[3]s))",
+		prefix, deviceRule(prefix+"device.", roles), homeRule(prefix+"home.", roles),
+	)
+}
+
+// sensorCreateRule is dataCreateRule for generic sensors, the mirrors of
+// the legacy sensor configs are only created by the server.
+func sensorCreateRule() string {
+	return dataCreateRule() + " && @request.body.legacy_config:isset = false && " + roomRule()
+}
+
+// sensorUpdateRule is dataUpdateRule for generic sensors. Sensors mirrored
+// from a legacy sensor config are changed through that config.
+func sensorUpdateRule() string {
+	return dataUpdateRule() + ` &&
+		legacy_config = '' &&
+		@request.body.legacy_config:isset = false &&
+		` + roomRule()
+}
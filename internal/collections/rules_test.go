@@ -0,0 +1,96 @@
+package collections
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/core"
+	// the system migrations, which pocketbase.New registers
+	_ "github.com/pocketbase/pocketbase/migrations"
+)
+
+var errRollback = errors.New("rollback")
+
+func TestCreateRules(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	for _, c := range []CollectionDefiner{&Homes{}, &Rooms{}, &Devices{}, &Memberships{}, &Sensors{}} {
+		if err := app.SaveNoValidate(c.Schema()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := func(collection *core.Collection, fields map[string]any) *core.Record {
+		t.Helper()
+		record := core.NewRecord(collection)
+		record.Load(fields)
+		if err := app.SaveNoValidate(record); err != nil {
+			t.Fatal(err)
+		}
+		return record
+	}
+
+	owner := save(users, map[string]any{"email": "owner@example.com"})
+	other := save(users, map[string]any{"email": "other@example.com"})
+	home := save((&Homes{}).Schema(), map[string]any{"owner": owner.Id, "name": "home"})
+	room := save((&Rooms{}).Schema(), map[string]any{"home": home.Id, "name": "hall"})
+	ownDevice := save((&Devices{}).Schema(), map[string]any{"user": other.Id, "device_name": "own"})
+
+	tests := []struct {
+		name       string
+		collection CollectionDefiner
+		auth       *core.Record
+		body       map[string]any
+		want       bool
+	}{
+		{name: "device", collection: &Devices{}, auth: other, body: map[string]any{"user": other.Id}, want: true},
+		{name: "device in own room", collection: &Devices{}, auth: owner, body: map[string]any{"user": owner.Id, "room": room.Id}, want: true},
+		{name: "device in foreign room", collection: &Devices{}, auth: other, body: map[string]any{"user": other.Id, "room": room.Id}},
+		{name: "device of another user", collection: &Devices{}, auth: other, body: map[string]any{"user": owner.Id}},
+		{name: "device with health", collection: &Devices{}, auth: other, body: map[string]any{"user": other.Id, "health": map[string]any{}}},
+		{name: "device decommissioned", collection: &Devices{}, auth: other, body: map[string]any{"user": other.Id, "deleted_at": "2026-01-01 00:00:00.000Z"}},
+		{name: "device with capabilities", collection: &Devices{}, auth: other, body: map[string]any{"user": other.Id, "capabilities": map[string]any{}}},
+		{name: "sensor", collection: &Sensors{}, auth: other, body: map[string]any{"device": ownDevice.Id}, want: true},
+		{name: "sensor in foreign room", collection: &Sensors{}, auth: other, body: map[string]any{"device": ownDevice.Id, "room": room.Id}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection, err := app.FindCollectionByNameOrId(tt.collection.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			// like the API, the record is checked inside the transaction
+			// creating it, which is rolled back
+			var got bool
+			app.RunInTransaction(func(txApp core.App) error {
+				record := core.NewRecord(collection)
+				record.Load(tt.body)
+				if err := txApp.SaveNoValidate(record); err != nil {
+					t.Fatal(err)
+				}
+
+				got, err = txApp.CanAccessRecord(record, &core.RequestInfo{
+					Method: "POST",
+					Auth:   tt.auth,
+					Body:   tt.body,
+				}, collection.CreateRule)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return errRollback
+			})
+			if got != tt.want {
+				t.Errorf("create allowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -24,16 +24,11 @@ func (*Security) Name() string {
 
 func (*Security) Schema() *core.Collection {
 	collection := core.NewBaseCollection(SecurityCollectionName, SecurityCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.CreateRule = types.Pointer("@request.auth.id != '' && @request.body.user = @request.auth.id")
-	collection.UpdateRule = types.Pointer(`
-        @request.auth.id != '' &&
-		@request.auth.id = device.user.id &&
-        (@request.body.user:isset = false || @request.body.user = @request.auth.id)
-    `)
-	collection.DeleteRule = types.Pointer(`@request.auth.id != '' && @request.auth.id = device.user`)
+	collection.ListRule = types.Pointer(deviceRule("device.", manageRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", manageRoles))
+	collection.CreateRule = types.Pointer(dataCreateRule())
+	collection.UpdateRule = types.Pointer(dataUpdateRule())
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
 
 	collection.Fields.Add(
 		&core.RelationField{
@@ -72,8 +67,8 @@ func (*SecurityLogs) Name() string {
 
 func (*SecurityLogs) Schema() *core.Collection {
 	collection := core.NewBaseCollection(SecurityLogsCollectionName, SecurityLogsCollectionName)
-	collection.ListRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
-	collection.ViewRule = types.Pointer("@request.auth.id != '' && @request.auth.id = device.user.id")
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
 	collection.CreateRule = types.Pointer("@request.auth.isAdmin = true")
 	collection.UpdateRule = types.Pointer("@request.auth.isAdmin = true")
 	collection.DeleteRule = types.Pointer("@request.auth.isAdmin = true")
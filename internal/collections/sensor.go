@@ -0,0 +1,254 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	SensorTypesCollectionName = "sensor_types"
+	SensorsCollectionName     = "sensors"
+	ReadingsCollectionName    = "readings"
+)
+
+// SensorMetric is a value a sensor type reports, with its valid range.
+type SensorMetric struct {
+	Name string  `json:"name"`
+	Unit string  `json:"unit"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+// SensorPort is a port a sensor of the type is wired to.
+type SensorPort struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+}
+
+// SensorTypes is the catalog of the sensors the server understands. Adding
+// a sensor type is a record, the readings are validated against its metrics
+// and the sensor configs against its ports.
+type SensorTypes struct {
+	ID       string         `json:"id"`
+	TypeName string         `json:"name"`
+	Label    string         `json:"label"`
+	Metrics  []SensorMetric `json:"metrics"`
+	Ports    []SensorPort   `json:"ports"`
+}
+
+// DefaultSensorTypes are added to the catalog on startup when missing. The
+// first three are the sensors of the legacy config collections.
+var DefaultSensorTypes = []SensorTypes{
+	{
+		TypeName: "climate",
+		Label:    "Climate (DHT22 and MQ135)",
+		Metrics: []SensorMetric{
+			{Name: "temperature", Unit: "°C", Min: -40, Max: 80},
+			{Name: "humidity", Unit: "%", Min: 0, Max: 100},
+			{Name: "air_quality", Unit: "AQI", Min: 0, Max: 500},
+		},
+		Ports: []SensorPort{
+			{Name: "dht22", Required: true},
+			{Name: "aqi", Required: true},
+			{Name: "buzzer"},
+		},
+	},
+	{
+		TypeName: "ldr",
+		Label:    "Light (LDR)",
+		Metrics:  []SensorMetric{{Name: "light", Unit: "raw", Min: 0, Max: 1023}},
+		Ports:    []SensorPort{{Name: "signal", Required: true}},
+	},
+	{
+		TypeName: "motion",
+		Label:    "Motion (PIR)",
+		Metrics:  []SensorMetric{{Name: "motion", Min: 0, Max: 1}},
+		Ports: []SensorPort{
+			{Name: "signal", Required: true},
+			{Name: "relay", Required: true},
+		},
+	},
+	{
+		TypeName: "soil_moisture",
+		Label:    "Soil moisture",
+		Metrics:  []SensorMetric{{Name: "moisture", Unit: "%", Min: 0, Max: 100}},
+		Ports:    []SensorPort{{Name: "signal", Required: true}},
+	},
+	{
+		TypeName: "current",
+		Label:    "Current (ACS712)",
+		Metrics:  []SensorMetric{{Name: "current", Unit: "A", Min: 0, Max: 30}},
+		Ports:    []SensorPort{{Name: "signal", Required: true}},
+	},
+}
+
+func (*SensorTypes) Name() string {
+	return SensorTypesCollectionName
+}
+
+func (*SensorTypes) Schema() *core.Collection {
+	collection := core.NewBaseCollection(SensorTypesCollectionName, SensorTypesCollectionName)
+	collection.ListRule = types.Pointer("@request.auth.id != ''")
+	collection.ViewRule = types.Pointer("@request.auth.id != ''")
+	collection.CreateRule = types.Pointer("@request.auth.isAdmin = true")
+	collection.UpdateRule = types.Pointer("@request.auth.isAdmin = true")
+	collection.DeleteRule = types.Pointer("@request.auth.isAdmin = true")
+
+	collection.Fields.Add(
+		&core.TextField{
+			Name:     "name",
+			Required: true,
+			Pattern:  `^[a-z0-9_]+$`,
+		},
+		&core.TextField{
+			Name:     "label",
+			Required: true,
+		},
+		&core.JSONField{
+			Name:     "metrics",
+			Required: true,
+		},
+		&core.JSONField{
+			Name: "ports",
+		},
+	)
+
+	collection.AddIndex("idx_sensor_types_name", true, "name", "")
+
+	return collection
+}
+
+// Sensors configure the sensors of every type in the catalog. Sensors
+// configured through the climate, LDR and motion config collections are
+// mirrored here with the id of their config in legacy_config.
+type Sensors struct {
+	ID           string             `json:"id"`
+	Device       string             `json:"device"`
+	Type         string             `json:"type"`
+	SensorId     int                `json:"sensor_id"`
+	Lable        string             `json:"lable"`
+	Room         string             `json:"room"`
+	Ports        map[string]int     `json:"ports"`
+	Settings     map[string]float64 `json:"settings"`
+	LegacyConfig string             `json:"legacy_config"`
+}
+
+func (*Sensors) Name() string {
+	return SensorsCollectionName
+}
+
+func (*Sensors) Schema() *core.Collection {
+	collection := core.NewBaseCollection(SensorsCollectionName, SensorsCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.CreateRule = types.Pointer(sensorCreateRule())
+	collection.UpdateRule = types.Pointer(sensorUpdateRule())
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles) + " && legacy_config = ''")
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.RelationField{
+			CollectionId: SensorTypesCollectionName,
+			Name:         "type",
+			Required:     true,
+			MinSelect:    1,
+			MaxSelect:    1,
+		},
+		&core.NumberField{
+			Name:     "sensor_id",
+			Required: true,
+			OnlyInt:  true,
+		},
+		&core.TextField{
+			Name:     "lable",
+			Required: true,
+		},
+		&core.RelationField{
+			CollectionId: RoomsCollectionName,
+			Name:         "room",
+			MaxSelect:    1,
+		},
+		// port name of the sensor type => port on the device
+		&core.JSONField{
+			Name: "ports",
+		},
+		&core.JSONField{
+			Name: "settings",
+		},
+		&core.TextField{
+			Name: "legacy_config",
+		},
+	)
+
+	collection.AddIndex("idx_sensors_device_type_sensor", true, "device, type, sensor_id", "")
+
+	return collection
+}
+
+// Readings store one value per metric of every sensor reading. The type is
+// the name of the sensor type, not a relation, as readings are written far
+// more often than they are expanded.
+type Readings struct {
+	ID        string  `json:"id"`
+	Device    string  `json:"device"`
+	Type      string  `json:"type"`
+	SensorId  int     `json:"sensor_id"`
+	Metric    string  `json:"metric"`
+	Value     float64 `json:"value"`
+	Timestamp string  `json:"timestamp"`
+}
+
+func (*Readings) Name() string {
+	return ReadingsCollectionName
+}
+
+func (*Readings) Schema() *core.Collection {
+	collection := core.NewBaseCollection(ReadingsCollectionName, ReadingsCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	// readings are only written by the server
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = types.Pointer(deviceRule("device.", manageRoles))
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.TextField{
+			Name:     "type",
+			Required: true,
+		},
+		&core.NumberField{
+			Name:    "sensor_id",
+			OnlyInt: true,
+		},
+		&core.TextField{
+			Name:     "metric",
+			Required: true,
+		},
+		&core.NumberField{
+			Name: "value",
+		},
+		&core.AutodateField{
+			Name:     "timestamp",
+			OnCreate: true,
+		},
+	)
+
+	collection.AddIndex("idx_readings_sensor", false, "device, type, sensor_id, timestamp", "")
+
+	return collection
+}
@@ -0,0 +1,75 @@
+package collections
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const DeviceShadowsCollectionName = "device_shadows"
+
+// DeviceShadows hold the state a device should have and the state it last
+// reported. The desired state follows the relay ports and sensor configs,
+// the delta is what the device still has to apply.
+type DeviceShadows struct {
+	ID              string         `json:"id"`
+	Device          string         `json:"device"`
+	Desired         map[string]any `json:"desired"`
+	Reported        map[string]any `json:"reported"`
+	Delta           map[string]any `json:"delta"`
+	Version         int            `json:"version"`
+	ReportedVersion int            `json:"reported_version"`
+	Updated         string         `json:"updated"`
+}
+
+func (*DeviceShadows) Name() string {
+	return DeviceShadowsCollectionName
+}
+
+func (*DeviceShadows) Schema() *core.Collection {
+	collection := core.NewBaseCollection(DeviceShadowsCollectionName, DeviceShadowsCollectionName)
+	collection.ListRule = types.Pointer(deviceRule("device.", viewRoles))
+	collection.ViewRule = types.Pointer(deviceRule("device.", viewRoles))
+	// shadows are only written by the server
+	collection.CreateRule = nil
+	collection.UpdateRule = nil
+	collection.DeleteRule = nil
+
+	collection.Fields.Add(
+		&core.RelationField{
+			CollectionId:  DevicesCollectionName,
+			Name:          "device",
+			CascadeDelete: true,
+			Required:      true,
+			MinSelect:     1,
+			MaxSelect:     1,
+		},
+		&core.JSONField{
+			Name: "desired",
+		},
+		&core.JSONField{
+			Name: "reported",
+		},
+		&core.JSONField{
+			Name: "delta",
+		},
+		// raised on every change of the desired state
+		&core.NumberField{
+			Name:    "version",
+			OnlyInt: true,
+		},
+		// the version the device last applied
+		&core.NumberField{
+			Name:    "reported_version",
+			OnlyInt: true,
+		},
+		&core.AutodateField{
+			Name:     "updated",
+			OnCreate: true,
+			OnUpdate: true,
+		},
+	)
+
+	collection.AddIndex("idx_device_shadows_device", true, "device", "")
+
+	return collection
+}
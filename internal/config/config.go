@@ -0,0 +1,501 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/bridge"
+	"github.com/joho/godotenv"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile is read when no config file is given and it exists.
+const DefaultFile = "smaas.yaml"
+
+// redacted replaces the secrets in the printed config.
+const redacted = "********"
+
+// Config is the configuration of the server. The defaults are overridden by
+// the config file, the file by the environment and the environment by the
+// command line flags. Fields with an env tag are read from that variable.
+type Config struct {
+	HTTP            HTTP          `yaml:"http"`
+	Admin           Admin         `yaml:"admin"`
+	Broker          Broker        `yaml:"broker"`
+	Ingest          Ingest        `yaml:"ingest"`
+	Retention       Retention     `yaml:"retention"`
+	Metrics         Metrics       `yaml:"metrics"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+}
+
+type HTTP struct {
+	// Address of the HTTP server, the --http flag of serve when empty.
+	Address string `yaml:"address" env:"HTTP_ADDRESS"`
+}
+
+// Admin is the superuser created on the first start.
+type Admin struct {
+	Email    string `yaml:"email" env:"ADMIN_EMAIL"`
+	Password string `yaml:"password" env:"ADMIN_PASSWORD" secret:"true"`
+}
+
+type Broker struct {
+	LogLevel   string     `yaml:"log_level" env:"MQTT_LOG_LEVEL"`
+	Listeners  Listeners  `yaml:"listeners"`
+	Auth       Auth       `yaml:"auth"`
+	RateLimits RateLimits `yaml:"rate_limits"`
+	Bridge     Bridge     `yaml:"bridge"`
+}
+
+type Listeners struct {
+	TCP       TCPListener       `yaml:"tcp"`
+	TLS       TLSListener       `yaml:"tls"`
+	WebSocket WebSocketListener `yaml:"websocket"`
+}
+
+// TCPListener is the plaintext listener, disabled when Port is 0, e.g. when
+// devices only connect over TLS.
+type TCPListener struct {
+	Port int `yaml:"port" env:"MQTT_PORT"`
+}
+
+// TLSListener is disabled when Port is 0. With ClientCA devices can log in
+// with a client certificate.
+type TLSListener struct {
+	Port     int    `yaml:"port" env:"MQTT_TLS_PORT"`
+	Cert     string `yaml:"cert" env:"MQTT_TLS_CERT"`
+	Key      string `yaml:"key" env:"MQTT_TLS_KEY"`
+	ClientCA string `yaml:"client_ca" env:"MQTT_TLS_CLIENT_CA"`
+}
+
+// WebSocketListener serves MQTT over WebSockets on the HTTP server.
+type WebSocketListener struct {
+	Enabled bool   `yaml:"enabled" env:"MQTT_WEBSOCKET"`
+	Path    string `yaml:"path" env:"MQTT_WEBSOCKET_PATH"`
+}
+
+// Auth are the shared credentials with access to every topic, both are
+// required.
+type Auth struct {
+	Username string `yaml:"username" env:"MQTT_USERNAME"`
+	Password string `yaml:"password" env:"MQTT_PASSWORD" secret:"true"`
+}
+
+type RateLimits struct {
+	// Limits per topic type, each overridden with
+	// MQTT_RATE_LIMIT_{TYPE}={rate}:{burst}:{max payload}.
+	Limits map[string]RateLimit `yaml:"limits"`
+	// DisconnectAfter dropped messages in a row a client is disconnected,
+	// never when 0.
+	DisconnectAfter int `yaml:"disconnect_after" env:"MQTT_RATE_LIMIT_DISCONNECT"`
+}
+
+type RateLimit struct {
+	Rate       float64 `yaml:"rate"`
+	Burst      int     `yaml:"burst"`
+	MaxPayload int     `yaml:"max_payload"`
+}
+
+// Bridge to an upstream broker, disabled when URL is empty.
+type Bridge struct {
+	URL      string `yaml:"url" env:"MQTT_BRIDGE_URL"`
+	Topics   string `yaml:"topics" env:"MQTT_BRIDGE_TOPICS"`
+	ClientId string `yaml:"client_id" env:"MQTT_BRIDGE_CLIENT_ID"`
+	Username string `yaml:"username" env:"MQTT_BRIDGE_USERNAME"`
+	Password string `yaml:"password" env:"MQTT_BRIDGE_PASSWORD" secret:"true"`
+	Buffer   int    `yaml:"buffer" env:"MQTT_BRIDGE_BUFFER"`
+}
+
+type Ingest struct {
+	QueueSize     int           `yaml:"queue_size" env:"INGEST_QUEUE_SIZE"`
+	BatchSize     int           `yaml:"batch_size" env:"INGEST_BATCH_SIZE"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"INGEST_FLUSH_INTERVAL"`
+	// LegacyTables writes the climate and LDR telemetry to the climate and
+	// ldr collections as well as to readings, for clients that still read
+	// them.
+	LegacyTables bool `yaml:"legacy_tables" env:"INGEST_LEGACY_TABLES"`
+}
+
+// Retention is how long data is kept, forever when 0.
+type Retention struct {
+	// Telemetry is the climate, LDR and sensor readings.
+	Telemetry        time.Duration `yaml:"telemetry" env:"RETENTION_TELEMETRY"`
+	RejectedMessages time.Duration `yaml:"rejected_messages" env:"RETENTION_REJECTED_MESSAGES"`
+	// DecommissionedDevices can be restored for this long before they are
+	// purged with all of their data.
+	DecommissionedDevices time.Duration `yaml:"decommissioned_devices" env:"RETENTION_DECOMMISSIONED_DEVICES"`
+}
+
+// Metrics are served on /metrics to clients with the token or superuser
+// auth.
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" env:"METRICS_ENABLED"`
+	Token   string `yaml:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+func Default() *Config {
+	return &Config{
+		Broker: Broker{
+			LogLevel: "error",
+			Listeners: Listeners{
+				TCP:       TCPListener{Port: 1883},
+				WebSocket: WebSocketListener{Enabled: true, Path: "/mqtt"},
+			},
+			RateLimits: RateLimits{
+				Limits: map[string]RateLimit{
+					"telemetry":    {Rate: 5, Burst: 20, MaxPayload: 1 << 10},
+					"health":       {Rate: 1, Burst: 5, MaxPayload: 4 << 10},
+					"provisioning": {Rate: 0.2, Burst: 3, MaxPayload: 1 << 10},
+					"device":       {Rate: 10, Burst: 50, MaxPayload: 64 << 10},
+				},
+				DisconnectAfter: 200,
+			},
+			Bridge: Bridge{Buffer: bridge.DefaultBufferSize},
+		},
+		Ingest: Ingest{
+			QueueSize:     4096,
+			BatchSize:     256,
+			FlushInterval: 500 * time.Millisecond,
+			LegacyTables:  true,
+		},
+		Retention: Retention{
+			DecommissionedDevices: 7 * 24 * time.Hour,
+		},
+		Metrics:         Metrics{Enabled: true},
+		ShutdownTimeout: 30 * time.Second,
+	}
+}
+
+// flags are the command line flags of the config. They are parsed before
+// the commands run, as the server is set up from the config.
+type flags struct {
+	set          *pflag.FlagSet
+	file         string
+	http         string
+	mqttPort     int
+	mqttLogLevel string
+}
+
+func newFlags() *flags {
+	f := &flags{set: pflag.NewFlagSet("config", pflag.ContinueOnError)}
+	f.set.ParseErrorsWhitelist.UnknownFlags = true
+	f.set.SetOutput(io.Discard)
+	f.set.StringVar(&f.file, "config", "", "the config file, "+DefaultFile+" when it exists")
+	f.set.StringVar(&f.http, "http", "", "")
+	f.set.IntVar(&f.mqttPort, "mqtt-port", 0, "the port of the MQTT TCP listener")
+	f.set.StringVar(&f.mqttLogLevel, "mqtt-log-level", "", "the log level of the MQTT broker")
+	return f
+}
+
+// RegisterFlags adds the flags of the config to the flags of the commands,
+// so they are accepted. The --http flag belongs to the serve command.
+func RegisterFlags(set *pflag.FlagSet) {
+	newFlags().set.VisitAll(func(f *pflag.Flag) {
+		if f.Name != "http" {
+			set.AddFlag(f)
+		}
+	})
+}
+
+// Load reads the config from the .env file, the config file, the
+// environment and the command line arguments, and validates it.
+func Load(args []string) (*Config, error) {
+	f := newFlags()
+	if err := f.set.Parse(args); err != nil && !errors.Is(err, pflag.ErrHelp) {
+		return nil, err
+	}
+
+	// the environment takes precedence over the .env file
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read .env: %w", err)
+	}
+
+	c := Default()
+
+	file := cmp.Or(f.file, os.Getenv("SMAAS_CONFIG"))
+	required := file != ""
+	if !required {
+		file = DefaultFile
+	}
+	if err := c.readFile(file, required); err != nil {
+		return nil, err
+	}
+
+	if err := readEnv(reflect.ValueOf(c).Elem()); err != nil {
+		return nil, err
+	}
+	if err := c.readRateLimitEnv(); err != nil {
+		return nil, err
+	}
+
+	if f.set.Changed("http") {
+		c.HTTP.Address = f.http
+	}
+	if f.set.Changed("mqtt-port") {
+		c.Broker.Listeners.TCP.Port = f.mqttPort
+	}
+	if f.set.Changed("mqtt-log-level") {
+		c.Broker.LogLevel = f.mqttLogLevel
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) readFile(file string, required bool) error {
+	r, err := os.Open(file)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer r.Close()
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", file, err)
+	}
+	return nil
+}
+
+// readEnv sets the fields with an env tag from the environment.
+func readEnv(v reflect.Value) error {
+	for i := 0; i < v.NumField(); i++ {
+		field, kind := v.Field(i), v.Type().Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := readEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := kind.Tag.Get("env")
+		value, ok := os.LookupEnv(name)
+		if name == "" || !ok || value == "" {
+			continue
+		}
+		if err := setValue(field, value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return nil
+}
+
+func setValue(field reflect.Value, value string) error {
+	switch {
+	case field.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// readRateLimitEnv overrides a rate limit with
+// MQTT_RATE_LIMIT_{TYPE}={rate}:{burst}:{max payload}.
+func (c *Config) readRateLimitEnv() error {
+	for name := range c.Broker.RateLimits.Limits {
+		env := "MQTT_RATE_LIMIT_" + strings.ToUpper(name)
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+
+		var limit RateLimit
+		if _, err := fmt.Sscanf(value, "%g:%d:%d", &limit.Rate, &limit.Burst, &limit.MaxPayload); err != nil {
+			return fmt.Errorf("invalid %s %q, expected {rate}:{burst}:{max payload}: %w", env, value, err)
+		}
+		c.Broker.RateLimits.Limits[name] = limit
+	}
+	return nil
+}
+
+// Validate checks the whole config and returns every problem at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(field string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if c.HTTP.Address != "" {
+		if _, _, err := net.SplitHostPort(c.HTTP.Address); err != nil {
+			invalid("http.address", "%q is not a host:port address", c.HTTP.Address)
+		}
+	}
+
+	if c.Admin.Email != "" && !strings.Contains(c.Admin.Email, "@") {
+		invalid("admin.email", "%q is not an email address", c.Admin.Email)
+	}
+	if c.Admin.Email != "" && len(c.Admin.Password) < 8 {
+		invalid("admin.password", "must be at least 8 characters")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Broker.LogLevel)); err != nil {
+		invalid("broker.log_level", "%q is not one of debug, info, warn or error", c.Broker.LogLevel)
+	}
+
+	listeners := c.Broker.Listeners
+	if listeners.TCP.Port != 0 && !validPort(listeners.TCP.Port) {
+		invalid("broker.listeners.tcp.port", "%d is not a port", listeners.TCP.Port)
+	}
+	if listeners.TLS.Port != 0 {
+		if !validPort(listeners.TLS.Port) {
+			invalid("broker.listeners.tls.port", "%d is not a port", listeners.TLS.Port)
+		}
+		if listeners.TLS.Port == listeners.TCP.Port {
+			invalid("broker.listeners.tls.port", "is the port of the TCP listener")
+		}
+		if listeners.TLS.Cert == "" || listeners.TLS.Key == "" {
+			invalid("broker.listeners.tls", "cert and key are required when the port is set")
+		}
+	}
+	if listeners.TCP.Port == 0 && listeners.TLS.Port == 0 && !listeners.WebSocket.Enabled {
+		invalid("broker.listeners", "at least one of the TCP, TLS and WebSocket listeners is required")
+	}
+	if listeners.WebSocket.Enabled && !strings.HasPrefix(listeners.WebSocket.Path, "/") {
+		invalid("broker.listeners.websocket.path", "%q must start with /", listeners.WebSocket.Path)
+	}
+
+	// without them the clients without a username would log in with the
+	// shared credentials
+	if c.Broker.Auth.Username == "" || c.Broker.Auth.Password == "" {
+		invalid("broker.auth", "username and password are required")
+	}
+
+	names := slices.Sorted(maps.Keys(c.Broker.RateLimits.Limits))
+	defaults := Default().Broker.RateLimits.Limits
+	for _, name := range names {
+		limit, field := c.Broker.RateLimits.Limits[name], "broker.rate_limits.limits."+name
+		if _, ok := defaults[name]; !ok {
+			invalid(field, "unknown topic type, expected one of telemetry, health, provisioning or device")
+			continue
+		}
+		if limit.Rate <= 0 || limit.Burst < 1 {
+			invalid(field, "rate and burst must be positive")
+		}
+		if limit.MaxPayload < 0 {
+			invalid(field, "max_payload must not be negative")
+		}
+	}
+	if c.Broker.RateLimits.DisconnectAfter < 0 {
+		invalid("broker.rate_limits.disconnect_after", "must not be negative")
+	}
+
+	if c.Broker.Bridge.URL != "" {
+		u, err := url.Parse(c.Broker.Bridge.URL)
+		if err != nil || u.Host == "" || !slices.Contains([]string{"tcp", "ssl", "tls", "ws", "wss", "mqtt", "mqtts"}, u.Scheme) {
+			invalid("broker.bridge.url", "%q is not a broker URL like tcp://host:1883", c.Broker.Bridge.URL)
+		}
+		if rules, err := bridge.ParseRules(c.Broker.Bridge.Topics); err != nil {
+			invalid("broker.bridge.topics", "%s", err)
+		} else if len(rules) == 0 {
+			invalid("broker.bridge.topics", "is required when the url is set")
+		}
+		if c.Broker.Bridge.Buffer < 1 {
+			invalid("broker.bridge.buffer", "must be positive")
+		}
+	}
+
+	if c.Ingest.QueueSize < 1 {
+		invalid("ingest.queue_size", "must be positive")
+	}
+	if c.Ingest.BatchSize < 1 {
+		invalid("ingest.batch_size", "must be positive")
+	}
+	if c.Ingest.FlushInterval <= 0 {
+		invalid("ingest.flush_interval", "must be positive")
+	}
+
+	if c.Retention.Telemetry < 0 {
+		invalid("retention.telemetry", "must not be negative")
+	}
+	if c.Retention.RejectedMessages < 0 {
+		invalid("retention.rejected_messages", "must not be negative")
+	}
+	if c.Retention.DecommissionedDevices <= 0 {
+		invalid("retention.decommissioned_devices", "must be positive")
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		invalid("shutdown_timeout", "must be positive")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// LogLevel returns the level of the broker log.
+func (c *Config) LogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.Broker.LogLevel))
+	return level
+}
+
+// Print writes the config as YAML with the secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	printed := *c
+	printed.Broker.RateLimits.Limits = maps.Clone(c.Broker.RateLimits.Limits)
+	redact(reflect.ValueOf(&printed).Elem())
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(printed); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			redact(field)
+			continue
+		}
+		if v.Type().Field(i).Tag.Get("secret") == "true" && field.String() != "" {
+			field.SetString(redacted)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package hooks
+
+import (
+	"bytes"
+	"crypto/tls"
+	"slices"
+	"strings"
+	"sync"
+
+	"coderero.dev/iot/smaas-server/internal/provision"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// DeviceAuthenticator checks the credentials issued to a device.
+type DeviceAuthenticator interface {
+	Authenticate(deviceId string, password string) bool
+	// Active reports whether the device exists and is not decommissioned,
+	// for devices that log in with a client certificate.
+	Active(deviceId string) bool
+	// Exists reports whether the device exists, even if decommissioned.
+	Exists(deviceId string) bool
+}
+
+// Sessions are the sessions of the broker, connected or persisted.
+type Sessions interface {
+	Get(id string) (*mqtt.Client, bool)
+}
+
+// DashboardAuthenticator checks the PocketBase auth token of browser
+// clients and the devices their user may view.
+type DashboardAuthenticator interface {
+	AuthenticateToken(token string) (userId string, ok bool)
+	CanView(userId string, deviceId string) bool
+}
+
+type AuthOptions struct {
+	// Username and Password are the shared credentials with access to
+	// every topic.
+	Username string
+	Password string
+	Devices  DeviceAuthenticator
+	// Sessions keep bootstrap clients from taking over the session of a
+	// device or of another client.
+	Sessions Sessions
+	// Dashboards authenticates the clients of DashboardListener, which
+	// log in with their auth token as password and may only subscribe.
+	Dashboards        DashboardAuthenticator
+	DashboardListener string
+	// ReadOnly topics may be read by every client, e.g. the status of the
+	// server.
+	ReadOnly []string
+}
+
+// Auth authenticates clients with the shared credentials, with the
+// credentials issued to a device, with a device client certificate or as not
+// yet provisioned boards. Devices are limited to their own topics and boards
+// to their bootstrap topics.
+type Auth struct {
+	mqtt.HookBase
+	config *AuthOptions
+	// dashboards are the connected browsers by client id, to check them
+	// again when their access changes
+	dashboards sync.Map
+}
+
+// dashboardSession is a connected browser and the token it logged in with.
+type dashboardSession struct {
+	client *mqtt.Client
+	token  string
+}
+
+func (h *Auth) ID() string {
+	return "smaas-auth"
+}
+
+func (h *Auth) Provides(b byte) bool {
+	return bytes.Contains([]byte{
+		mqtt.OnConnectAuthenticate,
+		mqtt.OnACLCheck,
+		mqtt.OnDisconnect,
+	}, []byte{b})
+}
+
+func (h *Auth) Init(config any) error {
+	if _, ok := config.(*AuthOptions); !ok || config == nil {
+		return mqtt.ErrInvalidConfigType
+	}
+
+	h.config = config.(*AuthOptions)
+	return nil
+}
+
+func (h *Auth) OnConnectAuthenticate(cl *mqtt.Client, pk packets.Packet) bool {
+	username := string(pk.Connect.Username)
+	password := string(pk.Connect.Password)
+
+	if h.isDashboard(cl) {
+		return h.authenticateDashboard(cl, pk)
+	}
+
+	// the common name of a verified client certificate is the device id and
+	// replaces the password
+	if deviceId := certificateDevice(cl); deviceId != "" {
+		if (username != "" && username != deviceId) || cl.ID != deviceId {
+			return false
+		}
+		if h.config.Devices == nil || !h.config.Devices.Active(deviceId) {
+			return false
+		}
+		cl.Properties.Username = []byte(deviceId)
+		return true
+	}
+
+	switch {
+	case h.isShared(cl):
+		return password == h.config.Password
+	case username == provision.Username:
+		return h.authenticateBoard(cl, pk)
+	case h.config.Devices != nil:
+		// the client id is the device id, so a device cannot take over the
+		// session of another one
+		return cl.ID == username && h.config.Devices.Authenticate(username, password)
+	}
+
+	return false
+}
+
+// authenticateBoard logs in a board that is not provisioned yet. It has no
+// password, so it may not take over any session but the one of a bootstrap
+// client, and may not keep one.
+func (h *Auth) authenticateBoard(cl *mqtt.Client, pk packets.Packet) bool {
+	// the serial is used in the bootstrap topics
+	if cl.ID == "" || strings.ContainsAny(cl.ID, "/+#") || len(pk.Connect.Password) != 0 {
+		return false
+	}
+	if !pk.Connect.Clean {
+		return false
+	}
+	if h.config.Devices != nil && h.config.Devices.Exists(cl.ID) {
+		return false
+	}
+	if h.config.Sessions != nil {
+		if existing, ok := h.config.Sessions.Get(cl.ID); ok && string(existing.Properties.Username) != provision.Username {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Auth) OnACLCheck(cl *mqtt.Client, topic string, write bool) bool {
+	username := string(cl.Properties.Username)
+
+	switch {
+	case !write && slices.Contains(h.config.ReadOnly, topic):
+		return true
+	case h.isDashboard(cl):
+		return !write && h.dashboardFilter(username, topic)
+	case h.isShared(cl):
+		return true
+	case username == provision.Username:
+		if write {
+			return topic == provision.AnnounceTopic(cl.ID)
+		}
+		return topic == provision.CredentialsTopic(cl.ID)
+	}
+
+	return strings.HasPrefix(topic, "arduino/"+username+"/")
+}
+
+// isShared reports whether the client logged in with the shared username.
+// Without one nobody does, not even the clients without a username.
+func (h *Auth) isShared(cl *mqtt.Client) bool {
+	return h.config.Username != "" && string(cl.Properties.Username) == h.config.Username
+}
+
+func (h *Auth) isDashboard(cl *mqtt.Client) bool {
+	return h.config.Dashboards != nil && cl.Net.Listener == h.config.DashboardListener
+}
+
+// authenticateDashboard logs a browser in as the user of the token. The
+// client id has to be empty or start with the user id, so a browser cannot
+// take over the session of a device or of another user.
+func (h *Auth) authenticateDashboard(cl *mqtt.Client, pk packets.Packet) bool {
+	token := string(pk.Connect.Password)
+	userId, ok := h.config.Dashboards.AuthenticateToken(token)
+	if !ok {
+		return false
+	}
+
+	clientId := string(pk.Connect.ClientIdentifier)
+	if clientId != "" && !strings.HasPrefix(clientId, userId) {
+		return false
+	}
+
+	cl.Properties.Username = []byte(userId)
+	h.dashboards.Store(cl.ID, &dashboardSession{client: cl, token: token})
+	return true
+}
+
+func (h *Auth) OnDisconnect(cl *mqtt.Client, err error, expire bool) {
+	if session, ok := h.dashboards.Load(cl.ID); ok && session.(*dashboardSession).client == cl {
+		h.dashboards.CompareAndDelete(cl.ID, session)
+	}
+}
+
+// CheckDashboards disconnects the browsers whose token is not valid anymore,
+// because it expired, the password changed or the user was deleted, and those
+// subscribed to a device their user may not view anymore. Subscriptions are
+// only checked when they are made, so this runs when the access to devices
+// changes and periodically for the expiry of tokens.
+func (h *Auth) CheckDashboards() {
+	h.dashboards.Range(func(id, value any) bool {
+		session := value.(*dashboardSession)
+		cl := session.client
+		if cl.Closed() {
+			h.dashboards.CompareAndDelete(id, value)
+			return true
+		}
+
+		userId, ok := h.config.Dashboards.AuthenticateToken(session.token)
+		allowed := ok && userId == string(cl.Properties.Username)
+		for filter := range cl.State.Subscriptions.GetAll() {
+			if !allowed {
+				break
+			}
+			allowed = h.OnACLCheck(cl, filter, false)
+		}
+
+		if !allowed {
+			cl.Stop(packets.ErrNotAuthorized)
+		}
+		return true
+	})
+}
+
+// dashboardTopics are the telemetry topics below arduino/{device_id}/ that
+// browsers may read, also with the JSON suffix. The others carry RFID cards,
+// commands, reset nonces and download URLs.
+var dashboardTopics = []string{"climate", "ldr", "reading", "relay", "relay/full", "health", "shadow/reported"}
+
+// dashboardFilter allows subscriptions to the telemetry topics and below
+// state/{device_id}/ of the devices the user can view.
+func (h *Auth) dashboardFilter(userId string, filter string) bool {
+	parts := strings.SplitN(filter, "/", 3)
+	if len(parts) != 3 || parts[2] == "" || strings.ContainsAny(parts[1], "+#") {
+		return false
+	}
+
+	switch parts[0] {
+	case "arduino":
+		if !slices.Contains(dashboardTopics, strings.TrimSuffix(parts[2], "/json")) {
+			return false
+		}
+	case "state":
+	default:
+		return false
+	}
+
+	return h.config.Dashboards.CanView(userId, parts[1])
+}
+
+// certificateDevice returns the device id of the verified client certificate
+// of a TLS connection, or an empty string.
+func certificateDevice(cl *mqtt.Client) string {
+	conn, ok := cl.Net.Conn.(*tls.Conn)
+	if !ok {
+		return ""
+	}
+
+	state := conn.ConnectionState()
+	if len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	deviceId := state.VerifiedChains[0][0].Subject.CommonName
+	if strings.ContainsAny(deviceId, "/+#") {
+		return ""
+	}
+	return deviceId
+}
@@ -0,0 +1,132 @@
+package hooks
+
+import (
+	"net"
+	"testing"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// dashboards is a DashboardAuthenticator with the tokens of the users and
+// the devices they may view.
+type dashboards struct {
+	tokens  map[string]string
+	devices map[string][]string
+}
+
+func (d *dashboards) AuthenticateToken(token string) (string, bool) {
+	userId, ok := d.tokens[token]
+	return userId, ok
+}
+
+func (d *dashboards) CanView(userId string, deviceId string) bool {
+	for _, id := range d.devices[userId] {
+		if id == deviceId {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCheckDashboards(t *testing.T) {
+	tests := []struct {
+		name string
+		// change takes the access away or not
+		change func(d *dashboards)
+		stop   bool
+	}{
+		{name: "unchanged", change: func(d *dashboards) {}},
+		{name: "other device removed", change: func(d *dashboards) { d.devices["user1"] = []string{"device1"} }},
+		{name: "device removed", change: func(d *dashboards) { d.devices["user1"] = []string{"device2"} }, stop: true},
+		{name: "token expired", change: func(d *dashboards) { delete(d.tokens, "token1") }, stop: true},
+		{name: "token of another user", change: func(d *dashboards) { d.tokens["token1"] = "user2" }, stop: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &dashboards{
+				tokens:  map[string]string{"token1": "user1"},
+				devices: map[string][]string{"user1": {"device1", "device2"}},
+			}
+
+			h := new(Auth)
+			if err := h.Init(&AuthOptions{Dashboards: d, DashboardListener: "ws"}); err != nil {
+				t.Fatal(err)
+			}
+
+			conn, peer := net.Pipe()
+			defer peer.Close()
+			cl := mqtt.New(nil).NewClient(conn, "ws", "user1-dashboard", false)
+			if !h.OnConnectAuthenticate(cl, packets.Packet{Connect: packets.ConnectParams{
+				ClientIdentifier: cl.ID,
+				Password:         []byte("token1"),
+			}}) {
+				t.Fatal("dashboard not authenticated")
+			}
+			if !h.OnACLCheck(cl, "arduino/device1/climate", false) {
+				t.Fatal("subscription refused")
+			}
+			cl.State.Subscriptions.Add("arduino/device1/climate", packets.Subscription{Filter: "arduino/device1/climate"})
+
+			tt.change(d)
+			h.CheckDashboards()
+
+			if cl.Closed() != tt.stop {
+				t.Errorf("stopped = %v, want %v", cl.Closed(), tt.stop)
+			}
+		})
+	}
+}
+
+func TestCheckDashboardsForgetsDisconnected(t *testing.T) {
+	d := &dashboards{tokens: map[string]string{"token1": "user1"}}
+	h := new(Auth)
+	if err := h.Init(&AuthOptions{Dashboards: d, DashboardListener: "ws"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+	cl := mqtt.New(nil).NewClient(conn, "ws", "user1-dashboard", false)
+	h.OnConnectAuthenticate(cl, packets.Packet{Connect: packets.ConnectParams{Password: []byte("token1")}})
+	h.OnDisconnect(cl, nil, false)
+
+	if _, ok := h.dashboards.Load(cl.ID); ok {
+		t.Error("disconnected dashboard is still checked")
+	}
+}
+
+func TestDashboardFilter(t *testing.T) {
+	h := new(Auth)
+	d := &dashboards{devices: map[string][]string{"user1": {"device1"}}}
+	if err := h.Init(&AuthOptions{Dashboards: d, DashboardListener: "ws"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{filter: "arduino/device1/climate", want: true},
+		{filter: "arduino/device1/climate/json", want: true},
+		{filter: "arduino/device1/relay/full", want: true},
+		{filter: "state/device1/#", want: true},
+		{filter: "state/device1/relay/+", want: true},
+		{filter: "arduino/device2/climate"},
+		{filter: "arduino/+/climate"},
+		// RFID cards, commands and nonces are left to the API
+		{filter: "arduino/device1/rfid"},
+		{filter: "arduino/device1/factory_reset"},
+		{filter: "arduino/device1/command"},
+		{filter: "arduino/device1/#"},
+		{filter: "arduino/device1/+"},
+		{filter: "provision/device1/credentials"},
+	}
+
+	for _, tt := range tests {
+		if got := h.dashboardFilter("user1", tt.filter); got != tt.want {
+			t.Errorf("dashboardFilter(%s) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package hooks
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/provision"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+const (
+	// IncidentRate is a client publishing faster than its limit.
+	IncidentRate = "rate"
+	// IncidentPayload is a client publishing payloads larger than its limit.
+	IncidentPayload = "payload"
+	// IncidentDisconnected is a client disconnected for flooding.
+	IncidentDisconnected = "disconnected"
+)
+
+// limitsExpiry is how long the buckets of a device are kept after its last
+// message. They outlive its connection, so a device that is disconnected
+// for flooding does not start over with a full burst when it reconnects.
+const limitsExpiry = 10 * time.Minute
+
+// RateLimit limits the messages a client publishes on the topics of a type,
+// e.g. the telemetry topics.
+type RateLimit struct {
+	Name    string
+	Filters []string
+	// Rate is the messages per second a client may publish, and Burst the
+	// messages it may publish at once after being quiet.
+	Rate  float64
+	Burst int
+	// MaxPayload is the largest payload in bytes, unlimited when 0.
+	MaxPayload int
+}
+
+// IncidentRecorder records the incidents of a device. It is called once per
+// incident, not for every dropped message.
+type IncidentRecorder interface {
+	RecordIncident(deviceId string, limit string, incident string)
+}
+
+type RateLimitOptions struct {
+	// Limits are matched in order, the first limit with a matching filter
+	// applies. Topics without a limit are not limited.
+	Limits []RateLimit
+	// DisconnectAfter dropped messages in a row the client is disconnected,
+	// never when 0.
+	DisconnectAfter int
+	// Username of the shared credentials, which are not limited.
+	Username  string
+	Incidents IncidentRecorder
+}
+
+// RateLimiter drops the messages of clients publishing faster or larger
+// than the limit of the topic type, before they reach the handlers, and
+// disconnects clients that keep flooding.
+type RateLimiter struct {
+	mqtt.HookBase
+	config *RateLimitOptions
+
+	mu      sync.Mutex
+	clients map[string]*clientLimits
+	// swept is the last time the expired buckets were dropped
+	swept time.Time
+}
+
+// clientLimits are the token buckets of a device, per limit.
+type clientLimits struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// dropped messages in a row
+	dropped int
+	// used is the last message of the device, guarded by the mutex of the
+	// rate limiter
+	used time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// incident is set from the first dropped message until a message passes
+	incident bool
+}
+
+func (h *RateLimiter) ID() string {
+	return "smaas-rate-limit"
+}
+
+func (h *RateLimiter) Provides(b byte) bool {
+	return bytes.Contains([]byte{
+		mqtt.OnPublish,
+	}, []byte{b})
+}
+
+func (h *RateLimiter) Init(config any) error {
+	if _, ok := config.(*RateLimitOptions); !ok || config == nil {
+		return mqtt.ErrInvalidConfigType
+	}
+
+	h.config = config.(*RateLimitOptions)
+	h.clients = map[string]*clientLimits{}
+	return nil
+}
+
+func (h *RateLimiter) OnPublish(cl *mqtt.Client, pk packets.Packet) (packets.Packet, error) {
+	if cl.Net.Inline || (h.config.Username != "" && string(cl.Properties.Username) == h.config.Username) {
+		return pk, nil
+	}
+
+	limit, ok := h.limit(pk.TopicName)
+	if !ok {
+		return pk, nil
+	}
+
+	c := h.client(limitsKey(cl))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.buckets[limit.Name]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: time.Now()}
+		c.buckets[limit.Name] = b
+	}
+
+	incident := ""
+	switch {
+	case limit.MaxPayload > 0 && len(pk.Payload) > limit.MaxPayload:
+		incident = IncidentPayload
+	case !b.take(limit):
+		incident = IncidentRate
+	}
+
+	if incident == "" {
+		b.incident = false
+		c.dropped = 0
+		return pk, nil
+	}
+
+	c.dropped++
+	if !b.incident {
+		b.incident = true
+		h.Log.Warn("client rate limited",
+			slog.String("client", cl.ID),
+			slog.String("limit", limit.Name),
+			slog.String("incident", incident),
+			slog.String("topic", pk.TopicName),
+		)
+		h.record(cl, limit.Name, incident)
+	}
+
+	if h.config.DisconnectAfter > 0 && c.dropped >= h.config.DisconnectAfter {
+		h.Log.Warn("client disconnected for flooding", slog.String("client", cl.ID), slog.String("limit", limit.Name))
+		h.record(cl, limit.Name, IncidentDisconnected)
+		cl.Stop(packets.ErrMessageRateTooHigh)
+	}
+
+	// MQTT 5 clients are told why in the acknowledgement, any other error
+	// would be ignored by the broker
+	if cl.Properties.ProtocolVersion == 5 && pk.FixedHeader.Qos > 0 {
+		if incident == IncidentPayload {
+			return pk, packets.ErrPacketTooLarge
+		}
+		return pk, packets.ErrMessageRateTooHigh
+	}
+	// older clients are acknowledged without publishing the message, they
+	// would resend it forever without the PUBACK or PUBREC
+	if pk.FixedHeader.Qos > 0 {
+		return pk, packets.CodeSuccessIgnore
+	}
+	return pk, packets.ErrRejectPacket
+}
+
+// limitsKey is the device id of the client, as the client id can be changed
+// on every connect. Boards that are not provisioned yet share a username and
+// are told apart by their serial.
+func limitsKey(cl *mqtt.Client) string {
+	username := string(cl.Properties.Username)
+	if username == provision.Username {
+		return username + "/" + cl.ID
+	}
+	return username
+}
+
+func (h *RateLimiter) limit(topic string) (RateLimit, bool) {
+	for _, limit := range h.config.Limits {
+		for _, filter := range limit.Filters {
+			if MatchFilter(filter, topic) {
+				return limit, true
+			}
+		}
+	}
+	return RateLimit{}, false
+}
+
+func (h *RateLimiter) client(key string) *clientLimits {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(h.swept) > limitsExpiry {
+		for k, c := range h.clients {
+			if now.Sub(c.used) > limitsExpiry {
+				delete(h.clients, k)
+			}
+		}
+		h.swept = now
+	}
+
+	c, ok := h.clients[key]
+	if !ok {
+		c = &clientLimits{buckets: map[string]*bucket{}}
+		h.clients[key] = c
+	}
+	c.used = now
+	return c
+}
+
+// record hands the incident to the recorder without blocking the client.
+func (h *RateLimiter) record(cl *mqtt.Client, limit string, incident string) {
+	if h.config.Incidents == nil {
+		return
+	}
+
+	go h.config.Incidents.RecordIncident(string(cl.Properties.Username), limit, incident)
+}
+
+// take takes a token from the bucket, which is refilled at the rate of the
+// limit up to its burst.
+func (b *bucket) take(limit RateLimit) bool {
+	now := time.Now()
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// MatchFilter reports whether a topic matches an MQTT topic filter.
+func MatchFilter(filter string, topic string) bool {
+	filterParts := strings.Split(filter, "/")
+	topicParts := strings.Split(topic, "/")
+
+	for i, part := range filterParts {
+		switch {
+		case part == "#":
+			return true
+		case i >= len(topicParts):
+			return false
+		case part != "+" && part != topicParts[i]:
+			return false
+		}
+	}
+
+	return len(filterParts) == len(topicParts)
+}
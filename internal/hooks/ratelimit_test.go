@@ -0,0 +1,42 @@
+package hooks
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+func TestRateLimiterAcknowledgesDroppedMessages(t *testing.T) {
+	server, address := startBroker(t, func(server *mqtt.Server) error {
+		return server.AddHook(new(RateLimiter), &RateLimitOptions{
+			Limits: []RateLimit{{Name: "telemetry", Filters: []string{"sensors/#"}, Rate: 0.001, Burst: 1}},
+		})
+	})
+	defer server.Close()
+
+	var delivered atomic.Int32
+	err := server.Subscribe("sensors/#", 1, func(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
+		delivered.Add(1)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := connect(t, address, "device", nil)
+	defer client.Disconnect(0)
+
+	for _, qos := range []byte{1, 1, 2} {
+		// MQTT 3 clients resend messages until they are acknowledged
+		token := client.Publish("sensors/hall", qos, false, []byte("reading"))
+		if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+			t.Fatalf("QoS %d message was not acknowledged: %v", qos, token.Error())
+		}
+	}
+
+	if got := delivered.Load(); got != 1 {
+		t.Errorf("delivered %d messages, want only the burst", got)
+	}
+}
@@ -0,0 +1,309 @@
+package hooks
+
+import (
+	"bytes"
+	"encoding"
+	"log/slog"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/storage"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+type StorageOptions struct {
+	App core.App
+}
+
+// Storage persists the broker state in the broker_state collection, so
+// sessions of clients without clean session, their subscriptions and queued
+// QoS messages and the retained messages survive a restart. The collection
+// has to exist before the broker is started.
+type Storage struct {
+	mqtt.HookBase
+	app core.App
+}
+
+func (h *Storage) ID() string {
+	return "smaas-storage"
+}
+
+func (h *Storage) Provides(b byte) bool {
+	return bytes.Contains([]byte{
+		mqtt.OnSessionEstablished,
+		mqtt.OnDisconnect,
+		mqtt.OnSubscribed,
+		mqtt.OnUnsubscribed,
+		mqtt.OnRetainMessage,
+		mqtt.OnWillSent,
+		mqtt.OnQosPublish,
+		mqtt.OnQosComplete,
+		mqtt.OnQosDropped,
+		mqtt.OnClientExpired,
+		mqtt.OnRetainedExpired,
+		mqtt.StoredClients,
+		mqtt.StoredInflightMessages,
+		mqtt.StoredRetainedMessages,
+		mqtt.StoredSubscriptions,
+	}, []byte{b})
+}
+
+func (h *Storage) Init(config any) error {
+	options, ok := config.(*StorageOptions)
+	if !ok || options.App == nil {
+		return mqtt.ErrInvalidConfigType
+	}
+
+	h.app = options.App
+	return nil
+}
+
+func clientKey(cl *mqtt.Client) string {
+	return storage.ClientKey + "_" + cl.ID
+}
+
+func subscriptionKey(cl *mqtt.Client, filter string) string {
+	return storage.SubscriptionKey + "_" + cl.ID + ":" + filter
+}
+
+func retainedKey(topic string) string {
+	return storage.RetainedKey + "_" + topic
+}
+
+func inflightKey(cl *mqtt.Client, pk packets.Packet) string {
+	return storage.InflightKey + "_" + cl.ID + ":" + pk.FormatID()
+}
+
+func (h *Storage) OnSessionEstablished(cl *mqtt.Client, pk packets.Packet) {
+	h.updateClient(cl)
+}
+
+func (h *Storage) OnWillSent(cl *mqtt.Client, pk packets.Packet) {
+	h.updateClient(cl)
+}
+
+func (h *Storage) updateClient(cl *mqtt.Client) {
+	props := cl.Properties.Props.Copy(false)
+	h.set(clientKey(cl), storage.ClientKey, &storage.Client{
+		ID:              cl.ID,
+		T:               storage.ClientKey,
+		Remote:          cl.Net.Remote,
+		Listener:        cl.Net.Listener,
+		Username:        cl.Properties.Username,
+		Clean:           cl.Properties.Clean,
+		ProtocolVersion: cl.Properties.ProtocolVersion,
+		Properties: storage.ClientProperties{
+			SessionExpiryInterval: props.SessionExpiryInterval,
+			AuthenticationMethod:  props.AuthenticationMethod,
+			AuthenticationData:    props.AuthenticationData,
+			RequestProblemInfo:    props.RequestProblemInfo,
+			RequestResponseInfo:   props.RequestResponseInfo,
+			ReceiveMaximum:        props.ReceiveMaximum,
+			TopicAliasMaximum:     props.TopicAliasMaximum,
+			User:                  props.User,
+			MaximumPacketSize:     props.MaximumPacketSize,
+		},
+		Will: storage.ClientWill(cl.Properties.Will),
+	})
+}
+
+func (h *Storage) OnDisconnect(cl *mqtt.Client, _ error, expire bool) {
+	// sessions that do not expire are resumed after a restart
+	if !expire || cl.StopCause() == packets.ErrSessionTakenOver {
+		return
+	}
+
+	h.delete(clientKey(cl))
+}
+
+func (h *Storage) OnSubscribed(cl *mqtt.Client, pk packets.Packet, reasonCodes []byte) {
+	// the server subscribes its handlers again on every start, before the
+	// database is open
+	if cl.Net.Inline {
+		return
+	}
+
+	for i, sub := range pk.Filters {
+		if i >= len(reasonCodes) || reasonCodes[i] >= packets.ErrUnspecifiedError.Code {
+			continue
+		}
+
+		key := subscriptionKey(cl, sub.Filter)
+		h.set(key, storage.SubscriptionKey, &storage.Subscription{
+			ID:                key,
+			T:                 storage.SubscriptionKey,
+			Client:            cl.ID,
+			Qos:               reasonCodes[i],
+			Filter:            sub.Filter,
+			Identifier:        sub.Identifier,
+			NoLocal:           sub.NoLocal,
+			RetainHandling:    sub.RetainHandling,
+			RetainAsPublished: sub.RetainAsPublished,
+		})
+	}
+}
+
+func (h *Storage) OnUnsubscribed(cl *mqtt.Client, pk packets.Packet) {
+	if cl.Net.Inline {
+		return
+	}
+
+	for _, sub := range pk.Filters {
+		h.delete(subscriptionKey(cl, sub.Filter))
+	}
+}
+
+func (h *Storage) OnRetainMessage(cl *mqtt.Client, pk packets.Packet, r int64) {
+	if r == -1 {
+		h.delete(retainedKey(pk.TopicName))
+		return
+	}
+
+	h.set(retainedKey(pk.TopicName), storage.RetainedKey, message(retainedKey(pk.TopicName), storage.RetainedKey, cl, pk, 0))
+}
+
+func (h *Storage) OnQosPublish(cl *mqtt.Client, pk packets.Packet, sent int64, resends int) {
+	h.set(inflightKey(cl, pk), storage.InflightKey, message(inflightKey(cl, pk), storage.InflightKey, cl, pk, sent))
+}
+
+func (h *Storage) OnQosComplete(cl *mqtt.Client, pk packets.Packet) {
+	h.delete(inflightKey(cl, pk))
+}
+
+func (h *Storage) OnQosDropped(cl *mqtt.Client, pk packets.Packet) {
+	h.OnQosComplete(cl, pk)
+}
+
+func (h *Storage) OnRetainedExpired(filter string) {
+	h.delete(retainedKey(filter))
+}
+
+func (h *Storage) OnClientExpired(cl *mqtt.Client) {
+	h.delete(clientKey(cl))
+}
+
+func (h *Storage) StoredClients() ([]storage.Client, error) {
+	var v []storage.Client
+	err := h.load(storage.ClientKey, func(value []byte) error {
+		var c storage.Client
+		if err := c.UnmarshalBinary(value); err != nil {
+			return err
+		}
+		v = append(v, c)
+		return nil
+	})
+	return v, err
+}
+
+func (h *Storage) StoredSubscriptions() ([]storage.Subscription, error) {
+	var v []storage.Subscription
+	err := h.load(storage.SubscriptionKey, func(value []byte) error {
+		var s storage.Subscription
+		if err := s.UnmarshalBinary(value); err != nil {
+			return err
+		}
+		v = append(v, s)
+		return nil
+	})
+	return v, err
+}
+
+func (h *Storage) StoredRetainedMessages() ([]storage.Message, error) {
+	return h.loadMessages(storage.RetainedKey)
+}
+
+func (h *Storage) StoredInflightMessages() ([]storage.Message, error) {
+	return h.loadMessages(storage.InflightKey)
+}
+
+func (h *Storage) loadMessages(kind string) ([]storage.Message, error) {
+	var v []storage.Message
+	err := h.load(kind, func(value []byte) error {
+		var m storage.Message
+		if err := m.UnmarshalBinary(value); err != nil {
+			return err
+		}
+		v = append(v, m)
+		return nil
+	})
+	return v, err
+}
+
+func message(key string, kind string, cl *mqtt.Client, pk packets.Packet, sent int64) *storage.Message {
+	props := pk.Properties.Copy(false)
+	return &storage.Message{
+		ID:          key,
+		T:           kind,
+		Client:      cl.ID,
+		Origin:      pk.Origin,
+		PacketID:    pk.PacketID, // needed to resend inflight messages
+		FixedHeader: pk.FixedHeader,
+		TopicName:   pk.TopicName,
+		Payload:     pk.Payload,
+		Sent:        sent,
+		Created:     pk.Created,
+		Properties: storage.MessageProperties{
+			PayloadFormat:          props.PayloadFormat,
+			MessageExpiryInterval:  props.MessageExpiryInterval,
+			ContentType:            props.ContentType,
+			ResponseTopic:          props.ResponseTopic,
+			CorrelationData:        props.CorrelationData,
+			SubscriptionIdentifier: props.SubscriptionIdentifier,
+			TopicAlias:             props.TopicAlias,
+			User:                   props.User,
+		},
+	}
+}
+
+// set upserts an entry. The state is written with plain queries instead of
+// records, it changes with every QoS message.
+func (h *Storage) set(key string, kind string, v encoding.BinaryMarshaler) {
+	value, err := v.MarshalBinary()
+	if err != nil {
+		h.Log.Error("failed to encode broker state", slog.String("key", key), slog.String("error", err.Error()))
+		return
+	}
+
+	_, err = h.app.NonconcurrentDB().NewQuery(
+		"INSERT INTO {{" + collections.BrokerStateCollectionName + "}} ([[key]], [[kind]], [[value]], [[updated]]) " +
+			"VALUES ({:key}, {:kind}, {:value}, {:updated}) " +
+			"ON CONFLICT ([[key]]) DO UPDATE SET [[value]] = excluded.[[value]], [[updated]] = excluded.[[updated]]",
+	).Bind(dbx.Params{
+		"key":     key,
+		"kind":    kind,
+		"value":   string(value),
+		"updated": types.NowDateTime().String(),
+	}).Execute()
+	if err != nil {
+		h.Log.Error("failed to store broker state", slog.String("key", key), slog.String("error", err.Error()))
+	}
+}
+
+func (h *Storage) delete(key string) {
+	_, err := h.app.NonconcurrentDB().Delete(collections.BrokerStateCollectionName, dbx.HashExp{"key": key}).Execute()
+	if err != nil {
+		h.Log.Error("failed to delete broker state", slog.String("key", key), slog.String("error", err.Error()))
+	}
+}
+
+func (h *Storage) load(kind string, fn func(value []byte) error) error {
+	var values []string
+	err := h.app.DB().Select("value").
+		From(collections.BrokerStateCollectionName).
+		Where(dbx.HashExp{"kind": kind}).
+		Column(&values)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		if err := fn([]byte(value)); err != nil {
+			// a broken entry must not keep the broker from starting
+			h.Log.Warn("skipped broken broker state", slog.String("kind", kind), slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
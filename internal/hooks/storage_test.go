@@ -0,0 +1,135 @@
+package hooks
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/pocketbase/pocketbase/core"
+	// the system migrations, which pocketbase.New registers
+	_ "github.com/pocketbase/pocketbase/migrations"
+)
+
+// startBroker serves a broker allowing every client on a free port, with
+// the hooks added by addHooks.
+func startBroker(t *testing.T, addHooks func(server *mqtt.Server) error) (*mqtt.Server, string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	server := mqtt.New(&mqtt.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := addHooks(server); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.AddListener(listeners.NewTCP(listeners.Config{ID: "tcp", Address: address})); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	return server, address
+}
+
+// connect connects an MQTT 3.1.1 client without clean session.
+func connect(t *testing.T, address string, id string, handler paho.MessageHandler) paho.Client {
+	t.Helper()
+
+	client := paho.NewClient(paho.NewClientOptions().
+		AddBroker("tcp://" + address).
+		SetClientID(id).
+		SetProtocolVersion(4).
+		SetCleanSession(false).
+		SetAutoReconnect(false).
+		SetDefaultPublishHandler(handler))
+	if token := client.Connect(); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("failed to connect: %v", token.Error())
+	}
+	return client
+}
+
+func TestStorageRestoresStateAfterRestart(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+	if err := app.SaveNoValidate((&collections.BrokerState{}).Schema()); err != nil {
+		t.Fatal(err)
+	}
+
+	storage := func(server *mqtt.Server) error {
+		return server.AddHook(new(Storage), &StorageOptions{App: app})
+	}
+	server, address := startBroker(t, storage)
+
+	client := connect(t, address, "subscriber", nil)
+	if token := client.Subscribe("sensors/#", 1, nil); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("failed to subscribe: %v", token.Error())
+	}
+	client.Disconnect(250)
+
+	// queued for the session while the client is away
+	if err := server.Publish("sensors/hall", []byte("queued"), false, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Publish("status/hall", []byte("retained"), true, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	server, address = startBroker(t, storage)
+	defer server.Close()
+
+	cl, ok := server.Clients.Get("subscriber")
+	if !ok {
+		t.Fatal("session was not restored")
+	}
+	if _, ok := cl.State.Subscriptions.Get("sensors/#"); !ok {
+		t.Error("subscription was not restored")
+	}
+	if inflight := cl.State.Inflight.GetAll(false); len(inflight) != 1 || string(inflight[0].Payload) != "queued" {
+		t.Errorf("inflight messages = %v, want the queued message", inflight)
+	}
+	if pk, ok := server.Topics.Retained.Get("status/hall"); !ok || string(pk.Payload) != "retained" {
+		t.Error("retained message was not restored")
+	}
+
+	// the client resumes the session and gets the queued message
+	received := make(chan string, 1)
+	client = connect(t, address, "subscriber", func(_ paho.Client, msg paho.Message) {
+		received <- fmt.Sprintf("%s %s", msg.Topic(), msg.Payload())
+	})
+	defer client.Disconnect(0)
+
+	select {
+	case got := <-received:
+		if got != "sensors/hall queued" {
+			t.Errorf("received %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued message was not delivered after the restart")
+	}
+}
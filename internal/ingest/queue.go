@@ -0,0 +1,226 @@
+package ingest
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	DefaultQueueSize     = 4096
+	DefaultBatchSize     = 256
+	DefaultFlushInterval = 500 * time.Millisecond
+)
+
+type Options struct {
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+type Stats struct {
+	Enqueued uint64 `json:"enqueued"`
+	Dropped  uint64 `json:"dropped"`
+	Written  uint64 `json:"written"`
+	Failed   uint64 `json:"failed"`
+	Batches  uint64 `json:"batches"`
+	Pending  int    `json:"pending"`
+}
+
+// Queue buffers telemetry records and writes them to the database in
+// batches, each batch in a single transaction, so that the MQTT callbacks
+// never block on SQLite.
+type Queue struct {
+	app     core.App
+	options Options
+	records chan *core.Record
+
+	mu       sync.RWMutex
+	started  bool
+	stopped  bool
+	done     chan struct{}
+	dropping atomic.Bool
+
+	enqueued atomic.Uint64
+	dropped  atomic.Uint64
+	written  atomic.Uint64
+	failed   atomic.Uint64
+	batches  atomic.Uint64
+}
+
+func NewQueue(app core.App, options Options) *Queue {
+	if options.QueueSize <= 0 {
+		options.QueueSize = DefaultQueueSize
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = DefaultFlushInterval
+	}
+
+	return &Queue{
+		app:     app,
+		options: options,
+		records: make(chan *core.Record, options.QueueSize),
+		done:    make(chan struct{}),
+	}
+}
+
+func (q *Queue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.started || q.stopped {
+		return
+	}
+	q.started = true
+
+	go q.run()
+}
+
+// Enqueue adds the record to the queue without blocking. It returns false
+// when the queue is full or stopped, in which case the record is dropped.
+func (q *Queue) Enqueue(record *core.Record) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.stopped {
+		q.dropped.Add(1)
+		return false
+	}
+
+	select {
+	case q.records <- record:
+		q.enqueued.Add(1)
+		return true
+	default:
+		q.dropped.Add(1)
+		if !q.dropping.Swap(true) {
+			q.app.Logger().Warn("ingest queue is full, dropping records", slog.Int("queue_size", q.options.QueueSize))
+		}
+		return false
+	}
+}
+
+// Stop stops accepting new records and blocks until everything already
+// queued has been written.
+func (q *Queue) Stop() {
+	q.mu.Lock()
+	if q.stopped {
+		q.mu.Unlock()
+		<-q.done
+		return
+	}
+	q.stopped = true
+	started := q.started
+	close(q.records)
+	q.mu.Unlock()
+
+	if !started {
+		go q.run()
+	}
+	<-q.done
+}
+
+// Shutdown stops the queue like Stop, but gives up waiting for the queued
+// records to be written when the context is done. The records are still
+// written in the background.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		q.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) Running() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	return q.started && !q.stopped
+}
+
+func (q *Queue) Stats() Stats {
+	return Stats{
+		Enqueued: q.enqueued.Load(),
+		Dropped:  q.dropped.Load(),
+		Written:  q.written.Load(),
+		Failed:   q.failed.Load(),
+		Batches:  q.batches.Load(),
+		Pending:  len(q.records),
+	}
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*core.Record, 0, q.options.BatchSize)
+	for {
+		select {
+		case record, ok := <-q.records:
+			if !ok {
+				q.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= q.options.BatchSize {
+				q.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			q.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (q *Queue) flush(batch []*core.Record) {
+	if len(batch) == 0 {
+		return
+	}
+
+	var written, failed uint64
+	err := q.app.RunInTransaction(func(txApp core.App) error {
+		written, failed = 0, 0
+		for _, record := range batch {
+			if err := txApp.Save(record); err != nil {
+				failed++
+				q.app.Logger().Error(
+					"failed to save queued record",
+					slog.String("collection", record.Collection().Name),
+					slog.String("error", err.Error()),
+				)
+				continue
+			}
+			written++
+		}
+		return nil
+	})
+	if err != nil {
+		written, failed = 0, uint64(len(batch))
+		q.app.Logger().Error("failed to write ingest batch", slog.Int("size", len(batch)), slog.String("error", err.Error()))
+	}
+
+	q.batches.Add(1)
+	q.written.Add(written)
+	q.failed.Add(failed)
+
+	if q.dropping.Swap(false) {
+		q.app.Logger().Warn("ingest queue recovered", slog.Uint64("dropped_total", q.dropped.Load()))
+	}
+}
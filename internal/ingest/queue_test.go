@@ -0,0 +1,178 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	// the system migrations, which pocketbase.New registers
+	_ "github.com/pocketbase/pocketbase/migrations"
+)
+
+const testCollection = "telemetry"
+
+// newTestApp returns an app with a telemetry collection in a temporary data
+// directory.
+func newTestApp(tb testing.TB) (core.App, *core.Collection) {
+	tb.Helper()
+
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: tb.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { app.ResetBootstrapState() })
+
+	collection := core.NewBaseCollection(testCollection)
+	collection.Fields.Add(
+		&core.TextField{Name: "device"},
+		&core.NumberField{Name: "temperature"},
+		&core.AutodateField{Name: "timestamp", OnCreate: true},
+	)
+	if err := app.Save(collection); err != nil {
+		tb.Fatal(err)
+	}
+
+	return app, collection
+}
+
+func newRecord(collection *core.Collection, i int) *core.Record {
+	record := core.NewRecord(collection)
+	record.Set("device", "device1")
+	record.Set("temperature", 20+i%10)
+	return record
+}
+
+func countRows(tb testing.TB, app core.App) int64 {
+	tb.Helper()
+
+	count, err := app.CountRecords(testCollection)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return count
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestQueueWritesInBatches(t *testing.T) {
+	app, collection := newTestApp(t)
+	// the interval never passes, so only full batches are written
+	queue := NewQueue(app, Options{QueueSize: 100, BatchSize: 10, FlushInterval: time.Hour})
+	queue.Start()
+
+	for i := range 25 {
+		if !queue.Enqueue(newRecord(collection, i)) {
+			t.Fatalf("record %d was dropped", i)
+		}
+	}
+
+	waitFor(t, "two full batches", func() bool { return queue.Stats().Written == 20 })
+	if stats := queue.Stats(); stats.Batches != 2 {
+		t.Fatalf("batches = %d, want 2", stats.Batches)
+	}
+
+	// the last partial batch is written on stop
+	queue.Stop()
+
+	stats := queue.Stats()
+	if stats.Enqueued != 25 || stats.Written != 25 || stats.Batches != 3 || stats.Failed != 0 || stats.Pending != 0 {
+		t.Fatalf("stats = %+v, want 25 enqueued and written in 3 batches", stats)
+	}
+	if rows := countRows(t, app); rows != 25 {
+		t.Fatalf("rows = %d, want 25", rows)
+	}
+}
+
+func TestQueueFlushesOnInterval(t *testing.T) {
+	app, collection := newTestApp(t)
+	queue := NewQueue(app, Options{QueueSize: 100, BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	queue.Start()
+	defer queue.Stop()
+
+	for i := range 3 {
+		queue.Enqueue(newRecord(collection, i))
+	}
+
+	waitFor(t, "the partial batch", func() bool { return queue.Stats().Written == 3 })
+	if rows := countRows(t, app); rows != 3 {
+		t.Fatalf("rows = %d, want 3", rows)
+	}
+}
+
+func TestQueueCountsDropped(t *testing.T) {
+	app, collection := newTestApp(t)
+	// not started, so nothing is taken from the queue
+	queue := NewQueue(app, Options{QueueSize: 2, BatchSize: 10, FlushInterval: time.Hour})
+
+	for i := range 2 {
+		if !queue.Enqueue(newRecord(collection, i)) {
+			t.Fatalf("record %d was dropped with room in the queue", i)
+		}
+	}
+	if queue.Enqueue(newRecord(collection, 2)) {
+		t.Fatal("record was queued in a full queue")
+	}
+	if stats := queue.Stats(); stats.Dropped != 1 || stats.Pending != 2 {
+		t.Fatalf("stats = %+v, want 1 dropped and 2 pending", stats)
+	}
+
+	queue.Stop()
+	if queue.Enqueue(newRecord(collection, 3)) {
+		t.Fatal("record was queued in a stopped queue")
+	}
+
+	stats := queue.Stats()
+	if stats.Enqueued != 2 || stats.Dropped != 2 || stats.Written != 2 {
+		t.Fatalf("stats = %+v, want 2 enqueued, dropped and written", stats)
+	}
+	if rows := countRows(t, app); rows != 2 {
+		t.Fatalf("rows = %d, want 2", rows)
+	}
+}
+
+func TestQueueCountsFailed(t *testing.T) {
+	app, collection := newTestApp(t)
+	queue := NewQueue(app, Options{QueueSize: 10, BatchSize: 10, FlushInterval: time.Hour})
+	queue.Start()
+
+	queue.Enqueue(newRecord(collection, 0))
+	invalid := newRecord(collection, 1)
+	invalid.Id = "invalid id"
+	queue.Enqueue(invalid)
+	queue.Stop()
+
+	if stats := queue.Stats(); stats.Written != 1 || stats.Failed != 1 {
+		t.Fatalf("stats = %+v, want 1 written and 1 failed", stats)
+	}
+}
+
+func TestQueueShutdownWithDoneContext(t *testing.T) {
+	app, collection := newTestApp(t)
+	queue := NewQueue(app, Options{QueueSize: 10, BatchSize: 10, FlushInterval: time.Hour})
+	queue.Start()
+	queue.Enqueue(newRecord(collection, 0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := queue.Shutdown(ctx); err == nil {
+		// the flush may win the race with the done context
+		t.Log("queue flushed before the context was checked")
+	}
+
+	// the records are still written in the background
+	waitFor(t, "the background flush", func() bool { return queue.Stats().Written == 1 })
+	if queue.Running() {
+		t.Fatal("queue is running after the shutdown")
+	}
+}
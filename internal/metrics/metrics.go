@@ -0,0 +1,282 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the histogram buckets in seconds, from 1ms to 10s.
+var DefaultBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics of the server and writes them in the
+// Prometheus text format. Counters and histograms are updated as things
+// happen, collectors read stats kept elsewhere when the metrics are
+// scraped.
+type Registry struct {
+	mu         sync.Mutex
+	metrics    []metric
+	collectors []func(w *Writer)
+}
+
+type metric interface {
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) Counter(name string, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		vec: vec[*Counter]{name: name, help: help, labels: labels, series: map[string]*series[*Counter]{}},
+	}
+	c.vec.new = func() *Counter { return &Counter{} }
+	r.add(c)
+	return c
+}
+
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		vec: vec[*Histogram]{name: name, help: help, labels: labels, series: map[string]*series[*Histogram]{}},
+	}
+	h.vec.new = func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	}
+	r.add(h)
+	return h
+}
+
+// Collect adds a function writing metrics read on every scrape.
+func (r *Registry) Collect(collector func(w *Writer)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, collector)
+}
+
+func (r *Registry) add(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// Write writes every metric in the Prometheus text format.
+func (r *Registry) Write(out io.Writer) error {
+	r.mu.Lock()
+	metrics := slices.Clone(r.metrics)
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+
+	w := &Writer{out: &errWriter{w: out}}
+	for _, m := range metrics {
+		m.write(w.out)
+	}
+	for _, collect := range collectors {
+		collect(w)
+	}
+
+	return w.out.err
+}
+
+// Writer writes the metrics of collectors.
+type Writer struct {
+	out *errWriter
+}
+
+func (w *Writer) Gauge(name string, help string, value float64) {
+	writeHeader(w.out, name, help, "gauge")
+	fmt.Fprintf(w.out, "%s %s\n", name, formatFloat(value))
+}
+
+func (w *Writer) Counter(name string, help string, value float64) {
+	writeHeader(w.out, name, help, "counter")
+	fmt.Fprintf(w.out, "%s %s\n", name, formatFloat(value))
+}
+
+// vec is a metric with a series per combination of label values.
+type vec[T any] struct {
+	name   string
+	help   string
+	labels []string
+	new    func() T
+
+	mu     sync.Mutex
+	series map[string]*series[T]
+}
+
+type series[T any] struct {
+	values []string
+	metric T
+}
+
+func (v *vec[T]) with(values ...string) T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", v.name, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	s, ok := v.series[key]
+	if !ok {
+		s = &series[T]{values: slices.Clone(values), metric: v.new()}
+		v.series[key] = s
+	}
+	return s.metric
+}
+
+// each calls fn for every series sorted by their label values.
+func (v *vec[T]) each(fn func(labels string, metric T)) {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	all := make([]*series[T], len(keys))
+	for i, key := range keys {
+		all[i] = v.series[key]
+	}
+	v.mu.Unlock()
+
+	for _, s := range all {
+		fn(formatLabels(v.labels, s.values), s.metric)
+	}
+}
+
+type CounterVec struct {
+	vec vec[*Counter]
+}
+
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.vec.with(values...)
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	writeHeader(w, c.vec.name, c.vec.help, "counter")
+	c.vec.each(func(labels string, s *Counter) {
+		fmt.Fprintf(w, "%s%s %d\n", c.vec.name, labels, s.value.Load())
+	})
+}
+
+type Counter struct {
+	value atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+type HistogramVec struct {
+	vec vec[*Histogram]
+}
+
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.vec.with(values...)
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	writeHeader(w, h.vec.name, h.vec.help, "histogram")
+	h.vec.each(func(labels string, s *Histogram) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var cumulative uint64
+		for i, bound := range s.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.vec.name, withLabel(labels, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.vec.name, withLabel(labels, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.vec.name, labels, formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.vec.name, labels, s.count)
+	})
+}
+
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func writeHeader(w io.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escape(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func withLabel(labels string, name string, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return strings.TrimSuffix(labels, "}") + "," + pair + "}"
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// errWriter keeps the first write error, so the metrics can be written
+// without checking every line.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *errWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n, err := w.w.Write(p)
+	w.err = err
+	return n, err
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric func(r *Registry)
+		want   string
+	}{
+		{
+			name: "counter",
+			metric: func(r *Registry) {
+				c := r.Counter("smaas_messages_total", "Messages received.", "topic", "result")
+				c.With("rfid", "ok").Inc()
+				c.With("climate", "ok").Inc()
+				c.With("climate", "ok").Inc()
+				c.With("climate", "rejected")
+			},
+			want: `# HELP smaas_messages_total Messages received.
+# TYPE smaas_messages_total counter
+smaas_messages_total{topic="climate",result="ok"} 2
+smaas_messages_total{topic="climate",result="rejected"} 0
+smaas_messages_total{topic="rfid",result="ok"} 1
+`,
+		},
+		{
+			name: "counter without labels",
+			metric: func(r *Registry) {
+				r.Counter("smaas_restarts_total", "Restarts.").With().Inc()
+			},
+			want: `# HELP smaas_restarts_total Restarts.
+# TYPE smaas_restarts_total counter
+smaas_restarts_total 1
+`,
+		},
+		{
+			name: "label escaping",
+			metric: func(r *Registry) {
+				r.Counter("smaas_errors_total", "Errors.", "error").With("bad \"payload\"\nat C:\\dev").Inc()
+			},
+			want: `# HELP smaas_errors_total Errors.
+# TYPE smaas_errors_total counter
+smaas_errors_total{error="bad \"payload\"\nat C:\\dev"} 1
+`,
+		},
+		{
+			name: "histogram",
+			metric: func(r *Registry) {
+				h := r.Histogram("smaas_handler_seconds", "Handler duration.", []float64{0.1, 1, 10}, "topic")
+				// a value on a bound falls in its bucket
+				for _, v := range []float64{0.05, 0.1, 0.5, 20} {
+					h.With("climate").Observe(v)
+				}
+				h.With("rfid")
+			},
+			want: `# HELP smaas_handler_seconds Handler duration.
+# TYPE smaas_handler_seconds histogram
+smaas_handler_seconds_bucket{topic="climate",le="0.1"} 2
+smaas_handler_seconds_bucket{topic="climate",le="1"} 3
+smaas_handler_seconds_bucket{topic="climate",le="10"} 3
+smaas_handler_seconds_bucket{topic="climate",le="+Inf"} 4
+smaas_handler_seconds_sum{topic="climate"} 20.65
+smaas_handler_seconds_count{topic="climate"} 4
+smaas_handler_seconds_bucket{topic="rfid",le="0.1"} 0
+smaas_handler_seconds_bucket{topic="rfid",le="1"} 0
+smaas_handler_seconds_bucket{topic="rfid",le="10"} 0
+smaas_handler_seconds_bucket{topic="rfid",le="+Inf"} 0
+smaas_handler_seconds_sum{topic="rfid"} 0
+smaas_handler_seconds_count{topic="rfid"} 0
+`,
+		},
+		{
+			name: "histogram without labels",
+			metric: func(r *Registry) {
+				r.Histogram("smaas_flush_seconds", "Flush duration.", []float64{0.5}).With().Observe(0.25)
+			},
+			want: `# HELP smaas_flush_seconds Flush duration.
+# TYPE smaas_flush_seconds histogram
+smaas_flush_seconds_bucket{le="0.5"} 1
+smaas_flush_seconds_bucket{le="+Inf"} 1
+smaas_flush_seconds_sum 0.25
+smaas_flush_seconds_count 1
+`,
+		},
+		{
+			name: "collectors after the metrics",
+			metric: func(r *Registry) {
+				r.Collect(func(w *Writer) {
+					w.Gauge("smaas_clients", "Connected clients.", 3)
+					w.Counter("smaas_bytes_total", "Bytes received.", math.Inf(1))
+				})
+				r.Counter("smaas_drops_total", "Drops.").With().Inc()
+			},
+			want: `# HELP smaas_drops_total Drops.
+# TYPE smaas_drops_total counter
+smaas_drops_total 1
+# HELP smaas_clients Connected clients.
+# TYPE smaas_clients gauge
+smaas_clients 3
+# HELP smaas_bytes_total Bytes received.
+# TYPE smaas_bytes_total counter
+smaas_bytes_total +Inf
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.metric(r)
+
+			var b strings.Builder
+			if err := r.Write(&b); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -115,6 +115,162 @@ func (RelayStateType) EnumDescriptor() ([]byte, []int) {
 	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{1}
 }
 
+type OtaState int32
+
+const (
+	OtaState_OTA_PENDING     OtaState = 0
+	OtaState_OTA_DOWNLOADING OtaState = 1
+	OtaState_OTA_INSTALLING  OtaState = 2
+	OtaState_OTA_SUCCEEDED   OtaState = 3
+	OtaState_OTA_FAILED      OtaState = 4
+)
+
+// Enum value maps for OtaState.
+var (
+	OtaState_name = map[int32]string{
+		0: "OTA_PENDING",
+		1: "OTA_DOWNLOADING",
+		2: "OTA_INSTALLING",
+		3: "OTA_SUCCEEDED",
+		4: "OTA_FAILED",
+	}
+	OtaState_value = map[string]int32{
+		"OTA_PENDING":     0,
+		"OTA_DOWNLOADING": 1,
+		"OTA_INSTALLING":  2,
+		"OTA_SUCCEEDED":   3,
+		"OTA_FAILED":      4,
+	}
+)
+
+func (x OtaState) Enum() *OtaState {
+	p := new(OtaState)
+	*p = x
+	return p
+}
+
+func (x OtaState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OtaState) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_proto_transporter_proto_enumTypes[2].Descriptor()
+}
+
+func (OtaState) Type() protoreflect.EnumType {
+	return &file_pkg_proto_transporter_proto_enumTypes[2]
+}
+
+func (x OtaState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OtaState.Descriptor instead.
+func (OtaState) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{2}
+}
+
+type LogLevel int32
+
+const (
+	LogLevel_LOG_ERROR LogLevel = 0
+	LogLevel_LOG_WARN  LogLevel = 1
+	LogLevel_LOG_INFO  LogLevel = 2
+	LogLevel_LOG_DEBUG LogLevel = 3
+)
+
+// Enum value maps for LogLevel.
+var (
+	LogLevel_name = map[int32]string{
+		0: "LOG_ERROR",
+		1: "LOG_WARN",
+		2: "LOG_INFO",
+		3: "LOG_DEBUG",
+	}
+	LogLevel_value = map[string]int32{
+		"LOG_ERROR": 0,
+		"LOG_WARN":  1,
+		"LOG_INFO":  2,
+		"LOG_DEBUG": 3,
+	}
+)
+
+func (x LogLevel) Enum() *LogLevel {
+	p := new(LogLevel)
+	*p = x
+	return p
+}
+
+func (x LogLevel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogLevel) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_proto_transporter_proto_enumTypes[3].Descriptor()
+}
+
+func (LogLevel) Type() protoreflect.EnumType {
+	return &file_pkg_proto_transporter_proto_enumTypes[3]
+}
+
+func (x LogLevel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogLevel.Descriptor instead.
+func (LogLevel) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{3}
+}
+
+type CommandState int32
+
+const (
+	CommandState_COMMAND_ACKNOWLEDGED CommandState = 0
+	CommandState_COMMAND_SUCCEEDED    CommandState = 1
+	CommandState_COMMAND_FAILED       CommandState = 2
+)
+
+// Enum value maps for CommandState.
+var (
+	CommandState_name = map[int32]string{
+		0: "COMMAND_ACKNOWLEDGED",
+		1: "COMMAND_SUCCEEDED",
+		2: "COMMAND_FAILED",
+	}
+	CommandState_value = map[string]int32{
+		"COMMAND_ACKNOWLEDGED": 0,
+		"COMMAND_SUCCEEDED":    1,
+		"COMMAND_FAILED":       2,
+	}
+)
+
+func (x CommandState) Enum() *CommandState {
+	p := new(CommandState)
+	*p = x
+	return p
+}
+
+func (x CommandState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CommandState) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_proto_transporter_proto_enumTypes[4].Descriptor()
+}
+
+func (CommandState) Type() protoreflect.EnumType {
+	return &file_pkg_proto_transporter_proto_enumTypes[4]
+}
+
+func (x CommandState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CommandState.Descriptor instead.
+func (CommandState) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{4}
+}
+
 type WifiCredentials struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1082,6 +1238,8 @@ type RelayState struct {
 	Type  RelayType      `protobuf:"varint,1,opt,name=type,proto3,enum=proto.RelayType" json:"type,omitempty"`
 	Port  uint32         `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
 	State RelayStateType `protobuf:"varint,3,opt,name=state,proto3,enum=proto.RelayStateType" json:"state,omitempty"`
+	// version of the shadow the device applied, 0 if unknown
+	Version uint64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"`
 }
 
 func (x *RelayState) Reset() {
@@ -1137,6 +1295,13 @@ func (x *RelayState) GetState() RelayStateType {
 	return RelayStateType_OFF
 }
 
+func (x *RelayState) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
 type RelayStateSync struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1301,212 +1466,1955 @@ func (x *LDRData) GetValue() uint32 {
 	return 0
 }
 
-var File_pkg_proto_transporter_proto protoreflect.FileDescriptor
+type ProvisionAnnounce struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_pkg_proto_transporter_proto_rawDesc = []byte{
-	0x0a, 0x1b, 0x70, 0x6b, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x74, 0x72, 0x61, 0x6e,
-	0x73, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x41, 0x0a, 0x0f, 0x57, 0x69, 0x66, 0x69, 0x43, 0x72, 0x65, 0x64,
-	0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x73, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x73, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x70,
-	0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70,
-	0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x22, 0x1b, 0x0a, 0x03, 0x55, 0x49, 0x44, 0x12, 0x14,
-	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76,
-	0x61, 0x6c, 0x75, 0x65, 0x22, 0x21, 0x0a, 0x0f, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x40, 0x0a, 0x10, 0x52, 0x65, 0x67, 0x69, 0x73,
-	0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69,
-	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1c, 0x0a, 0x03, 0x75,
-	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x2e, 0x55, 0x49, 0x44, 0x52, 0x03, 0x75, 0x69, 0x64, 0x22, 0x2d, 0x0a, 0x0d, 0x52, 0x65, 0x76,
-	0x6f, 0x6b, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x03, 0x75, 0x69,
-	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
-	0x55, 0x49, 0x44, 0x52, 0x03, 0x75, 0x69, 0x64, 0x22, 0xe5, 0x01, 0x0a, 0x0c, 0x52, 0x66, 0x69,
-	0x64, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x12, 0x43, 0x0a, 0x10, 0x72, 0x65, 0x67,
-	0x69, 0x73, 0x74, 0x65, 0x72, 0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x67, 0x69,
-	0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x0f, 0x72,
-	0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x46,
-	0x0a, 0x11, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x5f, 0x72, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x48, 0x00, 0x52, 0x10, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3d, 0x0a, 0x0e, 0x72, 0x65, 0x76, 0x6f, 0x6b, 0x65,
-	0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x76, 0x6f, 0x6b, 0x65, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x0d, 0x72, 0x65, 0x76, 0x6f, 0x6b, 0x65, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64,
-	0x22, 0x95, 0x01, 0x0a, 0x07, 0x43, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02,
-	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a,
-	0x64, 0x68, 0x74, 0x32, 0x32, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d,
-	0x52, 0x09, 0x64, 0x68, 0x74, 0x32, 0x32, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x61,
-	0x71, 0x69, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x61,
-	0x71, 0x69, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x68, 0x61, 0x73, 0x5f, 0x62, 0x75,
-	0x7a, 0x7a, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x68, 0x61, 0x73,
-	0x42, 0x75, 0x7a, 0x7a, 0x65, 0x72, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x62, 0x75, 0x7a, 0x7a, 0x65,
-	0x72, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x62, 0x75,
-	0x7a, 0x7a, 0x65, 0x72, 0x50, 0x6f, 0x72, 0x74, 0x22, 0x29, 0x0a, 0x03, 0x4c, 0x44, 0x52, 0x12,
-	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12,
-	0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70,
-	0x6f, 0x72, 0x74, 0x22, 0x7c, 0x0a, 0x06, 0x4d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x0a,
-	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a,
-	0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70, 0x6f, 0x72,
-	0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x50, 0x6f, 0x72, 0x74,
-	0x12, 0x2f, 0x0a, 0x0a, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x6c,
-	0x61, 0x79, 0x54, 0x79, 0x70, 0x65, 0x52, 0x09, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x54, 0x79, 0x70,
-	0x65, 0x22, 0x81, 0x01, 0x0a, 0x0a, 0x46, 0x75, 0x6c, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
-	0x12, 0x2a, 0x0a, 0x08, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43, 0x6c, 0x69, 0x6d, 0x61,
-	0x74, 0x65, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x73, 0x12, 0x1e, 0x0a, 0x04,
-	0x6c, 0x64, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x2e, 0x4c, 0x44, 0x52, 0x52, 0x04, 0x6c, 0x64, 0x72, 0x73, 0x12, 0x27, 0x0a, 0x07,
-	0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x6d, 0x6f,
-	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0xc3, 0x01, 0x0a, 0x0b, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
-	0x54, 0x6f, 0x70, 0x69, 0x63, 0x12, 0x2a, 0x0a, 0x07, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43,
-	0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x48, 0x00, 0x52, 0x07, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74,
-	0x65, 0x12, 0x1e, 0x0a, 0x03, 0x6c, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x44, 0x52, 0x48, 0x00, 0x52, 0x03, 0x6c, 0x64,
-	0x72, 0x12, 0x27, 0x0a, 0x06, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28,
-	0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4d, 0x6f, 0x74, 0x69, 0x6f, 0x6e,
-	0x48, 0x00, 0x52, 0x06, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x34, 0x0a, 0x0b, 0x66, 0x75,
-	0x6c, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x75, 0x6c, 0x6c, 0x43, 0x6f, 0x6e, 0x66,
-	0x69, 0x67, 0x48, 0x00, 0x52, 0x0a, 0x66, 0x75, 0x6c, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
-	0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x20, 0x0a, 0x0e, 0x43,
-	0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x0e, 0x0a,
-	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x22, 0x1c, 0x0a,
-	0x0a, 0x4c, 0x44, 0x52, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x69,
-	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x22, 0x1f, 0x0a, 0x0d, 0x4d,
-	0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02,
-	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x22, 0xa4, 0x01, 0x0a,
-	0x0d, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x31,
-	0x0a, 0x07, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52,
-	0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x48, 0x00, 0x52, 0x07, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74,
-	0x65, 0x12, 0x25, 0x0a, 0x03, 0x6c, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x44, 0x52, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61,
-	0x6c, 0x48, 0x00, 0x52, 0x03, 0x6c, 0x64, 0x72, 0x12, 0x2e, 0x0a, 0x06, 0x6d, 0x6f, 0x74, 0x69,
-	0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x2e, 0x4d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x48, 0x00,
-	0x52, 0x06, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c,
-	0x6f, 0x61, 0x64, 0x22, 0x73, 0x0a, 0x0a, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61, 0x74,
-	0x65, 0x12, 0x24, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32,
-	0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x54, 0x79, 0x70,
-	0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x2b, 0x0a, 0x05, 0x73,
-	0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x2e, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61, 0x74, 0x65, 0x54, 0x79, 0x70,
-	0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x22, 0x10, 0x0a, 0x0e, 0x52, 0x65, 0x6c, 0x61,
-	0x79, 0x53, 0x74, 0x61, 0x74, 0x65, 0x53, 0x79, 0x6e, 0x63, 0x22, 0x6d, 0x0a, 0x0b, 0x43, 0x6c,
-	0x69, 0x6d, 0x61, 0x74, 0x65, 0x44, 0x61, 0x74, 0x61, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x74, 0x65, 0x6d,
-	0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0b,
-	0x74, 0x65, 0x6d, 0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x68,
-	0x75, 0x6d, 0x69, 0x64, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x68,
-	0x75, 0x6d, 0x69, 0x64, 0x69, 0x74, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x71, 0x69, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x61, 0x71, 0x69, 0x22, 0x2f, 0x0a, 0x07, 0x4c, 0x44, 0x52,
-	0x44, 0x61, 0x74, 0x61, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
-	0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0d, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x2a, 0x36, 0x0a, 0x09, 0x52, 0x65,
-	0x6c, 0x61, 0x79, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f,
-	0x57, 0x4e, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x4c, 0x4f, 0x57, 0x5f, 0x44, 0x55, 0x54, 0x59,
-	0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x48, 0x45, 0x41, 0x56, 0x59, 0x5f, 0x44, 0x55, 0x54, 0x59,
-	0x10, 0x02, 0x2a, 0x21, 0x0a, 0x0e, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61, 0x74, 0x65,
-	0x54, 0x79, 0x70, 0x65, 0x12, 0x07, 0x0a, 0x03, 0x4f, 0x46, 0x46, 0x10, 0x00, 0x12, 0x06, 0x0a,
-	0x02, 0x4f, 0x4e, 0x10, 0x01, 0x42, 0x0e, 0x5a, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f,
-	0x72, 0x74, 0x65, 0x72, 0x2f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	Serial    string `protobuf:"bytes,1,opt,name=serial,proto3" json:"serial,omitempty"`
+	ClaimCode string `protobuf:"bytes,2,opt,name=claim_code,json=claimCode,proto3" json:"claim_code,omitempty"`
 }
 
-var (
-	file_pkg_proto_transporter_proto_rawDescOnce sync.Once
-	file_pkg_proto_transporter_proto_rawDescData = file_pkg_proto_transporter_proto_rawDesc
-)
+func (x *ProvisionAnnounce) Reset() {
+	*x = ProvisionAnnounce{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-func file_pkg_proto_transporter_proto_rawDescGZIP() []byte {
-	file_pkg_proto_transporter_proto_rawDescOnce.Do(func() {
-		file_pkg_proto_transporter_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_proto_transporter_proto_rawDescData)
-	})
-	return file_pkg_proto_transporter_proto_rawDescData
+func (x *ProvisionAnnounce) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_pkg_proto_transporter_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_pkg_proto_transporter_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
-var file_pkg_proto_transporter_proto_goTypes = []any{
-	(RelayType)(0),           // 0: proto.RelayType
-	(RelayStateType)(0),      // 1: proto.RelayStateType
-	(*WifiCredentials)(nil),  // 2: proto.WifiCredentials
-	(*UID)(nil),              // 3: proto.UID
-	(*RegisterRequest)(nil),  // 4: proto.RegisterRequest
-	(*RegisterResponse)(nil), // 5: proto.RegisterResponse
-	(*RevokeRequest)(nil),    // 6: proto.RevokeRequest
-	(*RfidEnvelope)(nil),     // 7: proto.RfidEnvelope
-	(*Climate)(nil),          // 8: proto.Climate
-	(*LDR)(nil),              // 9: proto.LDR
-	(*Motion)(nil),           // 10: proto.Motion
-	(*FullConfig)(nil),       // 11: proto.FullConfig
-	(*ConfigTopic)(nil),      // 12: proto.ConfigTopic
-	(*ClimateRemoval)(nil),   // 13: proto.ClimateRemoval
-	(*LDRRemoval)(nil),       // 14: proto.LDRRemoval
-	(*MotionRemoval)(nil),    // 15: proto.MotionRemoval
-	(*ConfigRemoval)(nil),    // 16: proto.ConfigRemoval
-	(*RelayState)(nil),       // 17: proto.RelayState
-	(*RelayStateSync)(nil),   // 18: proto.RelayStateSync
-	(*ClimateData)(nil),      // 19: proto.ClimateData
-	(*LDRData)(nil),          // 20: proto.LDRData
+func (*ProvisionAnnounce) ProtoMessage() {}
+
+func (x *ProvisionAnnounce) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_pkg_proto_transporter_proto_depIdxs = []int32{
-	3,  // 0: proto.RegisterResponse.uid:type_name -> proto.UID
-	3,  // 1: proto.RevokeRequest.uid:type_name -> proto.UID
-	4,  // 2: proto.RfidEnvelope.register_request:type_name -> proto.RegisterRequest
-	5,  // 3: proto.RfidEnvelope.register_response:type_name -> proto.RegisterResponse
-	6,  // 4: proto.RfidEnvelope.revoke_request:type_name -> proto.RevokeRequest
-	0,  // 5: proto.Motion.relay_type:type_name -> proto.RelayType
-	8,  // 6: proto.FullConfig.climates:type_name -> proto.Climate
-	9,  // 7: proto.FullConfig.ldrs:type_name -> proto.LDR
-	10, // 8: proto.FullConfig.motions:type_name -> proto.Motion
-	8,  // 9: proto.ConfigTopic.climate:type_name -> proto.Climate
-	9,  // 10: proto.ConfigTopic.ldr:type_name -> proto.LDR
-	10, // 11: proto.ConfigTopic.motion:type_name -> proto.Motion
-	11, // 12: proto.ConfigTopic.full_config:type_name -> proto.FullConfig
-	13, // 13: proto.ConfigRemoval.climate:type_name -> proto.ClimateRemoval
-	14, // 14: proto.ConfigRemoval.ldr:type_name -> proto.LDRRemoval
-	15, // 15: proto.ConfigRemoval.motion:type_name -> proto.MotionRemoval
-	0,  // 16: proto.RelayState.type:type_name -> proto.RelayType
-	1,  // 17: proto.RelayState.state:type_name -> proto.RelayStateType
-	18, // [18:18] is the sub-list for method output_type
-	18, // [18:18] is the sub-list for method input_type
-	18, // [18:18] is the sub-list for extension type_name
-	18, // [18:18] is the sub-list for extension extendee
-	0,  // [0:18] is the sub-list for field type_name
+
+// Deprecated: Use ProvisionAnnounce.ProtoReflect.Descriptor instead.
+func (*ProvisionAnnounce) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{19}
 }
 
-func init() { file_pkg_proto_transporter_proto_init() }
-func file_pkg_proto_transporter_proto_init() {
-	if File_pkg_proto_transporter_proto != nil {
-		return
+func (x *ProvisionAnnounce) GetSerial() string {
+	if x != nil {
+		return x.Serial
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_pkg_proto_transporter_proto_msgTypes[0].Exporter = func(v any, i int) any {
-			switch v := v.(*WifiCredentials); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+	return ""
+}
+
+func (x *ProvisionAnnounce) GetClaimCode() string {
+	if x != nil {
+		return x.ClaimCode
+	}
+	return ""
+}
+
+type ProvisionCredentials struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DeviceId string `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Username string `protobuf:"bytes,2,opt,name=username,proto3" json:"username,omitempty"`
+	Password string `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`
+}
+
+func (x *ProvisionCredentials) Reset() {
+	*x = ProvisionCredentials{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ProvisionCredentials) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvisionCredentials) ProtoMessage() {}
+
+func (x *ProvisionCredentials) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_pkg_proto_transporter_proto_msgTypes[1].Exporter = func(v any, i int) any {
-			switch v := v.(*UID); i {
-			case 0:
-				return &v.state
-			case 1:
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvisionCredentials.ProtoReflect.Descriptor instead.
+func (*ProvisionCredentials) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ProvisionCredentials) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *ProvisionCredentials) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *ProvisionCredentials) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type OtaUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id      string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	Url     string `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	Sha256  []byte `protobuf:"bytes,4,opt,name=sha256,proto3" json:"sha256,omitempty"`
+	Size    uint32 `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+}
+
+func (x *OtaUpdate) Reset() {
+	*x = OtaUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtaUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtaUpdate) ProtoMessage() {}
+
+func (x *OtaUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtaUpdate.ProtoReflect.Descriptor instead.
+func (*OtaUpdate) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *OtaUpdate) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *OtaUpdate) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *OtaUpdate) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *OtaUpdate) GetSha256() []byte {
+	if x != nil {
+		return x.Sha256
+	}
+	return nil
+}
+
+func (x *OtaUpdate) GetSize() uint32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type OtaCancel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *OtaCancel) Reset() {
+	*x = OtaCancel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtaCancel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtaCancel) ProtoMessage() {}
+
+func (x *OtaCancel) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtaCancel.ProtoReflect.Descriptor instead.
+func (*OtaCancel) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *OtaCancel) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type OtaCommand struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Payload:
+	//
+	//	*OtaCommand_Update
+	//	*OtaCommand_Cancel
+	Payload isOtaCommand_Payload `protobuf_oneof:"payload"`
+}
+
+func (x *OtaCommand) Reset() {
+	*x = OtaCommand{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtaCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtaCommand) ProtoMessage() {}
+
+func (x *OtaCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtaCommand.ProtoReflect.Descriptor instead.
+func (*OtaCommand) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{23}
+}
+
+func (m *OtaCommand) GetPayload() isOtaCommand_Payload {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (x *OtaCommand) GetUpdate() *OtaUpdate {
+	if x, ok := x.GetPayload().(*OtaCommand_Update); ok {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *OtaCommand) GetCancel() *OtaCancel {
+	if x, ok := x.GetPayload().(*OtaCommand_Cancel); ok {
+		return x.Cancel
+	}
+	return nil
+}
+
+type isOtaCommand_Payload interface {
+	isOtaCommand_Payload()
+}
+
+type OtaCommand_Update struct {
+	Update *OtaUpdate `protobuf:"bytes,1,opt,name=update,proto3,oneof"`
+}
+
+type OtaCommand_Cancel struct {
+	Cancel *OtaCancel `protobuf:"bytes,2,opt,name=cancel,proto3,oneof"`
+}
+
+func (*OtaCommand_Update) isOtaCommand_Payload() {}
+
+func (*OtaCommand_Cancel) isOtaCommand_Payload() {}
+
+type OtaStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id       string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State    OtaState `protobuf:"varint,2,opt,name=state,proto3,enum=proto.OtaState" json:"state,omitempty"`
+	Progress uint32   `protobuf:"varint,3,opt,name=progress,proto3" json:"progress,omitempty"`
+	Error    string   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Version  string   `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+}
+
+func (x *OtaStatus) Reset() {
+	*x = OtaStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OtaStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OtaStatus) ProtoMessage() {}
+
+func (x *OtaStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OtaStatus.ProtoReflect.Descriptor instead.
+func (*OtaStatus) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *OtaStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *OtaStatus) GetState() OtaState {
+	if x != nil {
+		return x.State
+	}
+	return OtaState_OTA_PENDING
+}
+
+func (x *OtaStatus) GetProgress() uint32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *OtaStatus) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *OtaStatus) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type DeviceHealth struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Uptime uint32 `protobuf:"varint,1,opt,name=uptime,proto3" json:"uptime,omitempty"`
+	// not sent by boards that cannot measure it
+	FreeHeap         *uint32 `protobuf:"varint,2,opt,name=free_heap,json=freeHeap,proto3,oneof" json:"free_heap,omitempty"`
+	Rssi             int32   `protobuf:"varint,3,opt,name=rssi,proto3" json:"rssi,omitempty"`
+	FirmwareVersion  string  `protobuf:"bytes,4,opt,name=firmware_version,json=firmwareVersion,proto3" json:"firmware_version,omitempty"`
+	ResetReason      string  `protobuf:"bytes,5,opt,name=reset_reason,json=resetReason,proto3" json:"reset_reason,omitempty"`
+	MessagesSent     uint32  `protobuf:"varint,6,opt,name=messages_sent,json=messagesSent,proto3" json:"messages_sent,omitempty"`
+	MessagesReceived uint32  `protobuf:"varint,7,opt,name=messages_received,json=messagesReceived,proto3" json:"messages_received,omitempty"`
+	Reconnects       uint32  `protobuf:"varint,8,opt,name=reconnects,proto3" json:"reconnects,omitempty"`
+}
+
+func (x *DeviceHealth) Reset() {
+	*x = DeviceHealth{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeviceHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceHealth) ProtoMessage() {}
+
+func (x *DeviceHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceHealth.ProtoReflect.Descriptor instead.
+func (*DeviceHealth) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *DeviceHealth) GetUptime() uint32 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+func (x *DeviceHealth) GetFreeHeap() uint32 {
+	if x != nil && x.FreeHeap != nil {
+		return *x.FreeHeap
+	}
+	return 0
+}
+
+func (x *DeviceHealth) GetRssi() int32 {
+	if x != nil {
+		return x.Rssi
+	}
+	return 0
+}
+
+func (x *DeviceHealth) GetFirmwareVersion() string {
+	if x != nil {
+		return x.FirmwareVersion
+	}
+	return ""
+}
+
+func (x *DeviceHealth) GetResetReason() string {
+	if x != nil {
+		return x.ResetReason
+	}
+	return ""
+}
+
+func (x *DeviceHealth) GetMessagesSent() uint32 {
+	if x != nil {
+		return x.MessagesSent
+	}
+	return 0
+}
+
+func (x *DeviceHealth) GetMessagesReceived() uint32 {
+	if x != nil {
+		return x.MessagesReceived
+	}
+	return 0
+}
+
+func (x *DeviceHealth) GetReconnects() uint32 {
+	if x != nil {
+		return x.Reconnects
+	}
+	return 0
+}
+
+type FactoryReset struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Nonce string `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+func (x *FactoryReset) Reset() {
+	*x = FactoryReset{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FactoryReset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FactoryReset) ProtoMessage() {}
+
+func (x *FactoryReset) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FactoryReset.ProtoReflect.Descriptor instead.
+func (*FactoryReset) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *FactoryReset) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+type FactoryResetAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Nonce string `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+func (x *FactoryResetAck) Reset() {
+	*x = FactoryResetAck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FactoryResetAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FactoryResetAck) ProtoMessage() {}
+
+func (x *FactoryResetAck) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FactoryResetAck.ProtoReflect.Descriptor instead.
+func (*FactoryResetAck) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *FactoryResetAck) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+type Reboot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *Reboot) Reset() {
+	*x = Reboot{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Reboot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Reboot) ProtoMessage() {}
+
+func (x *Reboot) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Reboot.ProtoReflect.Descriptor instead.
+func (*Reboot) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{28}
+}
+
+type Identify struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seconds uint32 `protobuf:"varint,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	Beep    bool   `protobuf:"varint,2,opt,name=beep,proto3" json:"beep,omitempty"`
+}
+
+func (x *Identify) Reset() {
+	*x = Identify{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Identify) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Identify) ProtoMessage() {}
+
+func (x *Identify) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Identify.ProtoReflect.Descriptor instead.
+func (*Identify) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *Identify) GetSeconds() uint32 {
+	if x != nil {
+		return x.Seconds
+	}
+	return 0
+}
+
+func (x *Identify) GetBeep() bool {
+	if x != nil {
+		return x.Beep
+	}
+	return false
+}
+
+type HealthReportRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *HealthReportRequest) Reset() {
+	*x = HealthReportRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HealthReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthReportRequest) ProtoMessage() {}
+
+func (x *HealthReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthReportRequest.ProtoReflect.Descriptor instead.
+func (*HealthReportRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{30}
+}
+
+type ConfigDumpRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ConfigDumpRequest) Reset() {
+	*x = ConfigDumpRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[31]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ConfigDumpRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigDumpRequest) ProtoMessage() {}
+
+func (x *ConfigDumpRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[31]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigDumpRequest.ProtoReflect.Descriptor instead.
+func (*ConfigDumpRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{31}
+}
+
+type SetLogLevel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Level LogLevel `protobuf:"varint,1,opt,name=level,proto3,enum=proto.LogLevel" json:"level,omitempty"`
+}
+
+func (x *SetLogLevel) Reset() {
+	*x = SetLogLevel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[32]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetLogLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevel) ProtoMessage() {}
+
+func (x *SetLogLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[32]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevel.ProtoReflect.Descriptor instead.
+func (*SetLogLevel) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *SetLogLevel) GetLevel() LogLevel {
+	if x != nil {
+		return x.Level
+	}
+	return LogLevel_LOG_ERROR
+}
+
+type DeviceCommand struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// Types that are assignable to Command:
+	//
+	//	*DeviceCommand_Reboot
+	//	*DeviceCommand_Identify
+	//	*DeviceCommand_HealthReport
+	//	*DeviceCommand_ConfigDump
+	//	*DeviceCommand_SetLogLevel
+	Command isDeviceCommand_Command `protobuf_oneof:"command"`
+}
+
+func (x *DeviceCommand) Reset() {
+	*x = DeviceCommand{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[33]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeviceCommand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceCommand) ProtoMessage() {}
+
+func (x *DeviceCommand) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[33]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceCommand.ProtoReflect.Descriptor instead.
+func (*DeviceCommand) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *DeviceCommand) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (m *DeviceCommand) GetCommand() isDeviceCommand_Command {
+	if m != nil {
+		return m.Command
+	}
+	return nil
+}
+
+func (x *DeviceCommand) GetReboot() *Reboot {
+	if x, ok := x.GetCommand().(*DeviceCommand_Reboot); ok {
+		return x.Reboot
+	}
+	return nil
+}
+
+func (x *DeviceCommand) GetIdentify() *Identify {
+	if x, ok := x.GetCommand().(*DeviceCommand_Identify); ok {
+		return x.Identify
+	}
+	return nil
+}
+
+func (x *DeviceCommand) GetHealthReport() *HealthReportRequest {
+	if x, ok := x.GetCommand().(*DeviceCommand_HealthReport); ok {
+		return x.HealthReport
+	}
+	return nil
+}
+
+func (x *DeviceCommand) GetConfigDump() *ConfigDumpRequest {
+	if x, ok := x.GetCommand().(*DeviceCommand_ConfigDump); ok {
+		return x.ConfigDump
+	}
+	return nil
+}
+
+func (x *DeviceCommand) GetSetLogLevel() *SetLogLevel {
+	if x, ok := x.GetCommand().(*DeviceCommand_SetLogLevel); ok {
+		return x.SetLogLevel
+	}
+	return nil
+}
+
+type isDeviceCommand_Command interface {
+	isDeviceCommand_Command()
+}
+
+type DeviceCommand_Reboot struct {
+	Reboot *Reboot `protobuf:"bytes,2,opt,name=reboot,proto3,oneof"`
+}
+
+type DeviceCommand_Identify struct {
+	Identify *Identify `protobuf:"bytes,3,opt,name=identify,proto3,oneof"`
+}
+
+type DeviceCommand_HealthReport struct {
+	HealthReport *HealthReportRequest `protobuf:"bytes,4,opt,name=health_report,json=healthReport,proto3,oneof"`
+}
+
+type DeviceCommand_ConfigDump struct {
+	ConfigDump *ConfigDumpRequest `protobuf:"bytes,5,opt,name=config_dump,json=configDump,proto3,oneof"`
+}
+
+type DeviceCommand_SetLogLevel struct {
+	SetLogLevel *SetLogLevel `protobuf:"bytes,6,opt,name=set_log_level,json=setLogLevel,proto3,oneof"`
+}
+
+func (*DeviceCommand_Reboot) isDeviceCommand_Command() {}
+
+func (*DeviceCommand_Identify) isDeviceCommand_Command() {}
+
+func (*DeviceCommand_HealthReport) isDeviceCommand_Command() {}
+
+func (*DeviceCommand_ConfigDump) isDeviceCommand_Command() {}
+
+func (*DeviceCommand_SetLogLevel) isDeviceCommand_Command() {}
+
+type CommandResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id    string       `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State CommandState `protobuf:"varint,2,opt,name=state,proto3,enum=proto.CommandState" json:"state,omitempty"`
+	Error string       `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	// Types that are assignable to Result:
+	//
+	//	*CommandResult_Health
+	//	*CommandResult_Config
+	Result isCommandResult_Result `protobuf_oneof:"result"`
+}
+
+func (x *CommandResult) Reset() {
+	*x = CommandResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[34]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CommandResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommandResult) ProtoMessage() {}
+
+func (x *CommandResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[34]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommandResult.ProtoReflect.Descriptor instead.
+func (*CommandResult) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *CommandResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *CommandResult) GetState() CommandState {
+	if x != nil {
+		return x.State
+	}
+	return CommandState_COMMAND_ACKNOWLEDGED
+}
+
+func (x *CommandResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (m *CommandResult) GetResult() isCommandResult_Result {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+func (x *CommandResult) GetHealth() *DeviceHealth {
+	if x, ok := x.GetResult().(*CommandResult_Health); ok {
+		return x.Health
+	}
+	return nil
+}
+
+func (x *CommandResult) GetConfig() *FullConfig {
+	if x, ok := x.GetResult().(*CommandResult_Config); ok {
+		return x.Config
+	}
+	return nil
+}
+
+type isCommandResult_Result interface {
+	isCommandResult_Result()
+}
+
+type CommandResult_Health struct {
+	Health *DeviceHealth `protobuf:"bytes,4,opt,name=health,proto3,oneof"`
+}
+
+type CommandResult_Config struct {
+	Config *FullConfig `protobuf:"bytes,5,opt,name=config,proto3,oneof"`
+}
+
+func (*CommandResult_Health) isCommandResult_Result() {}
+
+func (*CommandResult_Config) isCommandResult_Result() {}
+
+type ShadowDelta struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version uint64           `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Relays  []*RelayState    `protobuf:"bytes,2,rep,name=relays,proto3" json:"relays,omitempty"`
+	Config  *FullConfig      `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	Removed []*ConfigRemoval `protobuf:"bytes,4,rep,name=removed,proto3" json:"removed,omitempty"`
+}
+
+func (x *ShadowDelta) Reset() {
+	*x = ShadowDelta{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[35]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShadowDelta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShadowDelta) ProtoMessage() {}
+
+func (x *ShadowDelta) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[35]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShadowDelta.ProtoReflect.Descriptor instead.
+func (*ShadowDelta) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ShadowDelta) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ShadowDelta) GetRelays() []*RelayState {
+	if x != nil {
+		return x.Relays
+	}
+	return nil
+}
+
+func (x *ShadowDelta) GetConfig() *FullConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *ShadowDelta) GetRemoved() []*ConfigRemoval {
+	if x != nil {
+		return x.Removed
+	}
+	return nil
+}
+
+type ShadowReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Version uint64        `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Relays  []*RelayState `protobuf:"bytes,2,rep,name=relays,proto3" json:"relays,omitempty"`
+	Config  *FullConfig   `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+}
+
+func (x *ShadowReport) Reset() {
+	*x = ShadowReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[36]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShadowReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShadowReport) ProtoMessage() {}
+
+func (x *ShadowReport) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[36]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShadowReport.ProtoReflect.Descriptor instead.
+func (*ShadowReport) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *ShadowReport) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ShadowReport) GetRelays() []*RelayState {
+	if x != nil {
+		return x.Relays
+	}
+	return nil
+}
+
+func (x *ShadowReport) GetConfig() *FullConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+// Capabilities are announced by a device after it connected. Boards that
+// never announce them speak protocol version 1.
+type Capabilities struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ProtocolVersion uint32 `protobuf:"varint,1,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	// climate, ldr and motion
+	Sensors []string    `protobuf:"bytes,2,rep,name=sensors,proto3" json:"sensors,omitempty"`
+	Relays  []RelayType `protobuf:"varint,3,rep,packed,name=relays,proto3,enum=proto.RelayType" json:"relays,omitempty"`
+	// shadow, ota, health and commands
+	Features []string `protobuf:"bytes,4,rep,name=features,proto3" json:"features,omitempty"`
+}
+
+func (x *Capabilities) Reset() {
+	*x = Capabilities{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[37]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Capabilities) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capabilities) ProtoMessage() {}
+
+func (x *Capabilities) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[37]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capabilities.ProtoReflect.Descriptor instead.
+func (*Capabilities) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *Capabilities) GetProtocolVersion() uint32 {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *Capabilities) GetSensors() []string {
+	if x != nil {
+		return x.Sensors
+	}
+	return nil
+}
+
+func (x *Capabilities) GetRelays() []RelayType {
+	if x != nil {
+		return x.Relays
+	}
+	return nil
+}
+
+func (x *Capabilities) GetFeatures() []string {
+	if x != nil {
+		return x.Features
+	}
+	return nil
+}
+
+// Metric is a single value of a sensor reading, named like the metric in
+// the sensor type catalog, e.g. temperature or soil_moisture.
+type Metric struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name  string  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value float64 `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *Metric) Reset() {
+	*x = Metric{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[38]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Metric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metric) ProtoMessage() {}
+
+func (x *Metric) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[38]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
+func (*Metric) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *Metric) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Metric) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+// SensorReading is sent by sensors of any type in the catalog on
+// arduino/{device_id}/reading.
+type SensorReading struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id      uint32    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type    string    `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Metrics []*Metric `protobuf:"bytes,3,rep,name=metrics,proto3" json:"metrics,omitempty"`
+}
+
+func (x *SensorReading) Reset() {
+	*x = SensorReading{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[39]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SensorReading) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SensorReading) ProtoMessage() {}
+
+func (x *SensorReading) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[39]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SensorReading.ProtoReflect.Descriptor instead.
+func (*SensorReading) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *SensorReading) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SensorReading) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *SensorReading) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+// SensorConfig configures a sensor of any type in the catalog. Ports are
+// named like the port requirements of the type.
+type SensorConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id       uint32             `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type     string             `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Ports    map[string]uint32  `protobuf:"bytes,3,rep,name=ports,proto3" json:"ports,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Settings map[string]float64 `protobuf:"bytes,4,rep,name=settings,proto3" json:"settings,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"fixed64,2,opt,name=value,proto3"`
+}
+
+func (x *SensorConfig) Reset() {
+	*x = SensorConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[40]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SensorConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SensorConfig) ProtoMessage() {}
+
+func (x *SensorConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[40]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SensorConfig.ProtoReflect.Descriptor instead.
+func (*SensorConfig) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *SensorConfig) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SensorConfig) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *SensorConfig) GetPorts() map[string]uint32 {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+func (x *SensorConfig) GetSettings() map[string]float64 {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+type SensorRemoval struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id   uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+}
+
+func (x *SensorRemoval) Reset() {
+	*x = SensorRemoval{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_proto_transporter_proto_msgTypes[41]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SensorRemoval) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SensorRemoval) ProtoMessage() {}
+
+func (x *SensorRemoval) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_transporter_proto_msgTypes[41]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SensorRemoval.ProtoReflect.Descriptor instead.
+func (*SensorRemoval) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_transporter_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *SensorRemoval) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *SensorRemoval) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+var File_pkg_proto_transporter_proto protoreflect.FileDescriptor
+
+var file_pkg_proto_transporter_proto_rawDesc = []byte{
+	0x0a, 0x1b, 0x70, 0x6b, 0x67, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x74, 0x72, 0x61, 0x6e,
+	0x73, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x05, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x41, 0x0a, 0x0f, 0x57, 0x69, 0x66, 0x69, 0x43, 0x72, 0x65, 0x64,
+	0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x73, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x73, 0x73, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x70,
+	0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70,
+	0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x22, 0x1b, 0x0a, 0x03, 0x55, 0x49, 0x44, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x22, 0x21, 0x0a, 0x0f, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x40, 0x0a, 0x10, 0x52, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1c, 0x0a, 0x03, 0x75,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x55, 0x49, 0x44, 0x52, 0x03, 0x75, 0x69, 0x64, 0x22, 0x2d, 0x0a, 0x0d, 0x52, 0x65, 0x76,
+	0x6f, 0x6b, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1c, 0x0a, 0x03, 0x75, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x55, 0x49, 0x44, 0x52, 0x03, 0x75, 0x69, 0x64, 0x22, 0xe5, 0x01, 0x0a, 0x0c, 0x52, 0x66, 0x69,
+	0x64, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x12, 0x43, 0x0a, 0x10, 0x72, 0x65, 0x67,
+	0x69, 0x73, 0x74, 0x65, 0x72, 0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x67, 0x69,
+	0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x0f, 0x72,
+	0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x46,
+	0x0a, 0x11, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x5f, 0x72, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x2e, 0x52, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x48, 0x00, 0x52, 0x10, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x65, 0x72, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3d, 0x0a, 0x0e, 0x72, 0x65, 0x76, 0x6f, 0x6b, 0x65,
+	0x5f, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x76, 0x6f, 0x6b, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x0d, 0x72, 0x65, 0x76, 0x6f, 0x6b, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64,
+	0x22, 0x95, 0x01, 0x0a, 0x07, 0x43, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x1d, 0x0a, 0x0a,
+	0x64, 0x68, 0x74, 0x32, 0x32, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x09, 0x64, 0x68, 0x74, 0x32, 0x32, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x61,
+	0x71, 0x69, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x61,
+	0x71, 0x69, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x68, 0x61, 0x73, 0x5f, 0x62, 0x75,
+	0x7a, 0x7a, 0x65, 0x72, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x68, 0x61, 0x73,
+	0x42, 0x75, 0x7a, 0x7a, 0x65, 0x72, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x62, 0x75, 0x7a, 0x7a, 0x65,
+	0x72, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x62, 0x75,
+	0x7a, 0x7a, 0x65, 0x72, 0x50, 0x6f, 0x72, 0x74, 0x22, 0x29, 0x0a, 0x03, 0x4c, 0x44, 0x52, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12,
+	0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70,
+	0x6f, 0x72, 0x74, 0x22, 0x7c, 0x0a, 0x06, 0x4d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70, 0x6f, 0x72,
+	0x74, 0x12, 0x1d, 0x0a, 0x0a, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x5f, 0x70, 0x6f, 0x72, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x50, 0x6f, 0x72, 0x74,
+	0x12, 0x2f, 0x0a, 0x0a, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x6c,
+	0x61, 0x79, 0x54, 0x79, 0x70, 0x65, 0x52, 0x09, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x54, 0x79, 0x70,
+	0x65, 0x22, 0x81, 0x01, 0x0a, 0x0a, 0x46, 0x75, 0x6c, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x12, 0x2a, 0x0a, 0x08, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43, 0x6c, 0x69, 0x6d, 0x61,
+	0x74, 0x65, 0x52, 0x08, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x73, 0x12, 0x1e, 0x0a, 0x04,
+	0x6c, 0x64, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x4c, 0x44, 0x52, 0x52, 0x04, 0x6c, 0x64, 0x72, 0x73, 0x12, 0x27, 0x0a, 0x07,
+	0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x6d, 0x6f,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x22, 0xc3, 0x01, 0x0a, 0x0b, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x54, 0x6f, 0x70, 0x69, 0x63, 0x12, 0x2a, 0x0a, 0x07, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43,
+	0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x48, 0x00, 0x52, 0x07, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74,
+	0x65, 0x12, 0x1e, 0x0a, 0x03, 0x6c, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0a,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x44, 0x52, 0x48, 0x00, 0x52, 0x03, 0x6c, 0x64,
+	0x72, 0x12, 0x27, 0x0a, 0x06, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4d, 0x6f, 0x74, 0x69, 0x6f, 0x6e,
+	0x48, 0x00, 0x52, 0x06, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x34, 0x0a, 0x0b, 0x66, 0x75,
+	0x6c, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x75, 0x6c, 0x6c, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x48, 0x00, 0x52, 0x0a, 0x66, 0x75, 0x6c, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x20, 0x0a, 0x0e, 0x43,
+	0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x22, 0x1c, 0x0a,
+	0x0a, 0x4c, 0x44, 0x52, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x22, 0x1f, 0x0a, 0x0d, 0x4d,
+	0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x22, 0xa4, 0x01, 0x0a,
+	0x0d, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x31,
+	0x0a, 0x07, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x15, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x52,
+	0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x48, 0x00, 0x52, 0x07, 0x63, 0x6c, 0x69, 0x6d, 0x61, 0x74,
+	0x65, 0x12, 0x25, 0x0a, 0x03, 0x6c, 0x64, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x44, 0x52, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61,
+	0x6c, 0x48, 0x00, 0x52, 0x03, 0x6c, 0x64, 0x72, 0x12, 0x2e, 0x0a, 0x06, 0x6d, 0x6f, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x2e, 0x4d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x48, 0x00,
+	0x52, 0x06, 0x6d, 0x6f, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c,
+	0x6f, 0x61, 0x64, 0x22, 0x8d, 0x01, 0x0a, 0x0a, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x12, 0x24, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x54, 0x79,
+	0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x2b, 0x0a, 0x05,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61, 0x74, 0x65, 0x54, 0x79,
+	0x70, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x22, 0x10, 0x0a, 0x0e, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x53, 0x79, 0x6e, 0x63, 0x22, 0x6d, 0x0a, 0x0b, 0x43, 0x6c, 0x69, 0x6d, 0x61, 0x74, 0x65,
+	0x44, 0x61, 0x74, 0x61, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x02, 0x69, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x74, 0x65, 0x6d, 0x70, 0x65, 0x72, 0x61, 0x74,
+	0x75, 0x72, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0b, 0x74, 0x65, 0x6d, 0x70, 0x65,
+	0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x68, 0x75, 0x6d, 0x69, 0x64, 0x69,
+	0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x68, 0x75, 0x6d, 0x69, 0x64, 0x69,
+	0x74, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x71, 0x69, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x03, 0x61, 0x71, 0x69, 0x22, 0x2f, 0x0a, 0x07, 0x4c, 0x44, 0x52, 0x44, 0x61, 0x74, 0x61, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12,
+	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x4a, 0x0a, 0x11, 0x50, 0x72, 0x6f, 0x76, 0x69, 0x73, 0x69,
+	0x6f, 0x6e, 0x41, 0x6e, 0x6e, 0x6f, 0x75, 0x6e, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x65,
+	0x72, 0x69, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x65, 0x72, 0x69,
+	0x61, 0x6c, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x5f, 0x63, 0x6f, 0x64, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x63, 0x6c, 0x61, 0x69, 0x6d, 0x43, 0x6f, 0x64,
+	0x65, 0x22, 0x6b, 0x0a, 0x14, 0x50, 0x72, 0x6f, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x43, 0x72,
+	0x65, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x61, 0x6c, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x64, 0x65, 0x76,
+	0x69, 0x63, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x64, 0x65,
+	0x76, 0x69, 0x63, 0x65, 0x49, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64, 0x22, 0x73,
+	0x0a, 0x09, 0x4f, 0x74, 0x61, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x72, 0x6c, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x75, 0x72, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35,
+	0x36, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x73, 0x68, 0x61, 0x32, 0x35, 0x36, 0x12,
+	0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73,
+	0x69, 0x7a, 0x65, 0x22, 0x1b, 0x0a, 0x09, 0x4f, 0x74, 0x61, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c,
+	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64,
+	0x22, 0x6f, 0x0a, 0x0a, 0x4f, 0x74, 0x61, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x12, 0x2a,
+	0x0a, 0x06, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x61, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x48, 0x00, 0x52, 0x06, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x2a, 0x0a, 0x06, 0x63, 0x61,
+	0x6e, 0x63, 0x65, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x61, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x48, 0x00, 0x52, 0x06,
+	0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x42, 0x09, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61,
+	0x64, 0x22, 0x8e, 0x01, 0x0a, 0x09, 0x4f, 0x74, 0x61, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12,
+	0x25, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0f,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4f, 0x74, 0x61, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52,
+	0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65,
+	0x73, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65,
+	0x73, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x22, 0xaa, 0x02, 0x0a, 0x0c, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x48, 0x65, 0x61,
+	0x6c, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x75, 0x70, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x06, 0x75, 0x70, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x20, 0x0a, 0x09, 0x66,
+	0x72, 0x65, 0x65, 0x5f, 0x68, 0x65, 0x61, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00,
+	0x52, 0x08, 0x66, 0x72, 0x65, 0x65, 0x48, 0x65, 0x61, 0x70, 0x88, 0x01, 0x01, 0x12, 0x12, 0x0a,
+	0x04, 0x72, 0x73, 0x73, 0x69, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x72, 0x73, 0x73,
+	0x69, 0x12, 0x29, 0x0a, 0x10, 0x66, 0x69, 0x72, 0x6d, 0x77, 0x61, 0x72, 0x65, 0x5f, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x66, 0x69, 0x72,
+	0x6d, 0x77, 0x61, 0x72, 0x65, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c,
+	0x72, 0x65, 0x73, 0x65, 0x74, 0x5f, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x73, 0x65, 0x74, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12,
+	0x23, 0x0a, 0x0d, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x5f, 0x73, 0x65, 0x6e, 0x74,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73,
+	0x53, 0x65, 0x6e, 0x74, 0x12, 0x2b, 0x0a, 0x11, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73,
+	0x5f, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x10, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73, 0x52, 0x65, 0x63, 0x65, 0x69, 0x76, 0x65,
+	0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x73, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x72, 0x65, 0x63, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74,
+	0x73, 0x42, 0x0c, 0x0a, 0x0a, 0x5f, 0x66, 0x72, 0x65, 0x65, 0x5f, 0x68, 0x65, 0x61, 0x70, 0x22,
+	0x24, 0x0a, 0x0c, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x79, 0x52, 0x65, 0x73, 0x65, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x22, 0x27, 0x0a, 0x0f, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x79,
+	0x52, 0x65, 0x73, 0x65, 0x74, 0x41, 0x63, 0x6b, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x22, 0x08,
+	0x0a, 0x06, 0x52, 0x65, 0x62, 0x6f, 0x6f, 0x74, 0x22, 0x38, 0x0a, 0x08, 0x49, 0x64, 0x65, 0x6e,
+	0x74, 0x69, 0x66, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x12,
+	0x0a, 0x04, 0x62, 0x65, 0x65, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x62, 0x65,
+	0x65, 0x70, 0x22, 0x15, 0x0a, 0x13, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x52, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x13, 0x0a, 0x11, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x44, 0x75, 0x6d, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x34,
+	0x0a, 0x0b, 0x53, 0x65, 0x74, 0x4c, 0x6f, 0x67, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x25, 0x0a,
+	0x05, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0f, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4c, 0x6f, 0x67, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52, 0x05, 0x6c,
+	0x65, 0x76, 0x65, 0x6c, 0x22, 0xbc, 0x02, 0x0a, 0x0d, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x43,
+	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x27, 0x0a, 0x06, 0x72, 0x65, 0x62, 0x6f, 0x6f, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52,
+	0x65, 0x62, 0x6f, 0x6f, 0x74, 0x48, 0x00, 0x52, 0x06, 0x72, 0x65, 0x62, 0x6f, 0x6f, 0x74, 0x12,
+	0x2d, 0x0a, 0x08, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0f, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x49, 0x64, 0x65, 0x6e, 0x74, 0x69,
+	0x66, 0x79, 0x48, 0x00, 0x52, 0x08, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x79, 0x12, 0x41,
+	0x0a, 0x0d, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x48, 0x65,
+	0x61, 0x6c, 0x74, 0x68, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x48, 0x00, 0x52, 0x0c, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x52, 0x65, 0x70, 0x6f, 0x72,
+	0x74, 0x12, 0x3b, 0x0a, 0x0b, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x5f, 0x64, 0x75, 0x6d, 0x70,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43,
+	0x6f, 0x6e, 0x66, 0x69, 0x67, 0x44, 0x75, 0x6d, 0x70, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x48, 0x00, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x44, 0x75, 0x6d, 0x70, 0x12, 0x38,
+	0x0a, 0x0d, 0x73, 0x65, 0x74, 0x5f, 0x6c, 0x6f, 0x67, 0x5f, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x65,
+	0x74, 0x4c, 0x6f, 0x67, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x48, 0x00, 0x52, 0x0b, 0x73, 0x65, 0x74,
+	0x4c, 0x6f, 0x67, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x42, 0x09, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d,
+	0x61, 0x6e, 0x64, 0x22, 0xc6, 0x01, 0x0a, 0x0d, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x29, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43, 0x6f, 0x6d,
+	0x6d, 0x61, 0x6e, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x2d, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x44,
+	0x65, 0x76, 0x69, 0x63, 0x65, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x48, 0x00, 0x52, 0x06, 0x68,
+	0x65, 0x61, 0x6c, 0x74, 0x68, 0x12, 0x2b, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x75,
+	0x6c, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x48, 0x00, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66,
+	0x69, 0x67, 0x42, 0x08, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0xad, 0x01, 0x0a,
+	0x0b, 0x53, 0x68, 0x61, 0x64, 0x6f, 0x77, 0x44, 0x65, 0x6c, 0x74, 0x61, 0x12, 0x18, 0x0a, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a, 0x06, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52,
+	0x65, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x06, 0x72, 0x65, 0x6c, 0x61, 0x79,
+	0x73, 0x12, 0x29, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x75, 0x6c, 0x6c, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x2e, 0x0a, 0x07,
+	0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x65, 0x6d, 0x6f,
+	0x76, 0x61, 0x6c, 0x52, 0x07, 0x72, 0x65, 0x6d, 0x6f, 0x76, 0x65, 0x64, 0x22, 0x7e, 0x0a, 0x0c,
+	0x53, 0x68, 0x61, 0x64, 0x6f, 0x77, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x18, 0x0a, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x29, 0x0a, 0x06, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x73,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52,
+	0x65, 0x6c, 0x61, 0x79, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x06, 0x72, 0x65, 0x6c, 0x61, 0x79,
+	0x73, 0x12, 0x29, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x11, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x46, 0x75, 0x6c, 0x6c, 0x43, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x52, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x22, 0x99, 0x01, 0x0a,
+	0x0c, 0x43, 0x61, 0x70, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x69, 0x65, 0x73, 0x12, 0x29, 0x0a,
+	0x10, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f,
+	0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x6e, 0x73,
+	0x6f, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x6e, 0x73, 0x6f,
+	0x72, 0x73, 0x12, 0x28, 0x0a, 0x06, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x0e, 0x32, 0x10, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x52, 0x65, 0x6c, 0x61, 0x79,
+	0x54, 0x79, 0x70, 0x65, 0x52, 0x06, 0x72, 0x65, 0x6c, 0x61, 0x79, 0x73, 0x12, 0x1a, 0x0a, 0x08,
+	0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08,
+	0x66, 0x65, 0x61, 0x74, 0x75, 0x72, 0x65, 0x73, 0x22, 0x32, 0x0a, 0x06, 0x4d, 0x65, 0x74, 0x72,
+	0x69, 0x63, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x5c, 0x0a, 0x0d,
+	0x53, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x52, 0x65, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70,
+	0x65, 0x12, 0x27, 0x0a, 0x07, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x4d, 0x65, 0x74, 0x72, 0x69,
+	0x63, 0x52, 0x07, 0x6d, 0x65, 0x74, 0x72, 0x69, 0x63, 0x73, 0x22, 0x9e, 0x02, 0x0a, 0x0c, 0x53,
+	0x65, 0x6e, 0x73, 0x6f, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x0e, 0x0a, 0x02, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x74,
+	0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12,
+	0x34, 0x0a, 0x05, 0x70, 0x6f, 0x72, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e, 0x53, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x67, 0x2e, 0x50, 0x6f, 0x72, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x05,
+	0x70, 0x6f, 0x72, 0x74, 0x73, 0x12, 0x3d, 0x0a, 0x08, 0x73, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67,
+	0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2e,
+	0x53, 0x65, 0x6e, 0x73, 0x6f, 0x72, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x2e, 0x53, 0x65, 0x74,
+	0x74, 0x69, 0x6e, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x73, 0x65, 0x74, 0x74,
+	0x69, 0x6e, 0x67, 0x73, 0x1a, 0x38, 0x0a, 0x0a, 0x50, 0x6f, 0x72, 0x74, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3b,
+	0x0a, 0x0d, 0x53, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x33, 0x0a, 0x0d, 0x53,
+	0x65, 0x6e, 0x73, 0x6f, 0x72, 0x52, 0x65, 0x6d, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04,
+	0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65,
+	0x2a, 0x36, 0x0a, 0x09, 0x52, 0x65, 0x6c, 0x61, 0x79, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a,
+	0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x4c, 0x4f,
+	0x57, 0x5f, 0x44, 0x55, 0x54, 0x59, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x48, 0x45, 0x41, 0x56,
+	0x59, 0x5f, 0x44, 0x55, 0x54, 0x59, 0x10, 0x02, 0x2a, 0x21, 0x0a, 0x0e, 0x52, 0x65, 0x6c, 0x61,
+	0x79, 0x53, 0x74, 0x61, 0x74, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x07, 0x0a, 0x03, 0x4f, 0x46,
+	0x46, 0x10, 0x00, 0x12, 0x06, 0x0a, 0x02, 0x4f, 0x4e, 0x10, 0x01, 0x2a, 0x67, 0x0a, 0x08, 0x4f,
+	0x74, 0x61, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x0f, 0x0a, 0x0b, 0x4f, 0x54, 0x41, 0x5f, 0x50,
+	0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x13, 0x0a, 0x0f, 0x4f, 0x54, 0x41, 0x5f,
+	0x44, 0x4f, 0x57, 0x4e, 0x4c, 0x4f, 0x41, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x12, 0x0a,
+	0x0e, 0x4f, 0x54, 0x41, 0x5f, 0x49, 0x4e, 0x53, 0x54, 0x41, 0x4c, 0x4c, 0x49, 0x4e, 0x47, 0x10,
+	0x02, 0x12, 0x11, 0x0a, 0x0d, 0x4f, 0x54, 0x41, 0x5f, 0x53, 0x55, 0x43, 0x43, 0x45, 0x45, 0x44,
+	0x45, 0x44, 0x10, 0x03, 0x12, 0x0e, 0x0a, 0x0a, 0x4f, 0x54, 0x41, 0x5f, 0x46, 0x41, 0x49, 0x4c,
+	0x45, 0x44, 0x10, 0x04, 0x2a, 0x44, 0x0a, 0x08, 0x4c, 0x6f, 0x67, 0x4c, 0x65, 0x76, 0x65, 0x6c,
+	0x12, 0x0d, 0x0a, 0x09, 0x4c, 0x4f, 0x47, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x00, 0x12,
+	0x0c, 0x0a, 0x08, 0x4c, 0x4f, 0x47, 0x5f, 0x57, 0x41, 0x52, 0x4e, 0x10, 0x01, 0x12, 0x0c, 0x0a,
+	0x08, 0x4c, 0x4f, 0x47, 0x5f, 0x49, 0x4e, 0x46, 0x4f, 0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09, 0x4c,
+	0x4f, 0x47, 0x5f, 0x44, 0x45, 0x42, 0x55, 0x47, 0x10, 0x03, 0x2a, 0x53, 0x0a, 0x0c, 0x43, 0x6f,
+	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x18, 0x0a, 0x14, 0x43, 0x4f,
+	0x4d, 0x4d, 0x41, 0x4e, 0x44, 0x5f, 0x41, 0x43, 0x4b, 0x4e, 0x4f, 0x57, 0x4c, 0x45, 0x44, 0x47,
+	0x45, 0x44, 0x10, 0x00, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x4f, 0x4d, 0x4d, 0x41, 0x4e, 0x44, 0x5f,
+	0x53, 0x55, 0x43, 0x43, 0x45, 0x45, 0x44, 0x45, 0x44, 0x10, 0x01, 0x12, 0x12, 0x0a, 0x0e, 0x43,
+	0x4f, 0x4d, 0x4d, 0x41, 0x4e, 0x44, 0x5f, 0x46, 0x41, 0x49, 0x4c, 0x45, 0x44, 0x10, 0x02, 0x42,
+	0x0e, 0x5a, 0x0c, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x72, 0x2f, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_pkg_proto_transporter_proto_rawDescOnce sync.Once
+	file_pkg_proto_transporter_proto_rawDescData = file_pkg_proto_transporter_proto_rawDesc
+)
+
+func file_pkg_proto_transporter_proto_rawDescGZIP() []byte {
+	file_pkg_proto_transporter_proto_rawDescOnce.Do(func() {
+		file_pkg_proto_transporter_proto_rawDescData = protoimpl.X.CompressGZIP(file_pkg_proto_transporter_proto_rawDescData)
+	})
+	return file_pkg_proto_transporter_proto_rawDescData
+}
+
+var file_pkg_proto_transporter_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_pkg_proto_transporter_proto_msgTypes = make([]protoimpl.MessageInfo, 44)
+var file_pkg_proto_transporter_proto_goTypes = []any{
+	(RelayType)(0),               // 0: proto.RelayType
+	(RelayStateType)(0),          // 1: proto.RelayStateType
+	(OtaState)(0),                // 2: proto.OtaState
+	(LogLevel)(0),                // 3: proto.LogLevel
+	(CommandState)(0),            // 4: proto.CommandState
+	(*WifiCredentials)(nil),      // 5: proto.WifiCredentials
+	(*UID)(nil),                  // 6: proto.UID
+	(*RegisterRequest)(nil),      // 7: proto.RegisterRequest
+	(*RegisterResponse)(nil),     // 8: proto.RegisterResponse
+	(*RevokeRequest)(nil),        // 9: proto.RevokeRequest
+	(*RfidEnvelope)(nil),         // 10: proto.RfidEnvelope
+	(*Climate)(nil),              // 11: proto.Climate
+	(*LDR)(nil),                  // 12: proto.LDR
+	(*Motion)(nil),               // 13: proto.Motion
+	(*FullConfig)(nil),           // 14: proto.FullConfig
+	(*ConfigTopic)(nil),          // 15: proto.ConfigTopic
+	(*ClimateRemoval)(nil),       // 16: proto.ClimateRemoval
+	(*LDRRemoval)(nil),           // 17: proto.LDRRemoval
+	(*MotionRemoval)(nil),        // 18: proto.MotionRemoval
+	(*ConfigRemoval)(nil),        // 19: proto.ConfigRemoval
+	(*RelayState)(nil),           // 20: proto.RelayState
+	(*RelayStateSync)(nil),       // 21: proto.RelayStateSync
+	(*ClimateData)(nil),          // 22: proto.ClimateData
+	(*LDRData)(nil),              // 23: proto.LDRData
+	(*ProvisionAnnounce)(nil),    // 24: proto.ProvisionAnnounce
+	(*ProvisionCredentials)(nil), // 25: proto.ProvisionCredentials
+	(*OtaUpdate)(nil),            // 26: proto.OtaUpdate
+	(*OtaCancel)(nil),            // 27: proto.OtaCancel
+	(*OtaCommand)(nil),           // 28: proto.OtaCommand
+	(*OtaStatus)(nil),            // 29: proto.OtaStatus
+	(*DeviceHealth)(nil),         // 30: proto.DeviceHealth
+	(*FactoryReset)(nil),         // 31: proto.FactoryReset
+	(*FactoryResetAck)(nil),      // 32: proto.FactoryResetAck
+	(*Reboot)(nil),               // 33: proto.Reboot
+	(*Identify)(nil),             // 34: proto.Identify
+	(*HealthReportRequest)(nil),  // 35: proto.HealthReportRequest
+	(*ConfigDumpRequest)(nil),    // 36: proto.ConfigDumpRequest
+	(*SetLogLevel)(nil),          // 37: proto.SetLogLevel
+	(*DeviceCommand)(nil),        // 38: proto.DeviceCommand
+	(*CommandResult)(nil),        // 39: proto.CommandResult
+	(*ShadowDelta)(nil),          // 40: proto.ShadowDelta
+	(*ShadowReport)(nil),         // 41: proto.ShadowReport
+	(*Capabilities)(nil),         // 42: proto.Capabilities
+	(*Metric)(nil),               // 43: proto.Metric
+	(*SensorReading)(nil),        // 44: proto.SensorReading
+	(*SensorConfig)(nil),         // 45: proto.SensorConfig
+	(*SensorRemoval)(nil),        // 46: proto.SensorRemoval
+	nil,                          // 47: proto.SensorConfig.PortsEntry
+	nil,                          // 48: proto.SensorConfig.SettingsEntry
+}
+var file_pkg_proto_transporter_proto_depIdxs = []int32{
+	6,  // 0: proto.RegisterResponse.uid:type_name -> proto.UID
+	6,  // 1: proto.RevokeRequest.uid:type_name -> proto.UID
+	7,  // 2: proto.RfidEnvelope.register_request:type_name -> proto.RegisterRequest
+	8,  // 3: proto.RfidEnvelope.register_response:type_name -> proto.RegisterResponse
+	9,  // 4: proto.RfidEnvelope.revoke_request:type_name -> proto.RevokeRequest
+	0,  // 5: proto.Motion.relay_type:type_name -> proto.RelayType
+	11, // 6: proto.FullConfig.climates:type_name -> proto.Climate
+	12, // 7: proto.FullConfig.ldrs:type_name -> proto.LDR
+	13, // 8: proto.FullConfig.motions:type_name -> proto.Motion
+	11, // 9: proto.ConfigTopic.climate:type_name -> proto.Climate
+	12, // 10: proto.ConfigTopic.ldr:type_name -> proto.LDR
+	13, // 11: proto.ConfigTopic.motion:type_name -> proto.Motion
+	14, // 12: proto.ConfigTopic.full_config:type_name -> proto.FullConfig
+	16, // 13: proto.ConfigRemoval.climate:type_name -> proto.ClimateRemoval
+	17, // 14: proto.ConfigRemoval.ldr:type_name -> proto.LDRRemoval
+	18, // 15: proto.ConfigRemoval.motion:type_name -> proto.MotionRemoval
+	0,  // 16: proto.RelayState.type:type_name -> proto.RelayType
+	1,  // 17: proto.RelayState.state:type_name -> proto.RelayStateType
+	26, // 18: proto.OtaCommand.update:type_name -> proto.OtaUpdate
+	27, // 19: proto.OtaCommand.cancel:type_name -> proto.OtaCancel
+	2,  // 20: proto.OtaStatus.state:type_name -> proto.OtaState
+	3,  // 21: proto.SetLogLevel.level:type_name -> proto.LogLevel
+	33, // 22: proto.DeviceCommand.reboot:type_name -> proto.Reboot
+	34, // 23: proto.DeviceCommand.identify:type_name -> proto.Identify
+	35, // 24: proto.DeviceCommand.health_report:type_name -> proto.HealthReportRequest
+	36, // 25: proto.DeviceCommand.config_dump:type_name -> proto.ConfigDumpRequest
+	37, // 26: proto.DeviceCommand.set_log_level:type_name -> proto.SetLogLevel
+	4,  // 27: proto.CommandResult.state:type_name -> proto.CommandState
+	30, // 28: proto.CommandResult.health:type_name -> proto.DeviceHealth
+	14, // 29: proto.CommandResult.config:type_name -> proto.FullConfig
+	20, // 30: proto.ShadowDelta.relays:type_name -> proto.RelayState
+	14, // 31: proto.ShadowDelta.config:type_name -> proto.FullConfig
+	19, // 32: proto.ShadowDelta.removed:type_name -> proto.ConfigRemoval
+	20, // 33: proto.ShadowReport.relays:type_name -> proto.RelayState
+	14, // 34: proto.ShadowReport.config:type_name -> proto.FullConfig
+	0,  // 35: proto.Capabilities.relays:type_name -> proto.RelayType
+	43, // 36: proto.SensorReading.metrics:type_name -> proto.Metric
+	47, // 37: proto.SensorConfig.ports:type_name -> proto.SensorConfig.PortsEntry
+	48, // 38: proto.SensorConfig.settings:type_name -> proto.SensorConfig.SettingsEntry
+	39, // [39:39] is the sub-list for method output_type
+	39, // [39:39] is the sub-list for method input_type
+	39, // [39:39] is the sub-list for extension type_name
+	39, // [39:39] is the sub-list for extension extendee
+	0,  // [0:39] is the sub-list for field type_name
+}
+
+func init() { file_pkg_proto_transporter_proto_init() }
+func file_pkg_proto_transporter_proto_init() {
+	if File_pkg_proto_transporter_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_pkg_proto_transporter_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*WifiCredentials); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*UID); i {
+			case 0:
+				return &v.state
+			case 1:
 				return &v.sizeCache
 			case 2:
 				return &v.unknownFields
@@ -1718,6 +3626,282 @@ func file_pkg_proto_transporter_proto_init() {
 				return nil
 			}
 		}
+		file_pkg_proto_transporter_proto_msgTypes[19].Exporter = func(v any, i int) any {
+			switch v := v.(*ProvisionAnnounce); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[20].Exporter = func(v any, i int) any {
+			switch v := v.(*ProvisionCredentials); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[21].Exporter = func(v any, i int) any {
+			switch v := v.(*OtaUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[22].Exporter = func(v any, i int) any {
+			switch v := v.(*OtaCancel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[23].Exporter = func(v any, i int) any {
+			switch v := v.(*OtaCommand); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[24].Exporter = func(v any, i int) any {
+			switch v := v.(*OtaStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[25].Exporter = func(v any, i int) any {
+			switch v := v.(*DeviceHealth); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[26].Exporter = func(v any, i int) any {
+			switch v := v.(*FactoryReset); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[27].Exporter = func(v any, i int) any {
+			switch v := v.(*FactoryResetAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[28].Exporter = func(v any, i int) any {
+			switch v := v.(*Reboot); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[29].Exporter = func(v any, i int) any {
+			switch v := v.(*Identify); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[30].Exporter = func(v any, i int) any {
+			switch v := v.(*HealthReportRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[31].Exporter = func(v any, i int) any {
+			switch v := v.(*ConfigDumpRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[32].Exporter = func(v any, i int) any {
+			switch v := v.(*SetLogLevel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[33].Exporter = func(v any, i int) any {
+			switch v := v.(*DeviceCommand); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[34].Exporter = func(v any, i int) any {
+			switch v := v.(*CommandResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[35].Exporter = func(v any, i int) any {
+			switch v := v.(*ShadowDelta); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[36].Exporter = func(v any, i int) any {
+			switch v := v.(*ShadowReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[37].Exporter = func(v any, i int) any {
+			switch v := v.(*Capabilities); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[38].Exporter = func(v any, i int) any {
+			switch v := v.(*Metric); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[39].Exporter = func(v any, i int) any {
+			switch v := v.(*SensorReading); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[40].Exporter = func(v any, i int) any {
+			switch v := v.(*SensorConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_proto_transporter_proto_msgTypes[41].Exporter = func(v any, i int) any {
+			switch v := v.(*SensorRemoval); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_pkg_proto_transporter_proto_msgTypes[5].OneofWrappers = []any{
 		(*RfidEnvelope_RegisterRequest)(nil),
@@ -1735,13 +3919,29 @@ func file_pkg_proto_transporter_proto_init() {
 		(*ConfigRemoval_Ldr)(nil),
 		(*ConfigRemoval_Motion)(nil),
 	}
+	file_pkg_proto_transporter_proto_msgTypes[23].OneofWrappers = []any{
+		(*OtaCommand_Update)(nil),
+		(*OtaCommand_Cancel)(nil),
+	}
+	file_pkg_proto_transporter_proto_msgTypes[25].OneofWrappers = []any{}
+	file_pkg_proto_transporter_proto_msgTypes[33].OneofWrappers = []any{
+		(*DeviceCommand_Reboot)(nil),
+		(*DeviceCommand_Identify)(nil),
+		(*DeviceCommand_HealthReport)(nil),
+		(*DeviceCommand_ConfigDump)(nil),
+		(*DeviceCommand_SetLogLevel)(nil),
+	}
+	file_pkg_proto_transporter_proto_msgTypes[34].OneofWrappers = []any{
+		(*CommandResult_Health)(nil),
+		(*CommandResult_Config)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pkg_proto_transporter_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   19,
+			NumEnums:      5,
+			NumMessages:   44,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
@@ -0,0 +1,51 @@
+package provision
+
+import (
+	"bytes"
+	"log/slog"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+// Hook handles the announcements of bootstrap clients. It is a hook rather
+// than an inline subscription because the credentials are written to the
+// announcing client only, and inline subscriptions do not see the publisher.
+type Hook struct {
+	mqtt.HookBase
+	manager *Manager
+}
+
+func NewHook(manager *Manager) *Hook {
+	return &Hook{manager: manager}
+}
+
+func (h *Hook) ID() string {
+	return "smaas-provision"
+}
+
+func (h *Hook) Provides(b byte) bool {
+	return bytes.Contains([]byte{
+		mqtt.OnPublish,
+		mqtt.OnDisconnect,
+	}, []byte{b})
+}
+
+func (h *Hook) OnPublish(cl *mqtt.Client, pk packets.Packet) (packets.Packet, error) {
+	if string(cl.Properties.Username) != Username || pk.TopicName != AnnounceTopic(cl.ID) {
+		return pk, nil
+	}
+
+	if err := h.manager.Announce(cl, pk.Payload); err != nil {
+		h.manager.app.Logger().Error("failed to handle board announcement", slog.String("serial", cl.ID), slog.String("error", err.Error()))
+	}
+
+	// the announcement carries the claim code and is never delivered
+	return pk, packets.ErrRejectPacket
+}
+
+func (h *Hook) OnDisconnect(cl *mqtt.Client, err error, expire bool) {
+	if string(cl.Properties.Username) == Username {
+		h.manager.Forget(cl)
+	}
+}
@@ -0,0 +1,304 @@
+package provision
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/protobuf/proto"
+)
+
+// Username is the MQTT username of boards that are not provisioned yet.
+// They connect without a password and with their serial as client id.
+const Username = "provision"
+
+const (
+	// alphabet leaves out characters that are easily confused when read
+	// from a printed label
+	alphabet        = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	serialLength    = 10
+	claimCodeLength = 12
+	passwordLength  = 32
+)
+
+var (
+	ErrInvalidClaim   = errors.New("invalid serial or claim code")
+	ErrAlreadyClaimed = errors.New("device is already claimed")
+	ErrDecommissioned = errors.New("device is decommissioned")
+)
+
+func AnnounceTopic(serial string) string {
+	return fmt.Sprintf("provision/%s/announce", serial)
+}
+
+func CredentialsTopic(serial string) string {
+	return fmt.Sprintf("provision/%s/credentials", serial)
+}
+
+// Board is a freshly generated board. The claim code is only known at
+// generation time, only its hash is stored.
+type Board struct {
+	Serial    string
+	ClaimCode string
+}
+
+// Manager claims boards and issues their MQTT credentials.
+type Manager struct {
+	app core.App
+
+	mu sync.Mutex
+	// boards are the connected bootstrap clients that announced themselves
+	// with a valid claim code, by serial
+	boards map[string]*mqtt.Client
+}
+
+func NewManager(app core.App) *Manager {
+	return &Manager{
+		app:    app,
+		boards: make(map[string]*mqtt.Client),
+	}
+}
+
+// Generate creates count boards with random serials and claim codes.
+func (m *Manager) Generate(count int) ([]Board, error) {
+	collection, err := m.app.FindCollectionByNameOrId(collections.ProvisioningCollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	boards := make([]Board, 0, count)
+	err = m.app.RunInTransaction(func(txApp core.App) error {
+		for range count {
+			board := Board{
+				Serial:    "SMA" + security.RandomStringWithAlphabet(serialLength, alphabet),
+				ClaimCode: security.RandomStringWithAlphabet(claimCodeLength, alphabet),
+			}
+
+			record := core.NewRecord(collection)
+			record.Set("serial", board.Serial)
+			record.Set("claim_code", board.ClaimCode)
+			if err := txApp.Save(record); err != nil {
+				return err
+			}
+
+			boards = append(boards, board)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return boards, nil
+}
+
+// Announce handles the announcement of a bootstrap client. A board that was
+// already claimed gets its credentials right away, otherwise they are sent
+// once the board is claimed. Once the credentials were handed out the board
+// has to be reset before it can announce itself again, so a leaked claim
+// code cannot be used to get the credentials of the device. A decommissioned
+// device gets none until it is restored.
+func (m *Manager) Announce(cl *mqtt.Client, payload []byte) error {
+	var d transporter.ProvisionAnnounce
+	if err := proto.Unmarshal(payload, &d); err != nil {
+		return err
+	}
+
+	if d.Serial != cl.ID {
+		return ErrInvalidClaim
+	}
+
+	record, err := m.verify(m.app, d.Serial, d.ClaimCode)
+	if err != nil {
+		return err
+	}
+
+	if record.GetString("password:hash") != "" {
+		return ErrAlreadyClaimed
+	}
+	if deviceId := record.GetString("device"); deviceId != "" && !m.Active(deviceId) {
+		return ErrDecommissioned
+	}
+
+	record.Set("announced_at", time.Now())
+	if err := m.app.Save(record); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.boards[d.Serial] = cl
+	m.mu.Unlock()
+
+	if record.GetString("device") == "" {
+		return nil
+	}
+
+	return m.issue(record)
+}
+
+// Forget drops a disconnected bootstrap client.
+func (m *Manager) Forget(cl *mqtt.Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.boards[cl.ID] == cl {
+		delete(m.boards, cl.ID)
+	}
+}
+
+// Claim binds the board to the user by creating its device. The board is
+// verified in the same transaction, so concurrent claims create one device.
+func (m *Manager) Claim(user string, serial string, claimCode string, deviceName string) (*core.Record, error) {
+	devices, err := m.app.FindCollectionByNameOrId(collections.DevicesCollectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	var record *core.Record
+	device := core.NewRecord(devices)
+	err = m.app.RunInTransaction(func(txApp core.App) error {
+		found, err := m.verify(txApp, serial, claimCode)
+		if err != nil {
+			return err
+		}
+
+		if found.GetString("device") != "" {
+			return ErrAlreadyClaimed
+		}
+		record = found
+
+		device.Set("user", user)
+		device.Set("device_name", deviceName)
+		device.Set("device_status", "offline")
+		if err := txApp.Save(device); err != nil {
+			return err
+		}
+
+		record.Set("device", device.Id)
+		record.Set("claimed_at", time.Now())
+		return txApp.Save(record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.issue(record); err != nil {
+		m.app.Logger().Error("failed to issue device credentials", slog.String("serial", serial), slog.String("error", err.Error()))
+	}
+
+	return device, nil
+}
+
+// Authenticate reports whether the password was issued to the device and
+// the device is not decommissioned.
+func (m *Manager) Authenticate(deviceId string, password string) bool {
+	if deviceId == "" || password == "" || !m.Active(deviceId) {
+		return false
+	}
+
+	record, err := m.app.FindFirstRecordByFilter(
+		collections.ProvisioningCollectionName,
+		"device = {:device}",
+		dbx.Params{"device": deviceId},
+	)
+	if err != nil {
+		return false
+	}
+
+	hash := record.GetString("password:hash")
+	return hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// Active reports whether the device exists and is not decommissioned.
+func (m *Manager) Active(deviceId string) bool {
+	device, err := m.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	return err == nil && device.GetDateTime("deleted_at").IsZero()
+}
+
+// Exists reports whether the device exists, even if decommissioned.
+func (m *Manager) Exists(deviceId string) bool {
+	_, err := m.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	return err == nil
+}
+
+func (m *Manager) verify(app core.App, serial string, claimCode string) (*core.Record, error) {
+	record, err := app.FindFirstRecordByFilter(
+		collections.ProvisioningCollectionName,
+		"serial = {:serial}",
+		dbx.Params{"serial": strings.ToUpper(strings.TrimSpace(serial))},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidClaim
+		}
+		return nil, err
+	}
+
+	hash := record.GetString("claim_code:hash")
+	code := strings.ToUpper(strings.TrimSpace(claimCode))
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) != nil {
+		return nil, ErrInvalidClaim
+	}
+
+	return record, nil
+}
+
+// issue sends a new password to the board if it is connected. The password
+// is only stored once it is handed out, so a board that is not connected
+// gets its credentials when it announces itself again.
+func (m *Manager) issue(record *core.Record) error {
+	serial := record.GetString("serial")
+	deviceId := record.GetString("device")
+	if !m.Active(deviceId) {
+		return ErrDecommissioned
+	}
+
+	m.mu.Lock()
+	cl, ok := m.boards[serial]
+	m.mu.Unlock()
+	if !ok || cl.Closed() {
+		return nil
+	}
+
+	password := security.RandomString(passwordLength)
+
+	payload, err := proto.Marshal(&transporter.ProvisionCredentials{
+		DeviceId: deviceId,
+		Username: deviceId,
+		Password: password,
+	})
+	if err != nil {
+		return err
+	}
+
+	record.Set("password", password)
+	if err := m.app.Save(record); err != nil {
+		return err
+	}
+
+	err = cl.WritePacket(packets.Packet{
+		FixedHeader: packets.FixedHeader{
+			Type: packets.Publish,
+		},
+		TopicName: CredentialsTopic(serial),
+		Payload:   payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	m.app.Logger().Info("issued device credentials", slog.String("serial", serial), slog.String("device_id", deviceId))
+	return nil
+}
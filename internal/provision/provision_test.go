@@ -0,0 +1,79 @@
+package provision
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/pocketbase/pocketbase/core"
+	// the system migrations, which pocketbase.New registers
+	_ "github.com/pocketbase/pocketbase/migrations"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecommissionedDevice(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	for _, c := range []collections.CollectionDefiner{&collections.Devices{}, &collections.Provisioning{}} {
+		if err := app.SaveNoValidate(c.Schema()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewManager(app)
+	boards, err := m.Generate(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := boards[0]
+
+	device := core.NewRecord((&collections.Devices{}).Schema())
+	device.Id = "device1"
+	if err := app.SaveNoValidate(device); err != nil {
+		t.Fatal(err)
+	}
+	record, err := app.FindFirstRecordByData(collections.ProvisioningCollectionName, "serial", board.Serial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.Set("device", device.Id)
+	record.Set("password", "secret")
+	if err := app.SaveNoValidate(record); err != nil {
+		t.Fatal(err)
+	}
+
+	if !m.Authenticate(device.Id, "secret") {
+		t.Fatal("active device not authenticated")
+	}
+
+	device.Set("deleted_at", time.Now())
+	if err := app.SaveNoValidate(device); err != nil {
+		t.Fatal(err)
+	}
+	if m.Authenticate(device.Id, "secret") {
+		t.Error("decommissioned device authenticated")
+	}
+
+	// the factory reset clears the password, the board announces again
+	record.Set("password", "")
+	if err := app.SaveNoValidate(record); err != nil {
+		t.Fatal(err)
+	}
+	payload, err := proto.Marshal(&transporter.ProvisionAnnounce{Serial: board.Serial, ClaimCode: board.ClaimCode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Announce(&mqtt.Client{ID: board.Serial}, payload); !errors.Is(err, ErrDecommissioned) {
+		t.Errorf("Announce = %v, want %v", err, ErrDecommissioned)
+	}
+	if err := m.issue(record); !errors.Is(err, ErrDecommissioned) {
+		t.Errorf("issue = %v, want %v", err, ErrDecommissioned)
+	}
+}
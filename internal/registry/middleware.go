@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/metrics"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Logging logs every message with the time its handler took.
+func Logging(app core.App) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(msg *Message) {
+			start := time.Now()
+			next(msg)
+			app.Logger().Debug("handled message",
+				slog.String("family", msg.Family.Name()),
+				slog.String("route", msg.Route),
+				slog.String("topic", msg.Packet.TopicName),
+				slog.String("device_id", msg.DeviceId),
+				slog.Duration("duration", time.Since(start)),
+			)
+		}
+	}
+}
+
+// Authorize drops the messages a device publishes on the topics of another
+// device. Clients with the shared credentials and the server itself may
+// publish for every device.
+func Authorize(app core.App, server *mqtt.Server, sharedUsername string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(msg *Message) {
+			if msg.Packet.Origin != mqtt.InlineClientId {
+				publisher, ok := server.Clients.Get(msg.Packet.Origin)
+				username := ""
+				if ok {
+					username = string(publisher.Properties.Username)
+				}
+				if !ok || (username != sharedUsername && username != msg.DeviceId) {
+					app.Logger().Warn("unauthorized message dropped",
+						slog.String("topic", msg.Packet.TopicName),
+						slog.String("client_id", msg.Packet.Origin),
+					)
+					return
+				}
+			}
+			next(msg)
+		}
+	}
+}
+
+// RouteStats are the counters of a route.
+type RouteStats struct {
+	Family   string        `json:"family"`
+	Route    string        `json:"route"`
+	Messages uint64        `json:"messages"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Metrics counts the messages and the handling time per route, and exports
+// them with the payloads that failed to decode to the metrics registry.
+type Metrics struct {
+	mu     sync.Mutex
+	routes map[string]*RouteStats
+
+	duration       *metrics.HistogramVec
+	decodeFailures *metrics.CounterVec
+}
+
+func NewMetrics(reg *metrics.Registry) *Metrics {
+	return &Metrics{
+		routes:         map[string]*RouteStats{},
+		duration:       reg.Histogram("smaas_handler_duration_seconds", "Time the handlers took per route.", metrics.DefaultBuckets, "family", "route"),
+		decodeFailures: reg.Counter("smaas_handler_decode_failures_total", "Payloads that failed to decode per route.", "family", "route"),
+	}
+}
+
+func (m *Metrics) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(msg *Message) {
+			start := time.Now()
+			next(msg)
+			elapsed := time.Since(start)
+
+			m.duration.With(msg.Family.Name(), msg.Route).Observe(elapsed.Seconds())
+			if msg.decodeErr != nil {
+				m.decodeFailures.With(msg.Family.Name(), msg.Route).Inc()
+			}
+
+			key := msg.Family.Name() + "/" + msg.Route
+			m.mu.Lock()
+			stats, ok := m.routes[key]
+			if !ok {
+				stats = &RouteStats{Family: msg.Family.Name(), Route: msg.Route}
+				m.routes[key] = stats
+			}
+			stats.Messages++
+			stats.Duration += elapsed
+			m.mu.Unlock()
+		}
+	}
+}
+
+// Stats returns a snapshot of the counters sorted by family and route.
+func (m *Metrics) Stats() []RouteStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]RouteStats, 0, len(m.routes))
+	for _, s := range m.routes {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Family != stats[j].Family {
+			return stats[i].Family < stats[j].Family
+		}
+		return stats[i].Route < stats[j].Route
+	})
+
+	return stats
+}
@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/pocketbase/core"
+	"google.golang.org/protobuf/proto"
+)
+
+// Family is a kind of device with its own topics, e.g. Arduino boards,
+// ESP32 boards or a Zigbee bridge.
+type Family interface {
+	Name() string
+	// DeviceId returns the device a topic of the family belongs to, or an
+	// empty string if the topic has no device.
+	DeviceId(topic string) string
+	// Decode decodes the payload of a message of the family.
+	Decode(pk packets.Packet, m proto.Message) error
+	Routes() []Route
+	// RegisterHooks binds the PocketBase hooks and cron jobs of the family.
+	RegisterHooks()
+}
+
+// Route is a topic filter of a family and the handler of its messages.
+type Route struct {
+	Filter  string
+	Name    string
+	Handler HandlerFunc
+}
+
+// Message is a message received on a route.
+type Message struct {
+	Family   Family
+	Route    string
+	DeviceId string
+	Client   *mqtt.Client
+	Packet   packets.Packet
+	// decodeErr is kept for the metrics
+	decodeErr error
+}
+
+// Decode decodes the payload with the decoder of the family.
+func (m *Message) Decode(v proto.Message) error {
+	m.decodeErr = m.Family.Decode(m.Packet, v)
+	return m.decodeErr
+}
+
+type HandlerFunc func(msg *Message)
+
+// Middleware wraps the handlers of every route.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Registry subscribes the routes of the device families and runs their
+// messages through the shared middleware.
+type Registry struct {
+	app        core.App
+	server     *mqtt.Server
+	middleware []Middleware
+
+	mu       sync.Mutex
+	families []Family
+	// id is the subscription id of the next route
+	id int
+
+	// running handlers
+	running atomic.Int64
+}
+
+func New(app core.App, server *mqtt.Server) *Registry {
+	return &Registry{
+		app:    app,
+		server: server,
+		id:     1,
+	}
+}
+
+// Use adds middleware, the first one added runs first. It only applies to
+// families registered afterwards.
+func (r *Registry) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Register subscribes the routes of a family and registers its hooks.
+func (r *Registry) Register(family Family) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, route := range family.Routes() {
+		handler := route.Handler
+		for i := len(r.middleware) - 1; i >= 0; i-- {
+			handler = r.middleware[i](handler)
+		}
+		handler = r.deviceId(handler)
+
+		if err := r.server.Subscribe(route.Filter, r.id, r.inline(family, route, handler)); err != nil {
+			return err
+		}
+		r.id++
+	}
+
+	family.RegisterHooks()
+	r.families = append(r.families, family)
+
+	r.app.Logger().Info("registered device family", slog.String("family", family.Name()))
+	return nil
+}
+
+// Families returns the registered device families.
+func (r *Registry) Families() []Family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Family(nil), r.families...)
+}
+
+// Wait blocks until the running handlers have returned, or the context is
+// done. It is called once the broker is closed and no new messages arrive.
+func (r *Registry) Wait(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for r.running.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (r *Registry) inline(family Family, route Route, handler HandlerFunc) mqtt.InlineSubFn {
+	return func(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
+		r.running.Add(1)
+		defer r.running.Add(-1)
+
+		handler(&Message{
+			Family:   family,
+			Route:    route.Name,
+			DeviceId: family.DeviceId(pk.TopicName),
+			Client:   cl,
+			Packet:   pk,
+		})
+	}
+}
+
+// deviceId drops the messages without a device before any middleware, so
+// neither the middleware nor the handlers have to check it.
+func (r *Registry) deviceId(next HandlerFunc) HandlerFunc {
+	return func(msg *Message) {
+		if msg.DeviceId == "" {
+			r.app.Logger().Error("failed to get device id from topic", slog.String("topic", msg.Packet.TopicName))
+			return
+		}
+		next(msg)
+	}
+}
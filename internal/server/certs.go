@@ -0,0 +1,127 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/certs"
+	"github.com/spf13/cobra"
+)
+
+// RegisterCertificates adds the commands of the internal CA issuing the
+// certificates for the MQTT TLS listener and the device client certificates.
+func (pb *PocketBase) RegisterCertificates() {
+	var dir, out string
+	var days int
+
+	caDir := func() string {
+		if dir != "" {
+			return dir
+		}
+		return filepath.Join(pb.app.DataDir(), "ca")
+	}
+	validity := func() (time.Duration, error) {
+		if days < 1 {
+			return 0, errors.New("days must be at least 1")
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	write := func(name string, certPEM []byte, keyPEM []byte) error {
+		if err := os.MkdirAll(out, 0o700); err != nil {
+			return err
+		}
+		certFile := filepath.Join(out, name+".crt")
+		keyFile := filepath.Join(out, name+".key")
+		if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+			return err
+		}
+		if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+			return err
+		}
+
+		fmt.Println(certFile)
+		fmt.Println(keyFile)
+		return nil
+	}
+
+	command := &cobra.Command{
+		Use:   "ca",
+		Short: "Manages the internal CA for MQTT TLS certificates",
+	}
+	command.PersistentFlags().StringVar(&dir, "dir", "", "CA directory (default pb_data/ca)")
+	command.PersistentFlags().IntVar(&days, "days", 825, "validity in days")
+
+	command.AddCommand(&cobra.Command{
+		Use:   "init",
+		Short: "Creates the CA certificate and key",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the CA outlives the certificates it issues
+			valid, err := validity()
+			if err != nil {
+				return err
+			}
+			if _, err := certs.InitCA(caDir(), 4*valid); err != nil {
+				return err
+			}
+
+			fmt.Println(certs.CertFile(caDir()))
+			return nil
+		},
+	})
+
+	device := &cobra.Command{
+		Use:   "device <device_id>",
+		Short: "Issues a client certificate for a device",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			valid, err := validity()
+			if err != nil {
+				return err
+			}
+			if !pb.provision.Active(args[0]) {
+				return fmt.Errorf("device %s does not exist", args[0])
+			}
+
+			ca, err := certs.LoadCA(caDir())
+			if err != nil {
+				return err
+			}
+			certPEM, keyPEM, err := ca.IssueDevice(args[0], valid)
+			if err != nil {
+				return err
+			}
+			return write(args[0], certPEM, keyPEM)
+		},
+	}
+	device.Flags().StringVarP(&out, "out", "o", ".", "output directory")
+
+	server := &cobra.Command{
+		Use:   "server <host>...",
+		Short: "Issues the certificate of the MQTT TLS listener",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			valid, err := validity()
+			if err != nil {
+				return err
+			}
+
+			ca, err := certs.LoadCA(caDir())
+			if err != nil {
+				return err
+			}
+			certPEM, keyPEM, err := ca.IssueServer(args, valid)
+			if err != nil {
+				return err
+			}
+			return write("server", certPEM, keyPEM)
+		},
+	}
+	server.Flags().StringVarP(&out, "out", "o", ".", "output directory")
+
+	command.AddCommand(device, server)
+	pb.app.RootCmd.AddCommand(command)
+}
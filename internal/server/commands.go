@@ -0,0 +1,68 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// memberCommands can be sent by every member of a device, the other
+// commands need a role that may change the device.
+var memberCommands = []string{collections.CommandIdentify, collections.CommandHealthReport}
+
+type commandRequest struct {
+	Command string         `json:"command"`
+	Params  map[string]any `json:"params"`
+}
+
+func (pb *PocketBase) deviceCommandHandler(e *core.RequestEvent) error {
+	device, err := e.App.FindRecordById(collections.DevicesCollectionName, e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("device not found", err)
+	}
+
+	var body commandRequest
+	if err := e.BindBody(&body); err != nil {
+		return e.BadRequestError("invalid request body", err)
+	}
+
+	info, err := e.RequestInfo()
+	if err != nil {
+		return e.BadRequestError("invalid request", err)
+	}
+
+	if ok, err := e.App.CanAccessRecord(device, info, device.Collection().ViewRule); !ok {
+		return e.NotFoundError("device not found", err)
+	}
+
+	if !slices.Contains(memberCommands, body.Command) {
+		if ok, _ := e.App.CanAccessRecord(device, info, device.Collection().UpdateRule); !ok {
+			return e.ForbiddenError("you are not allowed to send this command", nil)
+		}
+	}
+
+	if !device.GetDateTime("deleted_at").IsZero() {
+		return e.BadRequestError("device is decommissioned", nil)
+	}
+
+	collection, err := e.App.FindCollectionByNameOrId(collections.DeviceCommandsCollectionName)
+	if err != nil {
+		return e.InternalServerError("failed to send command", err)
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("device", device.Id)
+	if !e.HasSuperuserAuth() {
+		record.Set("user", e.Auth.Id)
+	}
+	record.Set("command", body.Command)
+	record.Set("params", body.Params)
+	record.Set("status", collections.CommandSent)
+	if err := e.App.Save(record); err != nil {
+		return e.BadRequestError("failed to send command", err)
+	}
+
+	return e.JSON(http.StatusCreated, record)
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"coderero.dev/iot/smaas-server/internal/config"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+// RegisterConfig adds the config flags and the config print command, and
+// serves HTTP on the configured address.
+func (pb *PocketBase) RegisterConfig() {
+	config.RegisterFlags(pb.app.RootCmd.PersistentFlags())
+
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Inspects the server config",
+	}
+	printCommand := &cobra.Command{
+		Use:          "print",
+		Short:        "Prints the config after the file, env and flag overrides, with the secrets redacted",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pb.config.Print(cmd.OutOrStdout())
+		},
+	}
+	// accepted like serve does, so the printed config is the one it runs with
+	printCommand.Flags().String("http", "", "the address of the HTTP server")
+	command.AddCommand(printCommand)
+	pb.app.RootCmd.AddCommand(command)
+
+	// the --http flag of serve is read into the config as well, the address
+	// is left alone for --https, which serves HTTP only for the redirects
+	pb.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		if pb.config.HTTP.Address != "" && !pb.serveFlagChanged("https") {
+			se.Server.Addr = pb.config.HTTP.Address
+		}
+
+		return se.Next()
+	})
+}
+
+func (pb *PocketBase) serveFlagChanged(name string) bool {
+	serve, _, err := pb.app.RootCmd.Find([]string{"serve"})
+	return err == nil && serve.Flags().Changed(name)
+}
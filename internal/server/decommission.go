@@ -0,0 +1,130 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// exportCollections hold the device data included in an export. The WiFi
+// credentials are left out on purpose.
+var exportCollections = []string{
+	collections.ClimateConfigCollectionName,
+	collections.LDRConfigCollectionName,
+	collections.MotionConfigCollectionName,
+	collections.SensorsCollectionName,
+	collections.UserPortLablesCollectionName,
+	collections.SecurityCollectionName,
+	collections.SecurityLogsCollectionName,
+	collections.ClimateCollectionName,
+	collections.LDRCollectionName,
+	collections.ReadingsCollectionName,
+	collections.RelayEventsCollectionName,
+	collections.DeviceHealthCollectionName,
+	collections.DeviceAlertsCollectionName,
+}
+
+func (pb *PocketBase) RegisterDecommission() {
+	pb.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		se.Router.POST("/api/devices/{id}/restore", pb.restoreDeviceHandler).Bind(apis.RequireAuth())
+		se.Router.GET("/api/devices/{id}/export", pb.exportDeviceHandler).Bind(apis.RequireAuth())
+
+		return se.Next()
+	})
+
+	pb.app.Cron().MustAdd("purge_devices", "0 * * * *", func() {
+		if err := pb.purgeDevices(); err != nil {
+			pb.app.Logger().Error("failed to purge devices", slog.String("error", err.Error()))
+		}
+	})
+}
+
+func (pb *PocketBase) restoreDeviceHandler(e *core.RequestEvent) error {
+	device, err := pb.findOwnedDevice(e)
+	if err != nil {
+		return err
+	}
+
+	if device.GetDateTime("deleted_at").IsZero() {
+		return e.BadRequestError("device is not decommissioned", nil)
+	}
+
+	device.Set("deleted_at", "")
+	device.Set("reset_nonce", "")
+	device.Set("reset_acknowledged", "")
+	if err := e.App.Save(device); err != nil {
+		return e.BadRequestError("failed to restore device", err)
+	}
+
+	return e.JSON(http.StatusOK, device)
+}
+
+func (pb *PocketBase) exportDeviceHandler(e *core.RequestEvent) error {
+	device, err := pb.findOwnedDevice(e)
+	if err != nil {
+		return err
+	}
+
+	export := map[string]any{
+		"device":      device,
+		"exported_at": types.NowDateTime(),
+	}
+	for _, name := range exportCollections {
+		records, err := e.App.FindAllRecords(name, dbx.HashExp{"device": device.Id})
+		if err != nil {
+			return e.InternalServerError("failed to export device", err)
+		}
+		export[name] = records
+	}
+
+	e.Response.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "device-"+device.Id+".json"))
+	return e.JSON(http.StatusOK, export)
+}
+
+// findOwnedDevice returns the device of the path if the user may delete it.
+func (pb *PocketBase) findOwnedDevice(e *core.RequestEvent) (*core.Record, error) {
+	device, err := e.App.FindRecordById(collections.DevicesCollectionName, e.Request.PathValue("id"))
+	if err != nil {
+		return nil, e.NotFoundError("device not found", err)
+	}
+
+	info, err := e.RequestInfo()
+	if err != nil {
+		return nil, e.BadRequestError("invalid request", err)
+	}
+
+	if ok, err := e.App.CanAccessRecord(device, info, device.Collection().DeleteRule); !ok {
+		return nil, e.NotFoundError("device not found", err)
+	}
+
+	return device, nil
+}
+
+func (pb *PocketBase) purgeDevices() error {
+	devices, err := pb.app.FindRecordsByFilter(
+		collections.DevicesCollectionName,
+		"deleted_at != '' && deleted_at < {:before}",
+		"",
+		0,
+		0,
+		dbx.Params{"before": types.NowDateTime().Add(-pb.config.Retention.DecommissionedDevices).String()},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range devices {
+		if err := pb.app.Delete(device); err != nil {
+			return err
+		}
+		pb.app.Logger().Info("purged device", slog.String("device_id", device.Id))
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// healthTimeout bounds the checks of a probe, so a locked database fails the
+// probe instead of hanging it.
+const healthTimeout = 5 * time.Second
+
+// schedulerGrace is how long the scheduler may go without running the
+// heartbeat job. It runs every minute, the first time on the minute after
+// the server starts.
+const schedulerGrace = 2 * time.Minute
+
+// errRollback rolls back the transaction of the database check.
+var errRollback = errors.New("rollback")
+
+// healthCheck reports whether a part of the server works.
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type healthResult struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// registerHealth serves the probes of Docker and Kubernetes. /healthz checks
+// that the broker and the background workers are running, /readyz also
+// checks that the database is writable and the broker listeners accept
+// connections. Both return 503 when a check fails.
+func (s *Server) registerHealth() {
+	live := []healthCheck{
+		{name: "broker", check: s.mqttServer.checkServing},
+		{name: "ingest", check: s.checkIngest},
+		{name: "scheduler", check: s.checkScheduler},
+	}
+	ready := slices.Concat(live, []healthCheck{
+		{name: "database", check: s.checkDatabase},
+		{name: "listeners", check: s.mqttServer.checkListeners},
+	})
+
+	s.pocketbaseServer.app.Cron().MustAdd("health_heartbeat", "* * * * *", func() {
+		s.heartbeat.Store(time.Now().UnixNano())
+	})
+
+	s.pocketbaseServer.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		s.heartbeat.Store(time.Now().UnixNano())
+
+		se.Router.GET("/healthz", healthHandler(live))
+		se.Router.GET("/readyz", healthHandler(ready))
+
+		return se.Next()
+	})
+}
+
+func healthHandler(checks []healthCheck) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		ctx, cancel := context.WithTimeout(e.Request.Context(), healthTimeout)
+		defer cancel()
+
+		result := healthResult{Status: "ok", Checks: map[string]checkResult{}}
+		for _, c := range checks {
+			if err := c.check(ctx); err != nil {
+				result.Status = "unavailable"
+				result.Checks[c.name] = checkResult{Status: "failed", Error: err.Error()}
+				continue
+			}
+			result.Checks[c.name] = checkResult{Status: "ok"}
+		}
+
+		if result.Status != "ok" {
+			return e.JSON(http.StatusServiceUnavailable, result)
+		}
+		return e.JSON(http.StatusOK, result)
+	}
+}
+
+func (s *Server) checkIngest(_ context.Context) error {
+	if !s.ingestQueue.Running() {
+		return errors.New("ingest queue is not running")
+	}
+	return nil
+}
+
+func (s *Server) checkScheduler(_ context.Context) error {
+	idle := time.Since(time.Unix(0, s.heartbeat.Load()))
+	if idle > schedulerGrace {
+		return fmt.Errorf("scheduler has not run for %s", idle.Round(time.Second))
+	}
+	return nil
+}
+
+// checkDatabase writes a row in a transaction that is rolled back, which
+// fails when the database is read-only, full or locked for too long.
+func (s *Server) checkDatabase(ctx context.Context) error {
+	err := s.pocketbaseServer.app.RunInTransaction(func(txApp core.App) error {
+		_, err := txApp.NonconcurrentDB().NewQuery(
+			"INSERT INTO {{_params}} ([[id]], [[value]]) VALUES ({:id}, {:value}) " +
+				"ON CONFLICT ([[id]]) DO UPDATE SET [[value]] = excluded.[[value]]",
+		).Bind(dbx.Params{
+			"id":    "smaas_health",
+			"value": "null",
+		}).WithContext(ctx).Execute()
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("database is not writable: %w", err)
+	}
+	return nil
+}
+
+func (m *MQTT) checkServing(_ context.Context) error {
+	if !m.serving.Load() {
+		return errors.New("broker is not serving")
+	}
+	return nil
+}
+
+// servingListener records whether a broker listener accepts connections.
+// mochi serves every listener in its own goroutine and keeps no state of
+// it.
+type servingListener struct {
+	listeners.Listener
+	serving atomic.Bool
+}
+
+func (l *servingListener) Serve(establish listeners.EstablishFn) {
+	l.serving.Store(true)
+	defer l.serving.Store(false)
+
+	// returns once the listener stops accepting connections
+	l.Listener.Serve(establish)
+}
+
+func (l *servingListener) Close(closeClients listeners.CloseFn) {
+	l.serving.Store(false)
+	l.Listener.Close(closeClients)
+}
+
+// checkListeners checks that the TCP listeners of the broker accept
+// connections. The WebSocket listener is served by the HTTP server answering
+// the probe.
+func (m *MQTT) checkListeners(_ context.Context) error {
+	for _, id := range []string{"tcp", "tls"} {
+		listener, ok := m.server.Listeners.Get(id)
+		if !ok {
+			continue
+		}
+
+		if l, ok := listener.(*servingListener); ok && !l.serving.Load() {
+			return fmt.Errorf("%s listener is not accepting connections", id)
+		}
+	}
+	return nil
+}
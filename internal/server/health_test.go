@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+func TestCheckListeners(t *testing.T) {
+	server := mqtt.New(&mqtt.Options{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	listener := &servingListener{Listener: listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})}
+	if err := server.AddListener(listener); err != nil {
+		t.Fatal(err)
+	}
+	m := &MQTT{server: server}
+
+	if err := m.checkListeners(context.Background()); err == nil {
+		t.Fatal("listener ready before the broker serves")
+	}
+
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	// the listeners are served in their own goroutines
+	deadline := time.Now().Add(5 * time.Second)
+	for m.checkListeners(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("listener not ready while the broker serves")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := server.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.checkListeners(context.Background()); err == nil {
+		t.Fatal("listener ready after the broker closed")
+	}
+}
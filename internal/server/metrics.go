@@ -0,0 +1,164 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/ingest"
+	"coderero.dev/iot/smaas-server/internal/metrics"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/hook"
+	"github.com/pocketbase/pocketbase/tools/router"
+)
+
+// metricsPriority runs the metrics handlers before any other handler of a
+// hook, so they time the whole chain.
+const metricsPriority = -1 << 20
+
+// RegisterMetrics times the HTTP requests and the record saves and hooks,
+// and serves the metrics on /metrics to clients with the metrics token as
+// bearer token or superuser auth.
+func (pb *PocketBase) RegisterMetrics(reg *metrics.Registry) {
+	requests := reg.Histogram("smaas_http_request_duration_seconds", "Time the HTTP requests took per route and status.", metrics.DefaultBuckets, "method", "route", "status")
+	saves := reg.Histogram("smaas_record_save_duration_seconds", "Time the record saves took per collection, including their execute hooks.", metrics.DefaultBuckets, "collection", "operation")
+	saveErrors := reg.Counter("smaas_record_save_errors_total", "Record saves that failed per collection.", "collection", "operation")
+	hooks := reg.Histogram("smaas_record_hook_duration_seconds", "Time the hooks after a successful save took per collection.", metrics.DefaultBuckets, "collection", "event")
+
+	timeSave := func(operation string) *hook.Handler[*core.RecordEvent] {
+		return &hook.Handler[*core.RecordEvent]{
+			Priority: metricsPriority,
+			Func: func(e *core.RecordEvent) error {
+				start := time.Now()
+				err := e.Next()
+				saves.With(e.Record.Collection().Name, operation).Observe(time.Since(start).Seconds())
+				if err != nil {
+					saveErrors.With(e.Record.Collection().Name, operation).Inc()
+				}
+				return err
+			},
+		}
+	}
+	pb.app.OnRecordCreateExecute().Bind(timeSave("create"))
+	pb.app.OnRecordUpdateExecute().Bind(timeSave("update"))
+	pb.app.OnRecordDeleteExecute().Bind(timeSave("delete"))
+
+	timeHooks := func(event string) *hook.Handler[*core.RecordEvent] {
+		return &hook.Handler[*core.RecordEvent]{
+			Priority: metricsPriority,
+			Func: func(e *core.RecordEvent) error {
+				start := time.Now()
+				err := e.Next()
+				hooks.With(e.Record.Collection().Name, event).Observe(time.Since(start).Seconds())
+				return err
+			},
+		}
+	}
+	pb.app.OnRecordAfterCreateSuccess().Bind(timeHooks("after_create"))
+	pb.app.OnRecordAfterUpdateSuccess().Bind(timeHooks("after_update"))
+	pb.app.OnRecordAfterDeleteSuccess().Bind(timeHooks("after_delete"))
+
+	pb.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		// after the panic recovery, so panics are counted as errors
+		se.Router.Bind(&hook.Handler[*core.RequestEvent]{
+			Priority: apis.DefaultPanicRecoverMiddlewarePriority + 1,
+			Func: func(e *core.RequestEvent) error {
+				start := time.Now()
+				err := e.Next()
+
+				status := e.Status()
+				if err != nil {
+					status = router.ToApiError(err).Status
+				}
+				requests.With(e.Request.Method, requestRoute(e.Request), strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+				return err
+			},
+		})
+
+		se.Router.GET("/metrics", func(e *core.RequestEvent) error {
+			if !pb.metricsAuthorized(e) {
+				return e.UnauthorizedError("the metrics token or superuser auth is required", nil)
+			}
+
+			e.Response.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			e.Response.WriteHeader(http.StatusOK)
+			return reg.Write(e.Response)
+		})
+
+		return se.Next()
+	})
+}
+
+// requestRoute is the route pattern of a request without its method, so
+// the ids in the path do not each get a series.
+func requestRoute(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	if _, route, ok := strings.Cut(r.Pattern, " "); ok {
+		return route
+	}
+	return r.Pattern
+}
+
+func (pb *PocketBase) metricsAuthorized(e *core.RequestEvent) bool {
+	if e.HasSuperuserAuth() {
+		return true
+	}
+
+	token := pb.config.Metrics.Token
+	bearer, ok := strings.CutPrefix(e.Request.Header.Get("Authorization"), "Bearer ")
+	return token != "" && ok && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
+
+// collectMetrics exports the $SYS stats of the broker and the status of the
+// bridge.
+func (m *MQTT) collectMetrics(w *metrics.Writer) {
+	info := m.server.Info.Clone()
+	w.Gauge("smaas_broker_uptime_seconds", "Seconds the broker has been running.", float64(info.Uptime))
+	w.Gauge("smaas_broker_clients_connected", "Clients currently connected.", float64(info.ClientsConnected))
+	w.Gauge("smaas_broker_clients_disconnected", "Clients with a persistent session currently disconnected.", float64(info.ClientsDisconnected))
+	w.Gauge("smaas_broker_clients_maximum", "Most clients connected at once.", float64(info.ClientsMaximum))
+	w.Gauge("smaas_broker_clients_total", "Connected clients and disconnected clients with a persistent session.", float64(info.ClientsTotal))
+	w.Counter("smaas_broker_messages_received_total", "Publish messages received.", float64(info.MessagesReceived))
+	w.Counter("smaas_broker_messages_sent_total", "Publish messages sent.", float64(info.MessagesSent))
+	w.Counter("smaas_broker_messages_dropped_total", "Publish messages dropped to slow subscribers.", float64(info.MessagesDropped))
+	w.Gauge("smaas_broker_retained", "Retained messages.", float64(info.Retained))
+	w.Gauge("smaas_broker_inflight", "Messages in flight.", float64(info.Inflight))
+	w.Counter("smaas_broker_inflight_dropped_total", "Messages in flight that were dropped.", float64(info.InflightDropped))
+	w.Gauge("smaas_broker_subscriptions", "Active subscriptions.", float64(info.Subscriptions))
+	w.Counter("smaas_broker_packets_received_total", "Packets received.", float64(info.PacketsReceived))
+	w.Counter("smaas_broker_packets_sent_total", "Packets sent.", float64(info.PacketsSent))
+	w.Counter("smaas_broker_bytes_received_total", "Bytes received.", float64(info.BytesReceived))
+	w.Counter("smaas_broker_bytes_sent_total", "Bytes sent.", float64(info.BytesSent))
+
+	status, ok := m.BridgeStatus()
+	if !ok {
+		return
+	}
+	connected := 0.0
+	if status.Connected {
+		connected = 1
+	}
+	w.Gauge("smaas_bridge_connected", "Whether the bridge is connected to the upstream broker.", connected)
+	w.Counter("smaas_bridge_forwarded_total", "Messages forwarded to the upstream broker.", float64(status.Forwarded))
+	w.Counter("smaas_bridge_received_total", "Messages received from the upstream broker.", float64(status.Received))
+	w.Gauge("smaas_bridge_buffered", "Messages buffered for the upstream broker.", float64(status.Buffered))
+	w.Counter("smaas_bridge_dropped_total", "Messages dropped from the full buffer.", float64(status.Dropped))
+}
+
+// collectIngestMetrics exports the counters of the ingestion queue.
+func collectIngestMetrics(queue *ingest.Queue) func(w *metrics.Writer) {
+	return func(w *metrics.Writer) {
+		stats := queue.Stats()
+		w.Counter("smaas_ingest_enqueued_total", "Records enqueued for saving.", float64(stats.Enqueued))
+		w.Counter("smaas_ingest_dropped_total", "Records dropped from the full queue.", float64(stats.Dropped))
+		w.Counter("smaas_ingest_written_total", "Records saved.", float64(stats.Written))
+		w.Counter("smaas_ingest_failed_total", "Records that failed to save.", float64(stats.Failed))
+		w.Counter("smaas_ingest_batches_total", "Batches saved.", float64(stats.Batches))
+		w.Gauge("smaas_ingest_pending", "Records waiting to be saved.", float64(stats.Pending))
+	}
+}
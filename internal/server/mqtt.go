@@ -1,72 +1,312 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"log/slog"
+	"net/http"
 	"os"
+	"sync/atomic"
+	"time"
 
+	"coderero.dev/iot/smaas-server/internal/bridge"
+	"coderero.dev/iot/smaas-server/internal/certs"
 	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/config"
+	"coderero.dev/iot/smaas-server/internal/hooks"
+	"coderero.dev/iot/smaas-server/internal/ingest"
+	"coderero.dev/iot/smaas-server/internal/metrics"
+	"coderero.dev/iot/smaas-server/internal/provision"
+	"coderero.dev/iot/smaas-server/internal/registry"
 	"coderero.dev/iot/smaas-server/internal/topics"
+	"coderero.dev/iot/smaas-server/internal/wsmqtt"
 	mqtt "github.com/mochi-mqtt/server/v2"
-	"github.com/mochi-mqtt/server/v2/hooks/auth"
 	"github.com/mochi-mqtt/server/v2/listeners"
 	"github.com/pocketbase/pocketbase/core"
-
-	_ "github.com/joho/godotenv/autoload"
+	"github.com/pocketbase/pocketbase/tools/types"
 )
 
+// websocketListener is the id of the WebSocket listener mounted on the
+// PocketBase HTTP server.
+const websocketListener = "ws"
+
+// statusTopic holds the retained status of the server, which every client
+// may read. It is set to offline before the broker shuts down, so clients
+// without MQTT 5 know the disconnect is planned.
+const statusTopic = "server/status"
+
+// noticeGrace is the time the clients get to receive the offline status
+// before they are disconnected.
+const noticeGrace = 500 * time.Millisecond
+
+type serverStatus struct {
+	Status  string `json:"status"`
+	Updated string `json:"updated"`
+}
+
 type MQTT struct {
-	server  *mqtt.Server
-	arduino *topics.Arduino
+	server    *mqtt.Server
+	websocket *wsmqtt.Listener
+	auth      *hooks.Auth
+	bridge    *bridge.Bridge
+	registry  *registry.Registry
+	metrics   *registry.Metrics
+	families  []registry.Family
+	serving   atomic.Bool
 }
 
-func NewMQTT(port int, collections []collections.CollectionDefiner, app core.App) *MQTT {
+func NewMQTT(cfg *config.Config, collections []collections.CollectionDefiner, app core.App, queue *ingest.Queue, provisioning *provision.Manager, reg *metrics.Registry) *MQTT {
 	server := mqtt.New(&mqtt.Options{
 		InlineClient: true,
 		Logger: slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelError,
+			Level: cfg.LogLevel(),
 		})),
 	})
 
-	err := server.AddHook(new(auth.Hook), &auth.Options{
-		Ledger: &auth.Ledger{
-			Auth: auth.AuthRules{
-				{
-					Username: auth.RString(os.Getenv("MQTT_USERNAME")),
-					Password: auth.RString(os.Getenv("MQTT_PASSWORD")),
-					Allow:    true,
-				},
-			},
-		},
+	auth := new(hooks.Auth)
+	err := server.AddHook(auth, &hooks.AuthOptions{
+		Username: cfg.Broker.Auth.Username,
+		Password: cfg.Broker.Auth.Password,
+		Devices:  provisioning,
+		Sessions: server.Clients,
+
+		Dashboards:        wsmqtt.NewDashboards(app),
+		DashboardListener: websocketListener,
+		ReadOnly:          []string{statusTopic},
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	err = server.AddListener(listeners.NewTCP(listeners.Config{
-		Type:    "tcp",
-		ID:      "tcp",
-		Address: fmt.Sprintf("0.0.0.0:%d", port),
-	}))
+	// added before the other hooks, so floods are dropped before anything
+	// else looks at them
+	arduino := topics.NewArduino(collections, app, server, queue, reg, cfg.Ingest.LegacyTables)
+	err = server.AddHook(new(hooks.RateLimiter), &hooks.RateLimitOptions{
+		Limits:          rateLimits(cfg.Broker.RateLimits),
+		DisconnectAfter: cfg.Broker.RateLimits.DisconnectAfter,
+		Username:        cfg.Broker.Auth.Username,
+		Incidents:       arduino,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
+	err = server.AddHook(new(hooks.Storage), &hooks.StorageOptions{App: app})
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	err = server.AddHook(provision.NewHook(provisioning), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = server.AddHook(topics.NewShadowHook(arduino), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = server.AddHook(topics.NewStateHook(arduino), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Broker.Listeners.TCP.Port != 0 {
+		err = server.AddListener(&servingListener{Listener: listeners.NewTCP(listeners.Config{
+			Type:    "tcp",
+			ID:      "tcp",
+			Address: fmt.Sprintf("0.0.0.0:%d", cfg.Broker.Listeners.TCP.Port),
+		})})
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := addTLSListener(server, app, cfg.Broker.Listeners.TLS); err != nil {
+		log.Fatal(err)
+	}
+
+	var websocket *wsmqtt.Listener
+	if cfg.Broker.Listeners.WebSocket.Enabled {
+		websocket = wsmqtt.NewListener(websocketListener, cfg.Broker.Listeners.WebSocket.Path)
+		if err := server.AddListener(websocket); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	upstream, err := addBridge(server, app, cfg.Broker.Bridge)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	metrics := registry.NewMetrics(reg)
+	handlers := registry.New(app, server)
+	handlers.Use(
+		registry.Logging(app),
+		registry.Authorize(app, server, cfg.Broker.Auth.Username),
+		metrics.Middleware(),
+	)
+
 	return &MQTT{
-		server:  server,
-		arduino: topics.NewArduino(collections, app, server),
+		server:    server,
+		websocket: websocket,
+		auth:      auth,
+		bridge:    upstream,
+		registry:  handlers,
+		metrics:   metrics,
+		families:  []registry.Family{arduino},
+	}
+}
+
+// rateLimitFilters are the topics of the rate limits of the config, the
+// first limit with a matching filter applies.
+var rateLimitFilters = []struct {
+	name    string
+	filters []string
+}{
+	{name: "telemetry", filters: []string{"arduino/+/climate/#", "arduino/+/ldr/#", "arduino/+/reading/#"}},
+	{name: "health", filters: []string{"arduino/+/health/#", "arduino/+/capabilities/#"}},
+	{name: "provisioning", filters: []string{"provision/#"}},
+	{name: "device", filters: []string{"arduino/#"}},
+}
+
+func rateLimits(cfg config.RateLimits) []hooks.RateLimit {
+	limits := make([]hooks.RateLimit, 0, len(rateLimitFilters))
+	for _, r := range rateLimitFilters {
+		limit, ok := cfg.Limits[r.name]
+		if !ok {
+			continue
+		}
+		limits = append(limits, hooks.RateLimit{
+			Name:       r.name,
+			Filters:    r.filters,
+			Rate:       limit.Rate,
+			Burst:      limit.Burst,
+			MaxPayload: limit.MaxPayload,
+		})
 	}
+	return limits
+}
+
+// addTLSListener adds the TLS listener when its port is set. With a client
+// CA devices can log in with a client certificate instead of a password.
+// Renewed certificates are picked up without a restart.
+func addTLSListener(server *mqtt.Server, app core.App, cfg config.TLSListener) error {
+	if cfg.Port == 0 {
+		return nil
+	}
+
+	reloader, err := certs.NewReloader(cfg.Cert, cfg.Key, cfg.ClientCA, app.Logger)
+	if err != nil {
+		return fmt.Errorf("failed to load the MQTT TLS certificate: %w", err)
+	}
+
+	return server.AddListener(&servingListener{Listener: listeners.NewTCP(listeners.Config{
+		Type:      "tcp",
+		ID:        "tls",
+		Address:   fmt.Sprintf("0.0.0.0:%d", cfg.Port),
+		TLSConfig: reloader.TLSConfig(),
+	})})
+}
+
+// addBridge adds the bridge to the upstream broker when its URL is set. The
+// topics hold the rules, see bridge.ParseRules. The bridge connects once the
+// broker is serving and disconnects when it is closed.
+func addBridge(server *mqtt.Server, app core.App, cfg config.Bridge) (*bridge.Bridge, error) {
+	if cfg.URL == "" {
+		return nil, nil
+	}
+
+	rules, err := bridge.ParseRules(cfg.Topics)
+	if err != nil {
+		return nil, err
+	}
+
+	upstream := bridge.New(server, bridge.Options{
+		URL:        cfg.URL,
+		ClientId:   cfg.ClientId,
+		Username:   cfg.Username,
+		Password:   cfg.Password,
+		Rules:      rules,
+		BufferSize: cfg.Buffer,
+		Logger:     app.Logger,
+	})
+	if err := server.AddHook(upstream, nil); err != nil {
+		return nil, err
+	}
+
+	return upstream, nil
+}
+
+// BridgeStatus returns the status of the bridge, or false when no bridge is
+// configured.
+func (m *MQTT) BridgeStatus() (bridge.Status, bool) {
+	if m.bridge == nil {
+		return bridge.Status{}, false
+	}
+	return m.bridge.Status(), true
+}
+
+// WebsocketHandler serves MQTT over WebSockets for browser dashboards.
+func (m *MQTT) WebsocketHandler() http.Handler {
+	return m.websocket.Handler()
 }
 
 func (m *MQTT) Start() error {
 	if err := m.server.Serve(); err != nil {
 		return err
 	}
+	m.serving.Store(true)
+	m.publishStatus("online")
+	return nil
+}
+
+// Shutdown tells the clients the server is going down, disconnects them and
+// waits for the handlers of the messages already received, so their records
+// reach the ingest queue.
+func (m *MQTT) Shutdown(ctx context.Context) error {
+	if !m.serving.Swap(false) {
+		return nil
+	}
+
+	m.publishStatus("offline")
+	select {
+	case <-time.After(noticeGrace):
+	case <-ctx.Done():
+	}
+
+	if err := m.server.Close(); err != nil {
+		return err
+	}
+	if err := m.registry.Wait(ctx); err != nil {
+		return fmt.Errorf("handlers did not finish: %w", err)
+	}
 	return nil
 }
 
+func (m *MQTT) publishStatus(status string) {
+	payload, err := json.Marshal(serverStatus{Status: status, Updated: types.NowDateTime().String()})
+	if err != nil {
+		return
+	}
+	if err := m.server.Publish(statusTopic, payload, true, 1); err != nil {
+		m.server.Log.Error("failed to publish server status", slog.String("error", err.Error()))
+	}
+}
+
+// CheckDashboards disconnects the browsers that lost the access to a device
+// they subscribed to or whose token is not valid anymore.
+func (m *MQTT) CheckDashboards() {
+	m.auth.CheckDashboards()
+}
+
 func (m *MQTT) RegisterTopics() {
-	m.arduino.RegisterTopics()
+	for _, family := range m.families {
+		if err := m.registry.Register(family); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
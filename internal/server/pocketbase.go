@@ -2,19 +2,30 @@ package server
 
 import (
 	"coderero.dev/iot/smaas-server/internal/collections"
-	_ "github.com/joho/godotenv/autoload"
+	"coderero.dev/iot/smaas-server/internal/config"
+	"coderero.dev/iot/smaas-server/internal/provision"
 	"github.com/pocketbase/pocketbase"
 )
 
 type PocketBase struct {
 	app         *pocketbase.PocketBase
+	config      *config.Config
+	provision   *provision.Manager
 	collections []collections.CollectionDefiner
 }
 
-func NewPocketBase() *PocketBase {
+func NewPocketBase(cfg *config.Config) *PocketBase {
+	return newPocketBase(cfg, pocketbase.New())
+}
+
+func newPocketBase(cfg *config.Config, app *pocketbase.PocketBase) *PocketBase {
 	return &PocketBase{
-		app: pocketbase.New(),
+		app:       app,
+		config:    cfg,
+		provision: provision.NewManager(app),
 		collections: []collections.CollectionDefiner{
+			&collections.Homes{},
+			&collections.Rooms{},
 			&collections.Devices{},
 			&collections.WifiCredentials{},
 			&collections.Climate{},
@@ -26,7 +37,24 @@ func NewPocketBase() *PocketBase {
 			&collections.LDRConfig{},
 			&collections.Relay{},
 			&collections.UserPortLables{},
+			&collections.RelayEvents{},
 			&collections.MotionConfig{},
+			&collections.RejectedMessages{},
+			&collections.RejectedMessageCounts{},
+			&collections.Memberships{},
+			&collections.Invitations{},
+			&collections.Provisioning{},
+			&collections.Firmware{},
+			&collections.OtaRollouts{},
+			&collections.OtaUpdates{},
+			&collections.DeviceHealth{},
+			&collections.DeviceAlerts{},
+			&collections.DeviceCommands{},
+			&collections.DeviceShadows{},
+			&collections.SensorTypes{},
+			&collections.Sensors{},
+			&collections.Readings{},
+			&collections.BrokerState{},
 		},
 	}
 }
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"coderero.dev/iot/smaas-server/internal/provision"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/spf13/cobra"
+)
+
+type claimRequest struct {
+	Serial     string `json:"serial"`
+	ClaimCode  string `json:"claim_code"`
+	DeviceName string `json:"device_name"`
+}
+
+func (pb *PocketBase) RegisterProvisioning() {
+	pb.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		se.Router.POST("/api/devices/claim", pb.claimDeviceHandler).Bind(apis.RequireAuth())
+
+		return se.Next()
+	})
+
+	var count int
+	command := &cobra.Command{
+		Use:   "provision",
+		Short: "Generates serials and claim codes for new boards",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if count < 1 {
+				return errors.New("count must be at least 1")
+			}
+
+			if err := pb.collectionMigration(); err != nil {
+				return err
+			}
+
+			boards, err := pb.provision.Generate(count)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("serial,claim_code")
+			for _, board := range boards {
+				fmt.Printf("%s,%s\n", board.Serial, board.ClaimCode)
+			}
+			return nil
+		},
+	}
+	command.Flags().IntVarP(&count, "count", "n", 1, "number of boards to generate")
+
+	pb.app.RootCmd.AddCommand(command)
+}
+
+func (pb *PocketBase) claimDeviceHandler(e *core.RequestEvent) error {
+	var body claimRequest
+	if err := e.BindBody(&body); err != nil {
+		return e.BadRequestError("invalid request body", err)
+	}
+
+	body.DeviceName = strings.TrimSpace(body.DeviceName)
+	if body.Serial == "" || body.ClaimCode == "" || body.DeviceName == "" {
+		return e.BadRequestError("serial, claim_code and device_name are required", nil)
+	}
+
+	device, err := pb.provision.Claim(e.Auth.Id, body.Serial, body.ClaimCode, body.DeviceName)
+	switch {
+	case errors.Is(err, provision.ErrInvalidClaim):
+		return e.BadRequestError(err.Error(), nil)
+	case errors.Is(err, provision.ErrAlreadyClaimed):
+		return e.Error(http.StatusConflict, err.Error(), nil)
+	case err != nil:
+		return e.InternalServerError("failed to claim device", err)
+	}
+
+	return e.JSON(http.StatusCreated, device)
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"log/slog"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// telemetryCollections hold the readings deleted after the telemetry
+// retention.
+var telemetryCollections = []string{
+	collections.ClimateCollectionName,
+	collections.LDRCollectionName,
+	collections.ReadingsCollectionName,
+}
+
+// RegisterRetention deletes the telemetry and the rejected messages older
+// than their retention every hour. Nothing is deleted when it is 0.
+func (pb *PocketBase) RegisterRetention() {
+	retention := pb.config.Retention
+	if retention.Telemetry == 0 && retention.RejectedMessages == 0 {
+		return
+	}
+
+	pb.app.Cron().MustAdd("retention", "30 * * * *", func() {
+		if retention.Telemetry > 0 {
+			for _, collection := range telemetryCollections {
+				pb.purgeOlderThan(collection, retention.Telemetry)
+			}
+		}
+		if retention.RejectedMessages > 0 {
+			pb.purgeOlderThan(collections.RejectedMessagesCollectionName, retention.RejectedMessages)
+		}
+	})
+}
+
+// purgeOlderThan deletes the rows of a collection in one statement, as the
+// telemetry collections have no delete hooks and may hold millions of rows.
+func (pb *PocketBase) purgeOlderThan(collection string, retention time.Duration) {
+	result, err := pb.app.NonconcurrentDB().Delete(
+		collection,
+		dbx.NewExp("[[timestamp]] < {:before}", dbx.Params{"before": types.NowDateTime().Add(-retention).String()}),
+	).Execute()
+	if err != nil {
+		pb.app.Logger().Error("failed to purge old records", slog.String("collection", collection), slog.String("error", err.Error()))
+		return
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted > 0 {
+		pb.app.Logger().Info("purged old records", slog.String("collection", collection), slog.Int64("deleted", deleted))
+	}
+}
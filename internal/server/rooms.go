@@ -0,0 +1,216 @@
+package server
+
+import (
+	"net/http"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+type climateSummary struct {
+	SensorID    int     `json:"sensor_id"`
+	Lable       string  `json:"lable"`
+	Temperature float64 `json:"temperature"`
+	Humidity    float64 `json:"humidity"`
+	AirQuality  float64 `json:"air_quality"`
+	Timestamp   string  `json:"timestamp"`
+}
+
+type roomSummary struct {
+	Room        string           `json:"room"`
+	Name        string           `json:"name"`
+	Home        string           `json:"home"`
+	Devices     int              `json:"devices"`
+	Temperature *float64         `json:"temperature"`
+	Humidity    *float64         `json:"humidity"`
+	AirQuality  *float64         `json:"air_quality"`
+	Climate     []climateSummary `json:"climate"`
+	PortsTotal  int              `json:"ports_total"`
+	PortsOn     int              `json:"ports_on"`
+	LightsOn    []string         `json:"lights_on"`
+}
+
+type homeSummary struct {
+	Home  string        `json:"home"`
+	Name  string        `json:"name"`
+	Rooms []roomSummary `json:"rooms"`
+}
+
+func (pb *PocketBase) roomSummaryHandler(e *core.RequestEvent) error {
+	room, err := e.App.FindRecordById(collections.RoomsCollectionName, e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("room not found", err)
+	}
+
+	if ok, err := canView(e, room); !ok {
+		return e.NotFoundError("room not found", err)
+	}
+
+	summary, err := pb.roomSummary(e.App, room)
+	if err != nil {
+		return e.InternalServerError("failed to summarize room", err)
+	}
+
+	return e.JSON(http.StatusOK, summary)
+}
+
+func (pb *PocketBase) homeSummaryHandler(e *core.RequestEvent) error {
+	home, err := e.App.FindRecordById(collections.HomesCollectionName, e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("home not found", err)
+	}
+
+	if ok, err := canView(e, home); !ok {
+		return e.NotFoundError("home not found", err)
+	}
+
+	rooms, err := e.App.FindRecordsByFilter(
+		collections.RoomsCollectionName,
+		"home = {:home}",
+		"name",
+		0,
+		0,
+		dbx.Params{"home": home.Id},
+	)
+	if err != nil {
+		return e.InternalServerError("failed to find rooms", err)
+	}
+
+	summary := homeSummary{
+		Home:  home.Id,
+		Name:  home.GetString("name"),
+		Rooms: make([]roomSummary, 0, len(rooms)),
+	}
+	for _, room := range rooms {
+		rs, err := pb.roomSummary(e.App, room)
+		if err != nil {
+			return e.InternalServerError("failed to summarize room", err)
+		}
+		summary.Rooms = append(summary.Rooms, rs)
+	}
+
+	return e.JSON(http.StatusOK, summary)
+}
+
+// roomSummary aggregates the current state of a room. Sensors and ports
+// without an explicit room inherit the room of their device.
+func (pb *PocketBase) roomSummary(app core.App, room *core.Record) (roomSummary, error) {
+	summary := roomSummary{
+		Room:     room.Id,
+		Name:     room.GetString("name"),
+		Home:     room.GetString("home"),
+		Climate:  []climateSummary{},
+		LightsOn: []string{},
+	}
+	params := dbx.Params{"room": room.Id}
+
+	devices, err := app.CountRecords(collections.DevicesCollectionName, dbx.HashExp{"room": room.Id})
+	if err != nil {
+		return summary, err
+	}
+	summary.Devices = int(devices)
+
+	sensors, err := app.FindRecordsByFilter(
+		collections.ClimateConfigCollectionName,
+		"room = {:room} || (room = '' && device.room = {:room})",
+		"sensor_id",
+		0,
+		0,
+		params,
+	)
+	if err != nil {
+		return summary, err
+	}
+
+	var temperature, humidity, airQuality float64
+	for _, sensor := range sensors {
+		latest, err := latestClimate(app, sensor)
+		if err != nil {
+			return summary, err
+		}
+		if latest == nil {
+			continue
+		}
+
+		summary.Climate = append(summary.Climate, *latest)
+		temperature += latest.Temperature
+		humidity += latest.Humidity
+		airQuality += latest.AirQuality
+	}
+	if n := float64(len(summary.Climate)); n > 0 {
+		temperature, humidity, airQuality = temperature/n, humidity/n, airQuality/n
+		summary.Temperature = &temperature
+		summary.Humidity = &humidity
+		summary.AirQuality = &airQuality
+	}
+
+	ports, err := app.FindRecordsByFilter(
+		collections.UserPortLablesCollectionName,
+		"room = {:room} || (room = '' && device.room = {:room})",
+		"lable",
+		0,
+		0,
+		params,
+	)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.PortsTotal = len(ports)
+	for _, port := range ports {
+		if port.GetBool("state") {
+			summary.PortsOn++
+			summary.LightsOn = append(summary.LightsOn, port.GetString("lable"))
+		}
+	}
+
+	return summary, nil
+}
+
+// latestClimate returns the latest value of every metric of a climate
+// sensor from readings, which holds the telemetry whether or not it is
+// written to the climate collection too. It is nil when the sensor has not
+// reported yet.
+func latestClimate(app core.App, sensor *core.Record) (*climateSummary, error) {
+	latest := &climateSummary{
+		SensorID: sensor.GetInt("sensor_id"),
+		Lable:    sensor.GetString("lable"),
+	}
+	values := []struct {
+		metric string
+		value  *float64
+	}{
+		{"temperature", &latest.Temperature},
+		{"humidity", &latest.Humidity},
+		{"air_quality", &latest.AirQuality},
+	}
+
+	for _, v := range values {
+		readings, err := app.FindRecordsByFilter(
+			collections.ReadingsCollectionName,
+			"device = {:device} && type = 'climate' && sensor_id = {:sensor} && metric = {:metric}",
+			// readings of a batch can share their timestamp
+			"-timestamp,-@rowid",
+			1,
+			0,
+			dbx.Params{"device": sensor.GetString("device"), "sensor": sensor.GetInt("sensor_id"), "metric": v.metric},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(readings) == 0 {
+			continue
+		}
+
+		*v.value = readings[0].GetFloat("value")
+		if timestamp := readings[0].GetString("timestamp"); timestamp > latest.Timestamp {
+			latest.Timestamp = timestamp
+		}
+	}
+
+	if latest.Timestamp == "" {
+		return nil, nil
+	}
+	return latest, nil
+}
@@ -1,50 +1,114 @@
 package server
 
 import (
-	"golang.org/x/sync/errgroup"
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"coderero.dev/iot/smaas-server/internal/config"
+	"coderero.dev/iot/smaas-server/internal/ingest"
+	"coderero.dev/iot/smaas-server/internal/metrics"
+	"github.com/pocketbase/pocketbase/core"
 )
 
 type Server struct {
+	config           *config.Config
 	mqttServer       *MQTT
 	pocketbaseServer *PocketBase
+	ingestQueue      *ingest.Queue
+	metrics          *metrics.Registry
+	// heartbeat is the last run of the heartbeat job in unix nanoseconds
+	heartbeat atomic.Int64
+}
+
+func NewServer(cfg *config.Config) *Server {
+	return newServer(cfg, NewPocketBase(cfg))
 }
 
-func NewServer(mqttPort int) *Server {
-	pocketbaseServer := NewPocketBase()
+func newServer(cfg *config.Config, pocketbaseServer *PocketBase) *Server {
+	ingestQueue := ingest.NewQueue(pocketbaseServer.app, ingest.Options{
+		QueueSize:     cfg.Ingest.QueueSize,
+		BatchSize:     cfg.Ingest.BatchSize,
+		FlushInterval: cfg.Ingest.FlushInterval,
+	})
+	reg := metrics.NewRegistry()
 	return &Server{
+		config:           cfg,
 		pocketbaseServer: pocketbaseServer,
-		mqttServer:       NewMQTT(mqttPort, pocketbaseServer.GetCollectionsNames(), pocketbaseServer.app),
+		ingestQueue:      ingestQueue,
+		metrics:          reg,
+		mqttServer:       NewMQTT(cfg, pocketbaseServer.GetCollectionsNames(), pocketbaseServer.app, ingestQueue, pocketbaseServer.provision, reg),
 	}
 }
 
 func (s *Server) Start() error {
+	s.register()
+	return s.pocketbaseServer.Start()
+}
+
+// register binds the routes, hooks and workers to the app, which runs them
+// once it serves.
+func (s *Server) register() {
+	s.pocketbaseServer.RegisterConfig()
 	s.pocketbaseServer.RegisterRoutes()
+	if s.config.Broker.Listeners.WebSocket.Enabled {
+		s.pocketbaseServer.RegisterWebsocket(s.mqttServer.WebsocketHandler())
+		s.pocketbaseServer.RegisterDashboards(s.mqttServer.CheckDashboards)
+	}
+	s.pocketbaseServer.RegisterBridge(s.mqttServer.BridgeStatus)
+	s.pocketbaseServer.RegisterSharing()
+	s.pocketbaseServer.RegisterProvisioning()
+	s.pocketbaseServer.RegisterDecommission()
+	s.pocketbaseServer.RegisterCertificates()
 	s.pocketbaseServer.RegisterMigrations()
+	s.pocketbaseServer.RegisterRetention()
+	if s.config.Metrics.Enabled {
+		s.pocketbaseServer.RegisterMetrics(s.metrics)
+		s.metrics.Collect(s.mqttServer.collectMetrics)
+		s.metrics.Collect(collectIngestMetrics(s.ingestQueue))
+	}
 	s.mqttServer.RegisterTopics()
+	s.registerWorkers()
+	s.registerHealth()
+}
 
-	var g errgroup.Group
+func (s *Server) registerWorkers() {
+	s.pocketbaseServer.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		s.ingestQueue.Start()
 
-	g.Go(func() error {
-		if err := s.pocketbaseServer.Start(); err != nil {
+		// the broker is started once the collections are migrated, as it
+		// loads its state from the database
+		if err := s.mqttServer.Start(); err != nil {
 			return err
 		}
 
-		return nil
+		return se.Next()
 	})
 
-	// Run the MQTT server in a goroutine
-	g.Go(func() error {
-		if err := s.mqttServer.Start(); err != nil {
-			return err
-		}
-
-		return nil
+	// PocketBase stops serving HTTP on SIGINT and SIGTERM, or when its
+	// command exits, and closes the database after the terminate hooks
+	s.pocketbaseServer.app.OnTerminate().BindFunc(func(e *core.TerminateEvent) error {
+		s.shutdown()
+		return e.Next()
 	})
+}
+
+// shutdown drains the broker and then flushes the ingest queue, within
+// the shutdown timeout. Whatever is still pending after it is lost.
+func (s *Server) shutdown() {
+	logger := s.pocketbaseServer.app.Logger()
+	timeout := s.config.ShutdownTimeout
+	logger.Info("shutting down", slog.Duration("timeout", timeout))
 
-	// Wait for both servers to finish or return an error if any fails
-	if err := g.Wait(); err != nil {
-		return err
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := s.mqttServer.Shutdown(ctx); err != nil {
+		logger.Error("failed to stop MQTT broker", slog.String("error", err.Error()))
+	}
+	if err := s.ingestQueue.Shutdown(ctx); err != nil {
+		logger.Error("failed to flush ingest queue", slog.Int("pending", s.ingestQueue.Stats().Pending), slog.String("error", err.Error()))
 	}
 
-	return nil
+	logger.Info("shut down", slog.Any("ingest", s.ingestQueue.Stats()))
 }
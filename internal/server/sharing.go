@@ -0,0 +1,255 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// invitationTTL is how long an invitation can be accepted when no explicit
+// expiration is set.
+const invitationTTL = 7 * 24 * time.Hour
+
+func (pb *PocketBase) RegisterSharing() {
+	pb.app.OnRecordCreateExecute(
+		collections.MembershipsCollectionName,
+		collections.InvitationsCollectionName,
+	).BindFunc(func(e *core.RecordEvent) error {
+		record := e.Record
+
+		if (record.GetString("device") == "") == (record.GetString("home") == "") {
+			return errors.New("exactly one of device or home must be set")
+		}
+
+		expires := "expires"
+		if record.Collection().Name == collections.InvitationsCollectionName {
+			expires = "access_expires"
+
+			record.Set("email", strings.ToLower(strings.TrimSpace(record.GetString("email"))))
+			record.Set("status", collections.InvitationPending)
+			if record.GetDateTime("expires").IsZero() {
+				record.Set("expires", time.Now().Add(invitationTTL))
+			}
+		}
+
+		if record.GetString("role") == collections.RoleGuest && record.GetDateTime(expires).IsZero() {
+			return fmt.Errorf("guest access requires %s", expires)
+		}
+
+		return e.Next()
+	})
+
+	pb.app.OnRecordAfterCreateSuccess(collections.InvitationsCollectionName).BindFunc(func(e *core.RecordEvent) error {
+		if err := pb.sendInvitation(e.App, e.Record); err != nil {
+			e.App.Logger().Error("failed to send invitation", slog.String("invitation", e.Record.Id), slog.String("error", err.Error()))
+		}
+
+		return e.Next()
+	})
+
+	pb.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		se.Router.POST("/api/invitations/{id}/accept", pb.acceptInvitationHandler).Bind(apis.RequireAuth())
+		se.Router.POST("/api/invitations/{id}/decline", pb.declineInvitationHandler).Bind(apis.RequireAuth())
+
+		return se.Next()
+	})
+
+	pb.app.Cron().MustAdd("expired_memberships", "*/10 * * * *", func() {
+		if err := pb.deleteExpiredMemberships(); err != nil {
+			pb.app.Logger().Error("failed to delete expired memberships", slog.String("error", err.Error()))
+		}
+	})
+}
+
+func (pb *PocketBase) acceptInvitationHandler(e *core.RequestEvent) error {
+	invitation, err := pb.findInvitation(e)
+	if err != nil {
+		return err
+	}
+
+	membership := core.NewRecord(collectionSchema(pb, collections.MembershipsCollectionName))
+	err = e.App.RunInTransaction(func(txApp core.App) error {
+		existing, err := txApp.FindFirstRecordByFilter(
+			collections.MembershipsCollectionName,
+			"user = {:user} && device = {:device} && home = {:home}",
+			dbx.Params{
+				"user":   e.Auth.Id,
+				"device": invitation.GetString("device"),
+				"home":   invitation.GetString("home"),
+			},
+		)
+		switch {
+		case err == nil:
+			membership = existing
+		case !errors.Is(err, sql.ErrNoRows):
+			return err
+		}
+
+		role, expires := invitation.GetString("role"), invitation.GetDateTime("access_expires")
+		if existing != nil {
+			role, expires = mergeAccess(existing, role, expires)
+		}
+
+		membership.Set("user", e.Auth.Id)
+		membership.Set("device", invitation.GetString("device"))
+		membership.Set("home", invitation.GetString("home"))
+		membership.Set("role", role)
+		membership.Set("expires", expires)
+		if err := txApp.Save(membership); err != nil {
+			return err
+		}
+
+		invitation.Set("status", collections.InvitationAccepted)
+		return txApp.Save(invitation)
+	})
+	if err != nil {
+		return e.BadRequestError("failed to accept invitation", err)
+	}
+
+	return e.JSON(http.StatusOK, membership)
+}
+
+// roleRanks orders the roles from the least to the most privileged.
+var roleRanks = []string{collections.RoleGuest, collections.RoleMember, collections.RoleAdmin, collections.RoleOwner}
+
+// mergeAccess returns the role and expiry of an existing membership after
+// accepting an invitation. The invitation only raises the role, e.g. an
+// owner accepting a guest invitation stays owner, and only extends the
+// access of the same role.
+func mergeAccess(membership *core.Record, role string, expires types.DateTime) (string, types.DateTime) {
+	current, currentExpires := membership.GetString("role"), membership.GetDateTime("expires")
+
+	switch rank, currentRank := slices.Index(roleRanks, role), slices.Index(roleRanks, current); {
+	case rank > currentRank:
+		return role, expires
+	case rank < currentRank:
+		return current, currentExpires
+	}
+
+	// an empty expiry never expires
+	if currentExpires.IsZero() || (!expires.IsZero() && currentExpires.After(expires)) {
+		return current, currentExpires
+	}
+	return role, expires
+}
+
+func (pb *PocketBase) declineInvitationHandler(e *core.RequestEvent) error {
+	invitation, err := pb.findInvitation(e)
+	if err != nil {
+		return err
+	}
+
+	invitation.Set("status", collections.InvitationDeclined)
+	if err := e.App.Save(invitation); err != nil {
+		return e.BadRequestError("failed to decline invitation", err)
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// findInvitation returns the pending invitation of the path addressed to the
+// authenticated user. The address has to be verified, otherwise anyone
+// signing up with it could answer the invitation.
+func (pb *PocketBase) findInvitation(e *core.RequestEvent) (*core.Record, error) {
+	invitation, err := e.App.FindRecordById(collections.InvitationsCollectionName, e.Request.PathValue("id"))
+	if err != nil || !strings.EqualFold(invitation.GetString("email"), e.Auth.Email()) {
+		return nil, e.NotFoundError("invitation not found", err)
+	}
+
+	if !e.Auth.Verified() {
+		return nil, e.ForbiddenError("email address is not verified", nil)
+	}
+
+	if invitation.GetString("status") != collections.InvitationPending {
+		return nil, e.BadRequestError("invitation was already answered", nil)
+	}
+
+	if expires := invitation.GetDateTime("expires"); !expires.IsZero() && expires.Time().Before(time.Now()) {
+		return nil, e.BadRequestError("invitation has expired", nil)
+	}
+
+	return invitation, nil
+}
+
+func (pb *PocketBase) sendInvitation(app core.App, invitation *core.Record) error {
+	target := "home"
+	var name string
+	if deviceId := invitation.GetString("device"); deviceId != "" {
+		target = "device"
+		device, err := app.FindRecordById(collections.DevicesCollectionName, deviceId)
+		if err != nil {
+			return err
+		}
+		name = device.GetString("device_name")
+	} else {
+		home, err := app.FindRecordById(collections.HomesCollectionName, invitation.GetString("home"))
+		if err != nil {
+			return err
+		}
+		name = home.GetString("name")
+	}
+
+	meta := app.Settings().Meta
+	message := &mailer.Message{
+		From: mail.Address{
+			Name:    meta.SenderName,
+			Address: meta.SenderAddress,
+		},
+		To:      []mail.Address{{Address: invitation.GetString("email")}},
+		Subject: fmt.Sprintf("You have been invited to %s", name),
+		HTML: fmt.Sprintf(
+			"<p>You have been invited to the %s <strong>%s</strong> as %s.</p><p>Sign in to %s to accept or decline the invitation.</p>",
+			target,
+			html.EscapeString(name),
+			html.EscapeString(invitation.GetString("role")),
+			html.EscapeString(meta.AppURL),
+		),
+	}
+
+	return app.NewMailClient().Send(message)
+}
+
+func (pb *PocketBase) deleteExpiredMemberships() error {
+	expired, err := pb.app.FindRecordsByFilter(
+		collections.MembershipsCollectionName,
+		"expires != '' && expires < {:now}",
+		"",
+		0,
+		0,
+		dbx.Params{"now": types.NowDateTime().String()},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, membership := range expired {
+		if err := pb.app.Delete(membership); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func collectionSchema(pb *PocketBase, name string) *core.Collection {
+	for _, c := range pb.collections {
+		if c.Name() == name {
+			return c.Schema()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestMergeAccess(t *testing.T) {
+	soon, _ := types.ParseDateTime(time.Now().Add(time.Hour))
+	later, _ := types.ParseDateTime(time.Now().Add(24 * time.Hour))
+	never := types.DateTime{}
+
+	tests := []struct {
+		name        string
+		role        string
+		expires     types.DateTime
+		invited     string
+		invitedExp  types.DateTime
+		wantRole    string
+		wantExpires types.DateTime
+	}{
+		{name: "raised", role: collections.RoleGuest, expires: soon, invited: collections.RoleAdmin, invitedExp: never, wantRole: collections.RoleAdmin, wantExpires: never},
+		{name: "not lowered", role: collections.RoleOwner, expires: never, invited: collections.RoleGuest, invitedExp: soon, wantRole: collections.RoleOwner, wantExpires: never},
+		{name: "extended", role: collections.RoleGuest, expires: soon, invited: collections.RoleGuest, invitedExp: later, wantRole: collections.RoleGuest, wantExpires: later},
+		{name: "not shortened", role: collections.RoleGuest, expires: later, invited: collections.RoleGuest, invitedExp: soon, wantRole: collections.RoleGuest, wantExpires: later},
+		{name: "never expiring kept", role: collections.RoleMember, expires: never, invited: collections.RoleMember, invitedExp: soon, wantRole: collections.RoleMember, wantExpires: never},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			membership := core.NewRecord((&collections.Memberships{}).Schema())
+			membership.Set("role", tt.role)
+			membership.Set("expires", tt.expires)
+
+			role, expires := mergeAccess(membership, tt.invited, tt.invitedExp)
+			if role != tt.wantRole || !expires.Equal(tt.wantExpires) {
+				t.Errorf("mergeAccess = %s %v, want %s %v", role, expires, tt.wantRole, tt.wantExpires)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/config"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pocketbase/pocketbase"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestServer returns a server on a temporary data directory that serves
+// the broker on a free port, without the HTTP server. The ingest queue is
+// only flushed on shutdown.
+func newTestServer(t *testing.T, configure func(cfg *config.Config)) (*Server, *pocketbase.PocketBase) {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Admin = config.Admin{Email: "admin@example.com", Password: "password123456"}
+	cfg.Broker.Auth = config.Auth{Username: "server", Password: "secret"}
+	cfg.Broker.Listeners.TCP.Port = freePort(t)
+	cfg.Broker.Listeners.WebSocket.Enabled = false
+	cfg.Ingest.BatchSize = 1000
+	cfg.Ingest.FlushInterval = time.Hour
+	cfg.ShutdownTimeout = 10 * time.Second
+	if configure != nil {
+		configure(cfg)
+	}
+
+	app := pocketbase.NewWithConfig(pocketbase.Config{DefaultDataDir: t.TempDir(), HideStartBanner: true})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	s := newServer(cfg, newPocketBase(cfg, app))
+	s.register()
+
+	// runs the serve hooks like the serve command, which migrates the
+	// collections and starts the broker and the workers
+	router, err := apis.NewRouter(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := &core.ServeEvent{App: app, Router: router, Server: &http.Server{}}
+	if err := app.OnServe().Trigger(serve, func(e *core.ServeEvent) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	return s, app
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// newDevice adds a device with a climate sensor.
+func newDevice(t *testing.T, app core.App) string {
+	t.Helper()
+
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := core.NewRecord(users)
+	user.SetEmail("owner@example.com")
+	user.SetPassword("password123456")
+	if err := app.Save(user); err != nil {
+		t.Fatal(err)
+	}
+
+	devices, err := app.FindCollectionByNameOrId(collections.DevicesCollectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	device := core.NewRecord(devices)
+	device.Set("user", user.Id)
+	device.Set("device_name", "hall")
+	device.Set("device_status", "online")
+	if err := app.Save(device); err != nil {
+		t.Fatal(err)
+	}
+
+	climateConfig, err := app.FindCollectionByNameOrId(collections.ClimateConfigCollectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sensor := core.NewRecord(climateConfig)
+	sensor.Set("device", device.Id)
+	sensor.Set("sensor_id", 1)
+	sensor.Set("lable", "hall")
+	sensor.Set("dht22_port", 2)
+	sensor.Set("aqi_port", 3)
+	if err := app.Save(sensor); err != nil {
+		t.Fatal(err)
+	}
+
+	return device.Id
+}
+
+func TestShutdownStoresReceivedMessages(t *testing.T) {
+	t.Run("legacy tables", func(t *testing.T) { testShutdownStoresReceivedMessages(t, true) })
+	t.Run("readings only", func(t *testing.T) { testShutdownStoresReceivedMessages(t, false) })
+}
+
+func testShutdownStoresReceivedMessages(t *testing.T, legacyTables bool) {
+	s, app := newTestServer(t, func(cfg *config.Config) { cfg.Ingest.LegacyTables = legacyTables })
+	deviceId := newDevice(t, app)
+
+	client := paho.NewClient(paho.NewClientOptions().
+		AddBroker(fmt.Sprintf("tcp://127.0.0.1:%d", s.config.Broker.Listeners.TCP.Port)).
+		SetClientID("publisher").
+		SetUsername(s.config.Broker.Auth.Username).
+		SetPassword(s.config.Broker.Auth.Password).
+		SetAutoReconnect(false))
+	if token := client.Connect(); !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+		t.Fatalf("failed to connect: %v", token.Error())
+	}
+	defer client.Disconnect(0)
+
+	const messages = 50
+	for i := range messages {
+		payload, err := proto.Marshal(&transporter.ClimateData{Id: 1, Temperature: 20 + float32(i%10), Humidity: 40, Aqi: 50})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// acknowledged once the handlers have queued the records
+		token := client.Publish("arduino/"+deviceId+"/climate", 1, false, payload)
+		if !token.WaitTimeout(5*time.Second) || token.Error() != nil {
+			t.Fatalf("failed to publish message %d: %v", i, token.Error())
+		}
+	}
+
+	if written := s.ingestQueue.Stats().Written; written != 0 {
+		t.Fatalf("written = %d before the shutdown, want the records still queued", written)
+	}
+
+	// the database is left open, pocketbase closes it after the terminate
+	// hooks
+	terminate := &core.TerminateEvent{App: app}
+	if err := app.OnTerminate().Trigger(terminate, func(e *core.TerminateEvent) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.mqttServer.serving.Load() {
+		t.Fatal("broker is serving after the shutdown")
+	}
+	if stats := s.ingestQueue.Stats(); stats.Pending != 0 || stats.Dropped != 0 || stats.Failed != 0 {
+		t.Fatalf("ingest stats = %+v, want every record written", stats)
+	}
+
+	climate, err := app.CountRecords(collections.ClimateCollectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantClimate := int64(0)
+	if legacyTables {
+		wantClimate = messages
+	}
+	if climate != wantClimate {
+		t.Fatalf("climate rows = %d, want %d", climate, wantClimate)
+	}
+
+	// a reading per metric of the climate sensor
+	readings, err := app.CountRecords(collections.ReadingsCollectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if readings != 3*messages {
+		t.Fatalf("readings = %d, want %d", readings, 3*messages)
+	}
+
+	// the room summary reads readings, with or without the legacy tables
+	sensor, err := app.FindFirstRecordByData(collections.ClimateConfigCollectionName, "device", deviceId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := latestClimate(app, sensor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest == nil || latest.Temperature != 29 || latest.Humidity != 40 || latest.AirQuality != 50 {
+		t.Fatalf("latest climate = %+v, want the last message", latest)
+	}
+
+	rejected, err := app.CountRecords(collections.RejectedMessagesCollectionName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected != 0 {
+		t.Fatalf("rejected rows = %d, want 0", rejected)
+	}
+}
@@ -4,13 +4,18 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
+	"coderero.dev/iot/smaas-server/internal/bridge"
 	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/topics"
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/apis"
 	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/plugins/migratecmd"
+	"github.com/pocketbase/pocketbase/tools/search"
+	"github.com/spf13/cast"
 )
 
 func (pb *PocketBase) RegisterMigrations() {
@@ -132,6 +137,81 @@ func (pb *PocketBase) RegisterRoutes() {
 		// serves static files from the provided public dir (if exists)
 		se.Router.GET("/{path...}", apis.Static(os.DirFS("./pb_public"), false))
 
+		se.Router.GET("/api/ports/{id}/usage", pb.portUsageHandler).Bind(apis.RequireAuth())
+		se.Router.GET("/api/devices/{id}/usage", pb.deviceUsageHandler).Bind(apis.RequireAuth())
+		se.Router.GET("/api/rooms/{id}/summary", pb.roomSummaryHandler).Bind(apis.RequireAuth())
+		se.Router.GET("/api/homes/{id}/summary", pb.homeSummaryHandler).Bind(apis.RequireAuth())
+		se.Router.POST("/api/devices/{id}/commands", pb.deviceCommandHandler).Bind(apis.RequireAuth())
+
+		return se.Next()
+	})
+}
+
+// RegisterWebsocket mounts the MQTT WebSocket listener on its path, /mqtt by
+// default. Browsers log in with their auth token as MQTT password.
+func (pb *PocketBase) RegisterWebsocket(handler http.Handler) {
+	pb.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		se.Router.GET(pb.config.Broker.Listeners.WebSocket.Path, apis.WrapStdHandler(handler))
+
+		return se.Next()
+	})
+}
+
+// dashboardAccess are the fields of the collections giving users the access to
+// devices. Every change of memberships and users counts, e.g. a new password.
+var dashboardAccess = map[string][]string{
+	collections.MembershipsCollectionName: nil,
+	collections.DevicesCollectionName:     {"user", "room"},
+	collections.RoomsCollectionName:       {"home"},
+	collections.HomesCollectionName:       {"owner"},
+	"users":                               nil,
+}
+
+// RegisterDashboards checks the connected browsers again when the access to
+// devices changes, as it is only checked when they subscribe, and every
+// minute for expired tokens.
+func (pb *PocketBase) RegisterDashboards(check func()) {
+	names := make([]string, 0, len(dashboardAccess))
+	for name := range dashboardAccess {
+		names = append(names, name)
+	}
+
+	pb.app.OnRecordAfterUpdateSuccess(names...).BindFunc(func(e *core.RecordEvent) error {
+		err := e.Next()
+
+		fields := dashboardAccess[e.Record.Collection().Name]
+		changed := fields == nil
+		for _, field := range fields {
+			changed = changed || e.Record.GetString(field) != e.Record.Original().GetString(field)
+		}
+		if changed {
+			check()
+		}
+
+		return err
+	})
+
+	pb.app.OnRecordAfterDeleteSuccess(names...).BindFunc(func(e *core.RecordEvent) error {
+		err := e.Next()
+		check()
+		return err
+	})
+
+	pb.app.Cron().MustAdd("dashboard_sessions", "* * * * *", check)
+}
+
+// RegisterBridge serves the status of the bridge to the upstream broker to
+// superusers.
+func (pb *PocketBase) RegisterBridge(status func() (bridge.Status, bool)) {
+	pb.app.OnServe().BindFunc(func(se *core.ServeEvent) error {
+		se.Router.GET("/api/bridge", func(e *core.RequestEvent) error {
+			s, ok := status()
+			if !ok {
+				return e.NotFoundError("no bridge is configured", nil)
+			}
+			return e.JSON(http.StatusOK, s)
+		}).Bind(apis.RequireSuperuserAuth())
+
 		return se.Next()
 	})
 }
@@ -152,12 +232,51 @@ func (pb *PocketBase) collectionMigration() error {
 		_, err := pb.app.FindCollectionByNameOrId(collection.Name())
 		if err != nil {
 			collectionDefiner := collection.Schema()
+			// rules can reference collections that are created later in the
+			// loop, they are applied by syncCollection once all exist
+			collectionDefiner.ListRule = nil
+			collectionDefiner.ViewRule = nil
+			collectionDefiner.CreateRule = nil
+			collectionDefiner.UpdateRule = nil
+			collectionDefiner.DeleteRule = nil
 			if err := pb.app.Save(collectionDefiner); err != nil {
 				return err
 			}
 		}
 	}
 
+	for _, collection := range pb.collections {
+		existing, err := pb.app.FindCollectionByNameOrId(collection.Name())
+		if err != nil {
+			return err
+		}
+
+		if err := pb.syncCollection(existing, collection.Schema()); err != nil {
+			return err
+		}
+	}
+
+	// checked once every collection is synced, as the rules reference
+	// fields of the other collections
+	for _, collection := range pb.collections {
+		existing, err := pb.app.FindCollectionByNameOrId(collection.Name())
+		if err != nil {
+			return err
+		}
+
+		if err := pb.checkRules(existing); err != nil {
+			return err
+		}
+	}
+
+	if err := pb.setupSensorTypes(pb.app); err != nil {
+		return err
+	}
+
+	if err := topics.MigrateSensors(pb.app); err != nil {
+		return err
+	}
+
 	err = pb.setupRelays(pb.app)
 	if err != nil {
 		if err.Error() == "id: Value must be unique" {
@@ -168,6 +287,73 @@ func (pb *PocketBase) collectionMigration() error {
 	return nil
 }
 
+// syncCollection applies the access rules of the collection schema and adds
+// the fields that were introduced after the collection was first created.
+func (pb *PocketBase) syncCollection(existing *core.Collection, schema *core.Collection) error {
+	changed := false
+	rules := []struct {
+		current **string
+		defined *string
+	}{
+		{&existing.ListRule, schema.ListRule},
+		{&existing.ViewRule, schema.ViewRule},
+		{&existing.CreateRule, schema.CreateRule},
+		{&existing.UpdateRule, schema.UpdateRule},
+		{&existing.DeleteRule, schema.DeleteRule},
+	}
+	for _, rule := range rules {
+		if cast.ToString(*rule.current) != cast.ToString(rule.defined) || (*rule.current == nil) != (rule.defined == nil) {
+			*rule.current = rule.defined
+			changed = true
+		}
+	}
+
+	if !existing.IsView() {
+		for _, field := range schema.Fields {
+			if existing.Fields.GetByName(field.GetName()) != nil {
+				continue
+			}
+			existing.Fields.Add(field)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	// the collection ids equal their names, which the collection validator
+	// rejects as a name clash on every update
+	return pb.app.SaveNoValidate(existing)
+}
+
+// checkRules parses the access rules of the collection like the collection
+// validator does, which syncCollection skips, so a broken rule fails the
+// start instead of the requests.
+func (pb *PocketBase) checkRules(collection *core.Collection) error {
+	rules := []struct {
+		name string
+		rule *string
+	}{
+		{"list", collection.ListRule},
+		{"view", collection.ViewRule},
+		{"create", collection.CreateRule},
+		{"update", collection.UpdateRule},
+		{"delete", collection.DeleteRule},
+	}
+	for _, rule := range rules {
+		if cast.ToString(rule.rule) == "" {
+			continue
+		}
+
+		resolver := core.NewRecordFieldResolver(pb.app, collection, nil, true)
+		if _, err := search.FilterData(*rule.rule).BuildExpr(resolver); err != nil {
+			return fmt.Errorf("invalid %s rule of %s: %w", rule.name, collection.Name, err)
+		}
+	}
+	return nil
+}
+
 func (pb *PocketBase) setupSuperuser(app core.App) error {
 	superuser, err := app.FindCollectionByNameOrId(core.CollectionNameSuperusers)
 	if err != nil {
@@ -175,8 +361,8 @@ func (pb *PocketBase) setupSuperuser(app core.App) error {
 	}
 
 	record := core.NewRecord(superuser)
-	record.Set("email", os.Getenv("ADMIN_EMAIL"))
-	record.Set("password", os.Getenv("ADMIN_PASSWORD"))
+	record.Set("email", pb.config.Admin.Email)
+	record.Set("password", pb.config.Admin.Password)
 
 	return app.Save(record)
 }
@@ -210,6 +396,36 @@ func (pb *PocketBase) setupRelays(app core.App) error {
 	return nil
 }
 
+// setupSensorTypes adds the default sensor types missing from the catalog.
+// Types changed or added by an admin are left alone.
+func (pb *PocketBase) setupSensorTypes(app core.App) error {
+	sensorTypes, err := app.FindCollectionByNameOrId(collections.SensorTypesCollectionName)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range collections.DefaultSensorTypes {
+		_, err := app.FindFirstRecordByData(sensorTypes, "name", t.TypeName)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		record := core.NewRecord(sensorTypes)
+		record.Set("name", t.TypeName)
+		record.Set("label", t.Label)
+		record.Set("metrics", t.Metrics)
+		record.Set("ports", t.Ports)
+		if err := app.Save(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (pb *PocketBase) newRelayPort(port int, typeId int, deviceId string) *core.Record {
 	userPortLablesCollection, err := pb.app.FindCollectionByNameOrId(collections.UserPortLablesCollectionName)
 	if err != nil {
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// maxUsageWindow limits the time range of a single usage query.
+const maxUsageWindow = 366 * 24 * time.Hour
+
+var (
+	errInvalidInterval = errors.New("interval must be day or month")
+	errInvalidWindow   = errors.New("invalid from/to time range")
+)
+
+type usageBucket struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	OnSeconds int64     `json:"on_seconds"`
+	EnergyWh  float64   `json:"energy_wh"`
+}
+
+type portUsage struct {
+	Port      string        `json:"port"`
+	Lable     string        `json:"lable"`
+	Watts     float64       `json:"watts"`
+	OnSeconds int64         `json:"on_seconds"`
+	EnergyWh  float64       `json:"energy_wh"`
+	Buckets   []usageBucket `json:"buckets"`
+}
+
+type usageResponse struct {
+	From     time.Time   `json:"from"`
+	To       time.Time   `json:"to"`
+	Interval string      `json:"interval"`
+	Ports    []portUsage `json:"ports"`
+}
+
+// onInterval is a period during which a relay port was switched on.
+type onInterval struct {
+	start time.Time
+	end   time.Time
+}
+
+func (pb *PocketBase) portUsageHandler(e *core.RequestEvent) error {
+	port, err := e.App.FindRecordById(collections.UserPortLablesCollectionName, e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("port not found", err)
+	}
+
+	if ok, err := canView(e, port); !ok {
+		return e.NotFoundError("port not found", err)
+	}
+
+	from, to, interval, err := usageWindow(e)
+	if err != nil {
+		return e.BadRequestError(err.Error(), nil)
+	}
+
+	usage, err := pb.portUsage(e.App, port, from, to, interval)
+	if err != nil {
+		return e.InternalServerError("failed to compute port usage", err)
+	}
+
+	return e.JSON(http.StatusOK, usageResponse{
+		From:     from,
+		To:       to,
+		Interval: interval,
+		Ports:    []portUsage{usage},
+	})
+}
+
+func (pb *PocketBase) deviceUsageHandler(e *core.RequestEvent) error {
+	device, err := e.App.FindRecordById(collections.DevicesCollectionName, e.Request.PathValue("id"))
+	if err != nil {
+		return e.NotFoundError("device not found", err)
+	}
+
+	if ok, err := canView(e, device); !ok {
+		return e.NotFoundError("device not found", err)
+	}
+
+	from, to, interval, err := usageWindow(e)
+	if err != nil {
+		return e.BadRequestError(err.Error(), nil)
+	}
+
+	ports, err := e.App.FindRecordsByFilter(
+		collections.UserPortLablesCollectionName,
+		"device = {:device}",
+		"relay,port",
+		0,
+		0,
+		dbx.Params{"device": device.Id},
+	)
+	if err != nil {
+		return e.InternalServerError("failed to find device ports", err)
+	}
+
+	response := usageResponse{
+		From:     from,
+		To:       to,
+		Interval: interval,
+		Ports:    make([]portUsage, 0, len(ports)),
+	}
+	for _, port := range ports {
+		usage, err := pb.portUsage(e.App, port, from, to, interval)
+		if err != nil {
+			return e.InternalServerError("failed to compute port usage", err)
+		}
+		response.Ports = append(response.Ports, usage)
+	}
+
+	return e.JSON(http.StatusOK, response)
+}
+
+func (pb *PocketBase) portUsage(app core.App, port *core.Record, from, to time.Time, interval string) (portUsage, error) {
+	intervals, err := pb.onIntervals(app, port, from, to)
+	if err != nil {
+		return portUsage{}, err
+	}
+
+	watts := port.GetFloat("watts")
+	usage := portUsage{
+		Port:    port.Id,
+		Lable:   port.GetString("lable"),
+		Watts:   watts,
+		Buckets: []usageBucket{},
+	}
+
+	for start := from; start.Before(to); {
+		end := nextBucket(start, interval)
+		if end.After(to) {
+			end = to
+		}
+
+		var seconds int64
+		for _, on := range intervals {
+			seconds += overlap(on, start, end)
+		}
+
+		usage.Buckets = append(usage.Buckets, usageBucket{
+			Start:     start,
+			End:       end,
+			OnSeconds: seconds,
+			EnergyWh:  energy(watts, seconds),
+		})
+		usage.OnSeconds += seconds
+		start = end
+	}
+	usage.EnergyWh = energy(watts, usage.OnSeconds)
+
+	return usage, nil
+}
+
+// onIntervals rebuilds the periods during which the port was on within the
+// window from the recorded relay events.
+func (pb *PocketBase) onIntervals(app core.App, port *core.Record, from, to time.Time) ([]onInterval, error) {
+	now := time.Now().UTC()
+	if to.After(now) {
+		to = now
+	}
+
+	previous, err := app.FindRecordsByFilter(
+		collections.RelayEventsCollectionName,
+		"port = {:port} && timestamp < {:from}",
+		"-timestamp",
+		1,
+		0,
+		dbx.Params{"port": port.Id, "from": from.Format(types.DefaultDateLayout)},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := app.FindRecordsByFilter(
+		collections.RelayEventsCollectionName,
+		"port = {:port} && timestamp >= {:from} && timestamp < {:to}",
+		"timestamp",
+		0,
+		0,
+		dbx.Params{
+			"port": port.Id,
+			"from": from.Format(types.DefaultDateLayout),
+			"to":   to.Format(types.DefaultDateLayout),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var on bool
+	if len(previous) > 0 {
+		on = previous[0].GetBool("state")
+	} else if lastOn := port.GetDateTime("last_on"); port.GetBool("state") && !lastOn.IsZero() {
+		// the port was switched on before any event was recorded
+		on = lastOn.Time().Before(from)
+	}
+
+	intervals := []onInterval{}
+	start := from
+	for _, event := range events {
+		at := event.GetDateTime("timestamp").Time()
+		state := event.GetBool("state")
+		if state == on {
+			continue
+		}
+		if on {
+			intervals = append(intervals, onInterval{start: start, end: at})
+		} else {
+			start = at
+		}
+		on = state
+	}
+	if on && start.Before(to) {
+		intervals = append(intervals, onInterval{start: start, end: to})
+	}
+
+	return intervals, nil
+}
+
+func usageWindow(e *core.RequestEvent) (time.Time, time.Time, string, error) {
+	query := e.Request.URL.Query()
+
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "day" && interval != "month" {
+		return time.Time{}, time.Time{}, "", errInvalidInterval
+	}
+
+	now := time.Now().UTC()
+	to := now
+	if raw := query.Get("to"); raw != "" {
+		parsed, err := types.ParseDateTime(raw)
+		if err != nil || parsed.IsZero() {
+			return time.Time{}, time.Time{}, "", errInvalidWindow
+		}
+		to = parsed.Time()
+	}
+
+	var from time.Time
+	if raw := query.Get("from"); raw != "" {
+		parsed, err := types.ParseDateTime(raw)
+		if err != nil || parsed.IsZero() {
+			return time.Time{}, time.Time{}, "", errInvalidWindow
+		}
+		from = parsed.Time()
+	} else if interval == "day" {
+		from = time.Date(to.Year(), to.Month(), to.Day()-6, 0, 0, 0, 0, time.UTC)
+	} else {
+		from = time.Date(to.Year(), to.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	if !from.Before(to) || to.Sub(from) > maxUsageWindow {
+		return time.Time{}, time.Time{}, "", errInvalidWindow
+	}
+
+	return from, to, interval, nil
+}
+
+func nextBucket(start time.Time, interval string) time.Time {
+	if interval == "month" {
+		return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
+func overlap(on onInterval, start, end time.Time) int64 {
+	if on.start.After(start) {
+		start = on.start
+	}
+	if on.end.Before(end) {
+		end = on.end
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int64(end.Sub(start).Seconds())
+}
+
+func energy(watts float64, seconds int64) float64 {
+	return watts * float64(seconds) / 3600
+}
+
+// canView reports whether the request is allowed to see the record
+// according to its collection view rule.
+func canView(e *core.RequestEvent, record *core.Record) (bool, error) {
+	info, err := e.RequestInfo()
+	if err != nil {
+		return false, err
+	}
+
+	return e.App.CanAccessRecord(record, info, record.Collection().ViewRule)
+}
@@ -1,96 +1,146 @@
 package topics
 
 import (
+	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/ingest"
+	"coderero.dev/iot/smaas-server/internal/metrics"
 	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
 	mqtt "github.com/mochi-mqtt/server/v2"
-	"github.com/mochi-mqtt/server/v2/packets"
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
-	"google.golang.org/protobuf/proto"
 )
 
 type Arduino struct {
 	app         core.App
 	mqttServer  *mqtt.Server
+	ingest      *ingest.Queue
 	syncRequest bool
 	collections []collections.CollectionDefiner
+	// legacyTables writes the climate and LDR telemetry to the climate and
+	// ldr collections as well as to readings
+	legacyTables bool
+	// shadowMu serializes the updates of the device shadows
+	shadowMu sync.Mutex
+	// formats caches the payload format of the devices
+	formats sync.Map
+	// devices caches the devices that exist
+	devices sync.Map
+	// suffixes remembers the devices sending with the JSON suffix
+	suffixes sync.Map
+	// stateMu serializes the merges of the sensor states
+	stateMu sync.Mutex
+
+	publishErrors *metrics.CounterVec
 }
 
-func NewArduino(collections []collections.CollectionDefiner, app core.App, mqttServer *mqtt.Server) *Arduino {
+func NewArduino(collections []collections.CollectionDefiner, app core.App, mqttServer *mqtt.Server, queue *ingest.Queue, reg *metrics.Registry, legacyTables bool) *Arduino {
 	return &Arduino{
-		collections: collections,
-		app:         app,
-		mqttServer:  mqttServer,
-		syncRequest: false,
+		collections:   collections,
+		legacyTables:  legacyTables,
+		app:           app,
+		mqttServer:    mqttServer,
+		ingest:        queue,
+		syncRequest:   false,
+		publishErrors: reg.Counter("smaas_publish_errors_total", "Messages the server failed to publish per topic.", "topic"),
 	}
 }
-func (a *Arduino) Climate(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
-	a.app.Logger().Info("climate data received", slog.String("topic", pk.TopicName))
-	deviceId := a.getId(pk.TopicName)
-	if deviceId == "" {
-		a.app.Logger().Error("failed to get device id from topic", slog.String("topic", pk.TopicName))
-		return
+
+// publish publishes a message of the server and counts the failures per
+// topic, with the device id replaced by a wildcard.
+func (a *Arduino) publish(topic string, payload []byte, retain bool, qos byte) error {
+	err := a.mqttServer.Publish(topic, payload, retain, qos)
+	if err != nil {
+		parts := strings.Split(topic, "/")
+		if len(parts) > 1 {
+			parts[1] = "+"
+		}
+		a.publishErrors.With(strings.Join(parts, "/")).Inc()
 	}
+	return err
+}
+func (a *Arduino) Climate(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
+	a.app.Logger().Info("climate data received", slog.String("topic", pk.TopicName))
 
 	var d transporter.ClimateData
-	if err := proto.Unmarshal(pk.Payload, &d); err != nil {
+	if err := msg.Decode(&d); err != nil {
 		a.app.Logger().Error("failed to unmarshal climate data", slog.String("error", err.Error()))
+		a.reject(deviceId, 0, pk, fmt.Errorf("malformed payload: %w", err))
 		return
 	}
 
-	record := core.NewRecord(a.getCollection(collections.ClimateCollectionName))
-	record.Set("sensor_id", int(d.Id))
-	record.Set("device", deviceId)
-	record.Set("temperature", d.Temperature)
-	record.Set("humidity", d.Humidity)
-	record.Set("air_quality", int(d.Aqi))
+	if err := a.validateClimate(deviceId, &d); err != nil {
+		a.reject(deviceId, int(d.Id), pk, err)
+		return
+	}
 
 	a.app.Logger().Info("climate data", slog.String("device_id", deviceId), slog.String("temperature", fmt.Sprintf("%f", d.Temperature)), slog.String("humidity", fmt.Sprintf("%f", d.Humidity)), slog.String("air_quality", fmt.Sprintf("%d", d.Aqi)))
 
-	if err := a.app.Save(record); err != nil {
-		return
+	if a.legacyTables {
+		record := core.NewRecord(a.getCollection(collections.ClimateCollectionName))
+		record.Set("sensor_id", int(d.Id))
+		record.Set("device", deviceId)
+		record.Set("temperature", d.Temperature)
+		record.Set("humidity", d.Humidity)
+		record.Set("air_quality", int(d.Aqi))
+
+		if !a.ingest.Enqueue(record) {
+			a.app.Logger().Debug("climate data dropped", slog.String("device_id", deviceId))
+		}
 	}
+
+	a.storeReading(deviceId, sensorClimate, int(d.Id), climateMetrics(&d))
 }
 
-func (a *Arduino) LDR(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
-	deviceId := a.getId(pk.TopicName)
-	if deviceId == "" {
-		a.app.Logger().Error("failed to get device id from topic", slog.String("topic", pk.TopicName))
-		return
-	}
+func (a *Arduino) LDR(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
 
 	var d transporter.LDRData
-	if err := proto.Unmarshal(pk.Payload, &d); err != nil {
+	if err := msg.Decode(&d); err != nil {
 		a.app.Logger().Error("failed to unmarshal LDR data", slog.String("error", err.Error()))
+		a.reject(deviceId, 0, pk, fmt.Errorf("malformed payload: %w", err))
 		return
 	}
-	record := core.NewRecord(a.getCollection(collections.LDRCollectionName))
-	record.Set("sensor_id", d.Id)
-	record.Set("device", deviceId)
-	record.Set("ldr_value", d.Value)
 
-	if err := a.app.Save(record); err != nil {
-		a.app.Logger().Error("failed to save LDR data", slog.String("error", err.Error()))
+	if err := a.validateLDR(deviceId, &d); err != nil {
+		a.reject(deviceId, int(d.Id), pk, err)
 		return
 	}
+
+	if a.legacyTables {
+		record := core.NewRecord(a.getCollection(collections.LDRCollectionName))
+		record.Set("sensor_id", int(d.Id))
+		record.Set("device", deviceId)
+		record.Set("ldr_value", int(d.Value))
+
+		if !a.ingest.Enqueue(record) {
+			a.app.Logger().Debug("LDR data dropped", slog.String("device_id", deviceId))
+		}
+	}
+
+	a.storeReading(deviceId, sensorLDR, int(d.Id), ldrMetrics(&d))
 }
 
-func (a *Arduino) Relay(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
-	deviceId := a.getId(pk.TopicName)
-	if deviceId == "" {
-		a.app.Logger().Error("failed to get device id from topic", slog.String("topic", pk.TopicName))
+func (a *Arduino) Relay(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
+	// relay commands published by the server itself are echoed back to the
+	// inline subscription and must not be treated as device reports
+	if pk.Origin == mqtt.InlineClientId {
 		return
 	}
 
 	var d transporter.RelayState
-	if err := proto.Unmarshal(pk.Payload, &d); err != nil {
+	if err := msg.Decode(&d); err != nil {
 		a.app.Logger().Error("failed to unmarshal relay data", slog.String("error", err.Error()))
 		return
 	}
@@ -131,8 +181,20 @@ func (a *Arduino) Relay(cl *mqtt.Client, sub packets.Subscription, pk packets.Pa
 		state = false
 	}
 
+	err = a.updateReported(deviceId, d.Version, func(reported *shadowState) {
+		reported.Relays[relayKey(relayId, int(d.Port))] = state
+	})
+	if errors.Is(err, errStaleReport) {
+		a.app.Logger().Warn("stale relay report dropped", slog.String("device_id", deviceId), slog.Uint64("version", d.Version))
+		a.syncRequest = false
+		return
+	}
+	if err != nil {
+		a.app.Logger().Error("failed to update reported shadow", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+	}
+
 	record.Set("state", state)
-	if err := a.app.Save(record); err != nil {
+	if err := a.app.SaveWithContext(WithRelayOrigin(context.Background(), RelayOriginDevice), record); err != nil {
 		a.app.Logger().Error("failed to save relay data", slog.String("error", err.Error()))
 		return
 	}
@@ -140,14 +202,10 @@ func (a *Arduino) Relay(cl *mqtt.Client, sub packets.Subscription, pk packets.Pa
 	a.syncRequest = false
 }
 
-func (a *Arduino) FullRelayStateSync(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
-	deviceId := a.getId(pk.TopicName)
-	if deviceId == "" {
-		a.app.Logger().Error("failed to get device id from topic", slog.String("topic", pk.TopicName))
-		return
-	}
+func (a *Arduino) FullRelayStateSync(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
 	var d transporter.RelayStateSync
-	if err := proto.Unmarshal(pk.Payload, &d); err != nil {
+	if err := msg.Decode(&d); err != nil {
 		a.app.Logger().Error("failed to unmarshal relay data", slog.String("error", err.Error()))
 		return
 	}
@@ -197,12 +255,12 @@ func (a *Arduino) FullRelayStateSync(cl *mqtt.Client, sub packets.Subscription,
 			sd.State = transporter.RelayStateType_OFF
 		}
 
-		payload, err := proto.Marshal(sd)
+		topic, payload, err := a.marshal(deviceId, topic, sd)
 		if err != nil {
 			a.app.Logger().Error("failed to marshal relay data", slog.String("error", err.Error()))
 			return
 		}
-		if err := a.mqttServer.Publish(topic, payload, false, 0); err != nil {
+		if err := a.publish(topic, payload, false, 0); err != nil {
 			a.app.Logger().Error("failed to publish relay data", slog.String("error", err.Error()))
 			return
 		}
@@ -214,15 +272,11 @@ func (a *Arduino) FullRelayStateSync(cl *mqtt.Client, sub packets.Subscription,
 	a.syncRequest = false
 }
 
-func (a *Arduino) Secuirty(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
-	deviceId := a.getId(pk.TopicName)
-	if deviceId == "" {
-		a.app.Logger().Error("failed to get device id from topic", slog.String("topic", pk.TopicName))
-		return
-	}
+func (a *Arduino) Secuirty(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
 
 	var d transporter.RfidEnvelope
-	if err := proto.Unmarshal(pk.Payload, &d); err != nil {
+	if err := msg.Decode(&d); err != nil {
 		a.app.Logger().Error("failed to unmarshal security data", slog.String("error", err.Error()))
 		return
 	}
@@ -256,27 +310,89 @@ func (a *Arduino) Secuirty(cl *mqtt.Client, sub packets.Subscription, pk packets
 	}
 }
 
-func (a *Arduino) RegisterTopics() {
-	if err := a.mqttServer.Subscribe("arduino/+/climate", 0, a.Climate); err != nil {
-		a.app.Logger().Error("failed to subscribe to climate topic", slog.String("error", err.Error()))
-		return
-	}
-	if err := a.mqttServer.Subscribe("arduino/+/ldr", 0, a.LDR); err != nil {
-		a.app.Logger().Error("failed to subscribe to LDR topic", slog.String("error", err.Error()))
-		return
-	}
-	if err := a.mqttServer.Subscribe("arduino/+/relay", 0, a.Relay); err != nil {
-		a.app.Logger().Error("failed to subscribe to relay topic", slog.String("error", err.Error()))
-		return
-	}
-	if err := a.mqttServer.Subscribe("arduino/+/relay/full", 0, a.FullRelayStateSync); err != nil {
-		a.app.Logger().Error("failed to subscribe to full relay state sync topic", slog.String("error", err.Error()))
-		return
-	}
-	if err := a.mqttServer.Subscribe("arduino/+/rfid", 0, a.Secuirty); err != nil {
-		a.app.Logger().Error("failed to subscribe to security topic", slog.String("error", err.Error()))
-		return
+func (*Arduino) Name() string {
+	return "arduino"
+}
+
+func (a *Arduino) Routes() []registry.Route {
+	routes := []registry.Route{
+		{Filter: "arduino/+/climate", Name: "climate", Handler: a.Climate},
+		{Filter: "arduino/+/ldr", Name: "ldr", Handler: a.LDR},
+		{Filter: "arduino/+/relay", Name: "relay", Handler: a.Relay},
+		{Filter: "arduino/+/relay/full", Name: "relay_full", Handler: a.FullRelayStateSync},
+		{Filter: "arduino/+/rfid", Name: "rfid", Handler: a.Secuirty},
+		{Filter: "arduino/+/ota/status", Name: "ota_status", Handler: a.OtaStatus},
+		{Filter: "arduino/+/health", Name: "health", Handler: a.Health},
+		{Filter: "arduino/+/factory_reset/ack", Name: "factory_reset_ack", Handler: a.FactoryResetAck},
+		{Filter: "arduino/+/command/result", Name: "command_result", Handler: a.CommandResult},
+		{Filter: "arduino/+/capabilities", Name: "capabilities", Handler: a.Capabilities},
+		{Filter: "arduino/+/shadow/reported", Name: "shadow_reported", Handler: a.ShadowReport},
+		{Filter: "arduino/+/reading", Name: "reading", Handler: a.Reading},
+	}
+
+	// every topic is also available with the JSON suffix
+	for _, route := range routes[:len(routes):len(routes)] {
+		route.Filter += jsonSuffix
+		routes = append(routes, route)
+	}
+
+	return routes
+}
+
+func (a *Arduino) RegisterHooks() {
+	// bound first, the config hooks do not always continue the chain
+	shadowCollections := []string{
+		collections.UserPortLablesCollectionName,
+		collections.ClimateConfigCollectionName,
+		collections.LDRConfigCollectionName,
+		collections.MotionConfigCollectionName,
 	}
+	a.app.OnRecordAfterCreateSuccess(shadowCollections...).BindFunc(a.shadowHook)
+	a.app.OnRecordAfterUpdateSuccess(shadowCollections...).BindFunc(a.shadowHook)
+	a.app.OnRecordAfterDeleteSuccess(shadowCollections...).BindFunc(a.shadowHook)
+
+	legacySensorCollections := shadowCollections[1:]
+	a.app.OnRecordAfterCreateSuccess(legacySensorCollections...).BindFunc(a.legacySensorHook)
+	a.app.OnRecordAfterUpdateSuccess(legacySensorCollections...).BindFunc(a.legacySensorHook)
+	a.app.OnRecordAfterDeleteSuccess(legacySensorCollections...).BindFunc(a.legacySensorRemoveHook)
+
+	a.app.OnRecordAfterCreateSuccess(
+		collections.UserPortLablesCollectionName,
+	).BindFunc(a.relayStateHook)
+	a.app.OnRecordAfterUpdateSuccess(
+		collections.UserPortLablesCollectionName,
+	).BindFunc(a.relayStateHook)
+	a.app.OnRecordAfterDeleteSuccess(
+		collections.UserPortLablesCollectionName,
+	).BindFunc(a.relayStateRemoveHook)
+	a.app.OnRecordAfterCreateSuccess(
+		collections.ReadingsCollectionName,
+	).BindFunc(a.readingStateHook)
+	a.app.OnRecordAfterDeleteSuccess(
+		collections.SensorsCollectionName,
+	).BindFunc(a.sensorStateRemoveHook)
+	a.app.OnRecordAfterUpdateSuccess(
+		collections.DevicesCollectionName,
+	).BindFunc(a.deviceStateHook)
+	a.app.OnRecordAfterDeleteSuccess(
+		collections.DevicesCollectionName,
+	).BindFunc(a.deviceStateHook)
+
+	a.app.OnRecordCreateExecute(
+		collections.SensorsCollectionName,
+	).BindFunc(a.sensorValidateHook)
+	a.app.OnRecordUpdateExecute(
+		collections.SensorsCollectionName,
+	).BindFunc(a.sensorValidateHook)
+	a.app.OnRecordAfterCreateSuccess(
+		collections.SensorsCollectionName,
+	).BindFunc(a.sensorConfigHook)
+	a.app.OnRecordAfterUpdateSuccess(
+		collections.SensorsCollectionName,
+	).BindFunc(a.sensorConfigHook)
+	a.app.OnRecordAfterDeleteSuccess(
+		collections.SensorsCollectionName,
+	).BindFunc(a.sensorRemoveHook)
 
 	a.app.OnRecordAfterCreateSuccess(
 		collections.SecurityCollectionName,
@@ -285,12 +401,37 @@ func (a *Arduino) RegisterTopics() {
 		collections.SecurityCollectionName,
 	).BindFunc(a.securityRevoke)
 
+	a.app.OnRecordAfterCreateSuccess(
+		collections.DevicesCollectionName,
+	).BindFunc(a.deviceCacheHook)
+	a.app.OnRecordAfterUpdateSuccess(
+		collections.DevicesCollectionName,
+	).BindFunc(a.deviceCacheHook)
+	a.app.OnRecordAfterDeleteSuccess(
+		collections.DevicesCollectionName,
+	).BindFunc(a.deviceCacheHook)
+
+	a.app.OnRecordCreateExecute(
+		collections.ClimateConfigCollectionName,
+		collections.LDRConfigCollectionName,
+		collections.MotionConfigCollectionName,
+	).BindFunc(a.capabilityHook)
+	a.app.OnRecordUpdateExecute(
+		collections.ClimateConfigCollectionName,
+		collections.LDRConfigCollectionName,
+		collections.MotionConfigCollectionName,
+	).BindFunc(a.capabilityHook)
+
 	a.app.OnRecordAfterCreateSuccess(
 		collections.ClimateConfigCollectionName,
 		collections.LDRConfigCollectionName,
 		collections.MotionConfigCollectionName,
 	).BindFunc(a.configHook)
 
+	a.app.OnRecordUpdateExecute(
+		collections.UserPortLablesCollectionName,
+	).BindFunc(a.relayHistoryHook)
+
 	a.app.OnRecordUpdateExecute(
 		collections.UserPortLablesCollectionName,
 	).BindFunc(a.relaySwitchHook)
@@ -301,9 +442,39 @@ func (a *Arduino) RegisterTopics() {
 		collections.MotionConfigCollectionName,
 	).BindFunc(a.configResetHook)
 
+	a.app.OnRecordDeleteRequest(
+		collections.DevicesCollectionName,
+	).BindFunc(a.decommissionHook)
 	a.app.OnRecordDeleteExecute(
 		collections.DevicesCollectionName,
 	).BindFunc(a.factoryResetHook)
+
+	a.app.OnRecordCreateExecute(
+		collections.FirmwareCollectionName,
+	).BindFunc(a.firmwareChecksumHook)
+	a.app.OnRecordUpdateExecute(
+		collections.FirmwareCollectionName,
+	).BindFunc(a.firmwareChecksumHook)
+
+	a.app.OnRecordCreate(
+		collections.OtaRolloutsCollectionName,
+	).BindFunc(a.rolloutDefaultsHook)
+	a.app.OnRecordAfterCreateSuccess(
+		collections.OtaRolloutsCollectionName,
+	).BindFunc(a.rolloutHook)
+	a.app.OnRecordAfterUpdateSuccess(
+		collections.OtaRolloutsCollectionName,
+	).BindFunc(a.rolloutHook)
+
+	a.app.OnRecordCreateExecute(
+		collections.DeviceCommandsCollectionName,
+	).BindFunc(a.commandValidateHook)
+	a.app.OnRecordAfterCreateSuccess(
+		collections.DeviceCommandsCollectionName,
+	).BindFunc(a.commandHook)
+
+	a.app.Cron().MustAdd("offline_devices", "* * * * *", a.markOffline)
+	a.app.Cron().MustAdd("expired_commands", "* * * * *", a.expireCommands)
 }
 
 func (a *Arduino) securityRegister(e *core.RecordEvent) error {
@@ -328,12 +499,12 @@ func (a *Arduino) securityRegister(e *core.RecordEvent) error {
 		},
 	}
 
-	payload, err := proto.Marshal(&d)
+	topic, payload, err := a.marshal(deviceId, topic, &d)
 	if err != nil {
 		a.app.Logger().Error("failed to marshal security data", slog.String("error", err.Error()))
 		return nil
 	}
-	if err := a.mqttServer.Publish(topic, payload, false, 0); err != nil {
+	if err := a.publish(topic, payload, false, 0); err != nil {
 		a.app.Logger().Error("failed to publish security data", slog.String("error", err.Error()))
 		return nil
 	}
@@ -377,12 +548,12 @@ func (a *Arduino) securityRevoke(e *core.RecordEvent) error {
 		},
 	}
 
-	payload, err := proto.Marshal(&d)
+	topic, payload, err := a.marshal(deviceId, topic, &d)
 	if err != nil {
 		a.app.Logger().Error("failed to marshal security data", slog.String("error", err.Error()))
 		return nil
 	}
-	if err := a.mqttServer.Publish(topic, payload, false, 0); err != nil {
+	if err := a.publish(topic, payload, false, 0); err != nil {
 		a.app.Logger().Error("failed to publish security data", slog.String("error", err.Error()))
 		return nil
 	}
@@ -403,6 +574,11 @@ func (a *Arduino) configHook(e *core.RecordEvent) error {
 		return nil
 	}
 
+	// devices on the shadow get the config with the delta
+	if a.deviceUsesShadow(deviceId) {
+		return nil
+	}
+
 	topic := fmt.Sprintf("arduino/%s/config", deviceId)
 
 	configPayload := &transporter.ConfigTopic{}
@@ -446,12 +622,12 @@ func (a *Arduino) configHook(e *core.RecordEvent) error {
 		break
 	}
 
-	payload, err := proto.Marshal(configPayload)
+	topic, payload, err := a.marshal(deviceId, topic, configPayload)
 	if err != nil {
 		a.app.Logger().Error("failed to marshal config data", slog.String("error", err.Error()))
 		return nil
 	}
-	if err := a.mqttServer.Publish(topic, payload, false, 0); err != nil {
+	if err := a.publish(topic, payload, false, 0); err != nil {
 		a.app.Logger().Error("failed to publish config data", slog.String("error", err.Error()))
 		return nil
 	}
@@ -472,6 +648,10 @@ func (a *Arduino) configResetHook(e *core.RecordEvent) error {
 		return nil
 	}
 
+	if a.deviceUsesShadow(deviceId) {
+		return e.Next()
+	}
+
 	sensorID := record.GetInt("sensor_id")
 	topic := fmt.Sprintf("arduino/%s/config/remove", deviceId)
 
@@ -505,12 +685,12 @@ func (a *Arduino) configResetHook(e *core.RecordEvent) error {
 		}
 	}
 
-	payload, err := proto.Marshal(c)
+	topic, payload, err := a.marshal(deviceId, topic, c)
 	if err != nil {
 		a.app.Logger().Error("failed to marshal config data", slog.String("error", err.Error()))
 		return nil
 	}
-	if err := a.mqttServer.Publish(topic, payload, false, 0); err != nil {
+	if err := a.publish(topic, payload, false, 0); err != nil {
 		a.app.Logger().Error("failed to publish config data", slog.String("error", err.Error()))
 		return nil
 	}
@@ -519,32 +699,6 @@ func (a *Arduino) configResetHook(e *core.RecordEvent) error {
 	return e.Next()
 }
 
-func (a *Arduino) factoryResetHook(e *core.RecordEvent) error {
-	record := e.Record
-	if record == nil {
-		a.app.Logger().Error("failed to get record from event")
-		return nil
-	}
-	deviceId := record.Id
-	if deviceId == "" {
-		a.app.Logger().Error("failed to get device id from record", slog.String("record_id", record.Id))
-		return nil
-	}
-
-	topic := fmt.Sprintf("arduino/%s/factory_reset", deviceId)
-
-	// random bytes
-	d := []byte{0x00}
-
-	if err := a.mqttServer.Publish(topic, d, false, 0); err != nil {
-		a.app.Logger().Error("failed to publish factory reset data", slog.String("error", err.Error()))
-		return nil
-	}
-
-	a.app.Logger().Info("published factory reset data", slog.String("topic", topic), slog.String("device_id", deviceId))
-	return e.Next()
-}
-
 func (a *Arduino) relaySwitchHook(e *core.RecordEvent) error {
 	if a.syncRequest {
 		return e.Next()
@@ -561,6 +715,10 @@ func (a *Arduino) relaySwitchHook(e *core.RecordEvent) error {
 		return nil
 	}
 
+	if a.deviceUsesShadow(deviceId) {
+		return e.Next()
+	}
+
 	var t transporter.RelayType
 	relayId := record.GetString("relay")
 	if relayId == "relaylowduty001" {
@@ -581,14 +739,14 @@ func (a *Arduino) relaySwitchHook(e *core.RecordEvent) error {
 	d.Type = t
 	d.State = s
 	d.Port = uint32(record.GetInt("port"))
-	payload, err := proto.Marshal(&d)
+	topic := fmt.Sprintf("arduino/%s/relay", deviceId)
+	topic, payload, err := a.marshal(deviceId, topic, &d)
 	if err != nil {
 		a.app.Logger().Error("failed to marshal relay data", slog.String("error", err.Error()))
 		return nil
 	}
 
-	topic := fmt.Sprintf("arduino/%s/relay", deviceId)
-	if err := a.mqttServer.Publish(topic, payload, false, 0); err != nil {
+	if err := a.publish(topic, payload, false, 0); err != nil {
 		a.app.Logger().Error("failed to publish relay data", slog.String("error", err.Error()))
 		return nil
 	}
@@ -596,9 +754,10 @@ func (a *Arduino) relaySwitchHook(e *core.RecordEvent) error {
 	return e.Next()
 }
 
-func (*Arduino) getId(topic string) string {
+// DeviceId returns the device id of an arduino/{device_id}/... topic.
+func (*Arduino) DeviceId(topic string) string {
 	topicParts := strings.Split(topic, "/")
-	if len(topicParts) < 2 {
+	if len(topicParts) < 3 || topicParts[0] != "arduino" {
 		return ""
 	}
 
@@ -0,0 +1,163 @@
+package topics
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	// protocolLegacy is spoken by boards that never announce capabilities.
+	protocolLegacy = 1
+	// ProtocolVersion is the newest protocol version of the server.
+	ProtocolVersion = 2
+)
+
+const (
+	featureShadow   = "shadow"
+	featureOta      = "ota"
+	featureCommands = "commands"
+	featureSensors  = "sensors"
+)
+
+// capabilities are stored on the device as announced, relay modules by
+// their relay record id.
+type capabilities struct {
+	Sensors  []string `json:"sensors"`
+	Relays   []string `json:"relays"`
+	Features []string `json:"features"`
+}
+
+// deviceCapabilities returns the capabilities of a device, or false when the
+// device never announced them. Nothing is known about such boards, so they
+// are sent everything like before.
+func deviceCapabilities(device *core.Record) (capabilities, bool) {
+	var c capabilities
+	if device.GetInt("protocol_version") == 0 {
+		return c, false
+	}
+
+	_ = device.UnmarshalJSONField("capabilities", &c)
+	return c, true
+}
+
+// protocolVersion is the protocol version both the device and the server
+// speak.
+func protocolVersion(device *core.Record) int {
+	return min(max(device.GetInt("protocol_version"), protocolLegacy), ProtocolVersion)
+}
+
+func supportsFeature(device *core.Record, feature string) bool {
+	c, ok := deviceCapabilities(device)
+	return !ok || slices.Contains(c.Features, feature)
+}
+
+func supportsSensor(device *core.Record, kind string) bool {
+	c, ok := deviceCapabilities(device)
+	return !ok || slices.Contains(c.Sensors, kind)
+}
+
+func supportsRelay(device *core.Record, relayId string) bool {
+	c, ok := deviceCapabilities(device)
+	return !ok || slices.Contains(c.Relays, relayId)
+}
+
+// usesShadow reports whether config and relay changes are sent to the
+// device as shadow deltas instead of the per message topics.
+func usesShadow(device *core.Record) bool {
+	_, ok := deviceCapabilities(device)
+	return ok && protocolVersion(device) >= 2 && supportsFeature(device, featureShadow)
+}
+
+// deviceUsesShadow looks up a device to pick the messages it is sent.
+// Devices that cannot be found get the per message topics.
+func (a *Arduino) deviceUsesShadow(deviceId string) bool {
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	return err == nil && usesShadow(device)
+}
+
+func (a *Arduino) Capabilities(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
+
+	var d transporter.Capabilities
+	if err := msg.Decode(&d); err != nil {
+		a.app.Logger().Error("failed to unmarshal capabilities", slog.String("error", err.Error()))
+		a.reject(deviceId, 0, pk, fmt.Errorf("malformed payload: %w", err))
+		return
+	}
+
+	if d.ProtocolVersion < protocolLegacy {
+		a.reject(deviceId, 0, pk, fmt.Errorf("protocol version %d is not supported", d.ProtocolVersion))
+		return
+	}
+
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	if err != nil {
+		a.reject(deviceId, 0, pk, errUnknownDevice)
+		return
+	}
+
+	c := capabilities{
+		Sensors:  d.Sensors,
+		Relays:   make([]string, 0, len(d.Relays)),
+		Features: d.Features,
+	}
+	for _, relay := range d.Relays {
+		c.Relays = append(c.Relays, relayIdOf(relay))
+	}
+	if c.Sensors == nil {
+		c.Sensors = []string{}
+	}
+	if c.Features == nil {
+		c.Features = []string{}
+	}
+
+	device.Set("protocol_version", int(d.ProtocolVersion))
+	device.Set("capabilities", c)
+	if err := a.app.Save(device); err != nil {
+		a.app.Logger().Error("failed to save capabilities", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	a.app.Logger().Info("capabilities announced", slog.String("device_id", deviceId), slog.Int("protocol_version", protocolVersion(device)))
+
+	if usesShadow(device) {
+		a.PushShadowDelta(deviceId)
+	}
+}
+
+// capabilityHook refuses sensor configs the device cannot handle.
+func (a *Arduino) capabilityHook(e *core.RecordEvent) error {
+	device, err := e.App.FindRecordById(collections.DevicesCollectionName, e.Record.GetString("device"))
+	if err != nil {
+		return e.Next()
+	}
+
+	var kind string
+	switch e.Record.Collection().Name {
+	case collections.ClimateConfigCollectionName:
+		kind = sensorClimate
+	case collections.LDRConfigCollectionName:
+		kind = sensorLDR
+	case collections.MotionConfigCollectionName:
+		kind = sensorMotion
+		relayId := relayHeavyDuty
+		if e.Record.GetInt("relay_type") == 1 {
+			relayId = relayLowDuty
+		}
+		if !supportsRelay(device, relayId) {
+			return fmt.Errorf("device does not support the %s relay module", relayId)
+		}
+	}
+
+	if !supportsSensor(device, kind) {
+		return fmt.Errorf("device does not support %s sensors", kind)
+	}
+
+	return e.Next()
+}
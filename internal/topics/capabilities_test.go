@@ -0,0 +1,187 @@
+package topics
+
+import (
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/pocketbase/core"
+	// the system migrations, which pocketbase.New registers
+	_ "github.com/pocketbase/pocketbase/migrations"
+)
+
+// newDevice returns a device record speaking the protocol version with the
+// capabilities, which it never announced when the version is 0.
+func newDevice(protocol int, c capabilities) *core.Record {
+	device := core.NewRecord((&collections.Devices{}).Schema())
+	device.Id = "device1"
+	device.Set("protocol_version", protocol)
+	if protocol > 0 {
+		device.Set("capabilities", c)
+	}
+	return device
+}
+
+func recordEvent(app core.App, record *core.Record) *core.RecordEvent {
+	e := new(core.RecordEvent)
+	e.App = app
+	e.Record = record
+	return e
+}
+
+func TestSupports(t *testing.T) {
+	announced := capabilities{
+		Sensors:  []string{sensorClimate},
+		Relays:   []string{relayLowDuty},
+		Features: []string{featureOta},
+	}
+
+	tests := []struct {
+		name    string
+		device  *core.Record
+		feature bool
+		sensor  bool
+		relay   bool
+		// whether what was not announced is supported
+		others bool
+	}{
+		// boards that never announced anything are sent everything
+		{name: "legacy", device: newDevice(0, capabilities{}), feature: true, sensor: true, relay: true, others: true},
+		{name: "announced nothing", device: newDevice(2, capabilities{}), feature: false, sensor: false, relay: false},
+		{name: "announced", device: newDevice(2, announced), feature: true, sensor: true, relay: true},
+		{name: "protocol 1", device: newDevice(1, announced), feature: true, sensor: true, relay: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := supportsFeature(tt.device, featureOta); got != tt.feature {
+				t.Errorf("supportsFeature(ota) = %v, want %v", got, tt.feature)
+			}
+			if got := supportsSensor(tt.device, sensorClimate); got != tt.sensor {
+				t.Errorf("supportsSensor(climate) = %v, want %v", got, tt.sensor)
+			}
+			if got := supportsRelay(tt.device, relayLowDuty); got != tt.relay {
+				t.Errorf("supportsRelay(low duty) = %v, want %v", got, tt.relay)
+			}
+
+			if got := supportsFeature(tt.device, featureCommands); got != tt.others {
+				t.Errorf("supportsFeature(commands) = %v, want %v", got, tt.others)
+			}
+			if got := supportsSensor(tt.device, sensorLDR); got != tt.others {
+				t.Errorf("supportsSensor(ldr) = %v, want %v", got, tt.others)
+			}
+			if got := supportsRelay(tt.device, relayHeavyDuty); got != tt.others {
+				t.Errorf("supportsRelay(heavy duty) = %v, want %v", got, tt.others)
+			}
+		})
+	}
+}
+
+func TestUsesShadow(t *testing.T) {
+	shadow := capabilities{Features: []string{featureShadow}}
+
+	tests := []struct {
+		name   string
+		device *core.Record
+		want   bool
+	}{
+		{name: "legacy", device: newDevice(0, capabilities{}), want: false},
+		{name: "protocol 1 with shadow", device: newDevice(1, shadow), want: false},
+		{name: "protocol 2 without shadow", device: newDevice(2, capabilities{Features: []string{featureOta}}), want: false},
+		{name: "protocol 2 with shadow", device: newDevice(2, shadow), want: true},
+		// newer boards speak the newest protocol of the server
+		{name: "protocol 3 with shadow", device: newDevice(3, shadow), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := usesShadow(tt.device); got != tt.want {
+				t.Errorf("usesShadow = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtocolVersion(t *testing.T) {
+	for protocol, want := range map[int]int{0: 1, 1: 1, 2: 2, 3: ProtocolVersion} {
+		if got := protocolVersion(newDevice(protocol, capabilities{})); got != want {
+			t.Errorf("protocolVersion(%d) = %d, want %d", protocol, got, want)
+		}
+	}
+}
+
+func TestCapabilityHook(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	// the relations of the devices are not needed
+	if err := app.SaveNoValidate((&collections.Devices{}).Schema()); err != nil {
+		t.Fatal(err)
+	}
+
+	climate := core.NewRecord((&collections.ClimateConfig{}).Schema())
+	ldr := core.NewRecord((&collections.LDRConfig{}).Schema())
+	motion := core.NewRecord((&collections.MotionConfig{}).Schema())
+	motion.Set("relay_type", 1)
+
+	tests := []struct {
+		name     string
+		protocol int
+		c        capabilities
+		// configs the device accepts, the others are refused
+		accepts []*core.Record
+	}{
+		{name: "legacy", protocol: 0, accepts: []*core.Record{climate, ldr, motion}},
+		{
+			name:     "protocol 1",
+			protocol: 1,
+			c:        capabilities{Sensors: []string{sensorClimate}},
+			accepts:  []*core.Record{climate},
+		},
+		{
+			name:     "motion without its relay module",
+			protocol: 2,
+			c:        capabilities{Sensors: []string{sensorMotion}, Relays: []string{relayHeavyDuty}},
+		},
+		{
+			name:     "protocol 2",
+			protocol: 2,
+			c:        capabilities{Sensors: []string{sensorLDR, sensorMotion}, Relays: []string{relayLowDuty}},
+			accepts:  []*core.Record{ldr, motion},
+		},
+	}
+
+	a := &Arduino{app: app}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newDevice(tt.protocol, tt.c)
+			device.Set("user", "user1")
+			device.Set("device_name", "hall")
+			device.Set("device_status", "online")
+			if err := app.SaveNoValidate(device); err != nil {
+				t.Fatal(err)
+			}
+			defer app.Delete(device)
+
+			for _, config := range []*core.Record{climate, ldr, motion} {
+				config.Set("device", device.Id)
+				err := a.capabilityHook(recordEvent(app, config))
+				accepted := false
+				for _, r := range tt.accepts {
+					accepted = accepted || r == config
+				}
+				if (err == nil) != accepted {
+					t.Errorf("%s config: error = %v, want accepted %v", config.Collection().Name, err, accepted)
+				}
+			}
+		})
+	}
+
+	// configs of unknown devices are left to the relation check
+	climate.Set("device", "unknown")
+	if err := a.capabilityHook(recordEvent(app, climate)); err != nil {
+		t.Errorf("config of an unknown device: error = %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package topics
+
+import (
+	"strings"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/pocketbase/core"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// jsonSuffix selects JSON for a single message regardless of the payload
+// format of the device, e.g. arduino/{id}/climate/json.
+const jsonSuffix = "/json"
+
+var (
+	jsonUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+	jsonMarshal   = protojson.MarshalOptions{UseProtoNames: true}
+)
+
+// payloadFormat returns the payload format of a device. It is cached as it
+// is needed for every message, and dropped again by deviceCacheHook.
+func (a *Arduino) payloadFormat(deviceId string) string {
+	if format, ok := a.formats.Load(deviceId); ok {
+		return format.(string)
+	}
+
+	format := collections.PayloadProtobuf
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	if err != nil {
+		return format
+	}
+	if device.GetString("payload_format") == collections.PayloadJSON {
+		format = collections.PayloadJSON
+	}
+
+	a.formats.Store(deviceId, format)
+	return format
+}
+
+// setJSONSuffix remembers whether the last message of a device had the JSON
+// suffix, so it is answered the same way.
+func (a *Arduino) setJSONSuffix(deviceId string, suffix bool) {
+	if previous, ok := a.suffixes.Load(deviceId); !ok || previous.(bool) != suffix {
+		a.suffixes.Store(deviceId, suffix)
+	}
+}
+
+func (a *Arduino) usesJSONSuffix(deviceId string) bool {
+	suffix, ok := a.suffixes.Load(deviceId)
+	return ok && suffix.(bool)
+}
+
+// Decode decodes the payload of a device message as JSON when the topic has
+// the JSON suffix or the device is set to JSON, and as protobuf otherwise.
+// Both decode into the same message, so the validation is the same.
+func (a *Arduino) Decode(pk packets.Packet, m proto.Message) error {
+	deviceId := a.DeviceId(pk.TopicName)
+	suffix := strings.HasSuffix(pk.TopicName, jsonSuffix)
+	// the server publishes on some device topics itself
+	if pk.Origin != mqtt.InlineClientId {
+		a.setJSONSuffix(deviceId, suffix)
+	}
+
+	if suffix || a.payloadFormat(deviceId) == collections.PayloadJSON {
+		return jsonUnmarshal.Unmarshal(pk.Payload, m)
+	}
+
+	return proto.Unmarshal(pk.Payload, m)
+}
+
+// marshal encodes a message sent to a device on the topic. Devices sending
+// with the JSON suffix get it as JSON on the topic with the suffix, devices
+// set to JSON as JSON on the topic and the others as protobuf. It returns
+// the topic to publish on.
+func (a *Arduino) marshal(deviceId string, topic string, m proto.Message) (string, []byte, error) {
+	if a.usesJSONSuffix(deviceId) {
+		payload, err := jsonMarshal.Marshal(m)
+		return topic + jsonSuffix, payload, err
+	}
+	if a.payloadFormat(deviceId) == collections.PayloadJSON {
+		payload, err := jsonMarshal.Marshal(m)
+		return topic, payload, err
+	}
+
+	payload, err := proto.Marshal(m)
+	return topic, payload, err
+}
+
+// deviceCacheHook drops what is cached about a device when it is created,
+// changed or deleted.
+func (a *Arduino) deviceCacheHook(e *core.RecordEvent) error {
+	a.formats.Delete(e.Record.Id)
+	a.devices.Delete(e.Record.Id)
+	return e.Next()
+}
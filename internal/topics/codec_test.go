@@ -0,0 +1,154 @@
+package topics
+
+import (
+	"strings"
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/pocketbase/core"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCodecFollowsJSONSuffix(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	devices := (&collections.Devices{}).Schema()
+	if err := app.SaveNoValidate(devices); err != nil {
+		t.Fatal(err)
+	}
+	for id, format := range map[string]string{"device1": collections.PayloadProtobuf, "device2": collections.PayloadJSON} {
+		device := core.NewRecord(devices)
+		device.Id = id
+		device.Set("payload_format", format)
+		if err := app.SaveNoValidate(device); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := &Arduino{app: app}
+	message := &transporter.RelayState{Port: 2, State: transporter.RelayStateType_ON}
+
+	send := func(deviceId string) (string, *transporter.RelayState, bool) {
+		t.Helper()
+
+		topic, payload, err := a.marshal(deviceId, "arduino/"+deviceId+"/relay", message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got transporter.RelayState
+		isJSON := jsonUnmarshal.Unmarshal(payload, &got) == nil
+		if !isJSON {
+			if err := proto.Unmarshal(payload, &got); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return topic, &got, isJSON
+	}
+
+	receive := func(topic string, origin string) {
+		t.Helper()
+
+		sent := &transporter.ClimateData{Id: 1, Temperature: 21.5}
+		var payload []byte
+		var err error
+		if strings.HasSuffix(topic, jsonSuffix) || a.payloadFormat(a.DeviceId(topic)) == collections.PayloadJSON {
+			payload, err = jsonMarshal.Marshal(sent)
+		} else {
+			payload, err = proto.Marshal(sent)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var d transporter.ClimateData
+		if err := a.Decode(packets.Packet{TopicName: topic, Origin: origin, Payload: payload}, &d); err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(&d, sent) {
+			t.Fatalf("decoded %v, want %v", &d, sent)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		deviceId string
+		// receive is the topic of a message of the device before, if any
+		receive string
+		origin  string
+		topic   string
+		json    bool
+	}{
+		{name: "protobuf", deviceId: "device1", topic: "arduino/device1/relay"},
+		{name: "payload format", deviceId: "device2", topic: "arduino/device2/relay", json: true},
+		{
+			name:     "suffix",
+			deviceId: "device1",
+			receive:  "arduino/device1/climate/json",
+			origin:   "device1",
+			topic:    "arduino/device1/relay/json",
+			json:     true,
+		},
+		// the server publishing on device topics does not change it
+		{
+			name:     "suffix published by the server",
+			deviceId: "device1",
+			receive:  "arduino/device1/rfid",
+			origin:   mqtt.InlineClientId,
+			topic:    "arduino/device1/relay/json",
+			json:     true,
+		},
+		{
+			name:     "suffix dropped",
+			deviceId: "device1",
+			receive:  "arduino/device1/climate",
+			origin:   "device1",
+			topic:    "arduino/device1/relay",
+		},
+		{
+			name:     "suffix with payload format",
+			deviceId: "device2",
+			receive:  "arduino/device2/climate/json",
+			origin:   "device2",
+			topic:    "arduino/device2/relay/json",
+			json:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.receive != "" {
+				receive(tt.receive, tt.origin)
+			}
+
+			topic, got, isJSON := send(tt.deviceId)
+			if topic != tt.topic || isJSON != tt.json {
+				t.Errorf("sent on %s as JSON %v, want %s as JSON %v", topic, isJSON, tt.topic, tt.json)
+			}
+			if !proto.Equal(got, message) {
+				t.Errorf("sent %v, want %v", got, message)
+			}
+		})
+	}
+}
+
+func TestShadowHookIgnoresOtherClients(t *testing.T) {
+	a := &Arduino{}
+	h := NewShadowHook(a)
+
+	cl := &mqtt.Client{ID: "dashboard"}
+	cl.Properties.Username = []byte("server")
+	h.OnSubscribed(cl, packets.Packet{Filters: packets.Subscriptions{
+		{Filter: "arduino/device1/shadow/delta/json"},
+	}}, []byte{0})
+
+	if a.usesJSONSuffix("device1") {
+		t.Fatal("subscription of another client changed the topics of the device")
+	}
+}
@@ -0,0 +1,201 @@
+package topics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// commandTimeout is how long a device has to finish a command.
+const commandTimeout = 2 * time.Minute
+
+// maxIdentifySeconds caps how long a device blinks or beeps.
+const maxIdentifySeconds = 300
+
+var logLevels = map[string]transporter.LogLevel{
+	"error": transporter.LogLevel_LOG_ERROR,
+	"warn":  transporter.LogLevel_LOG_WARN,
+	"info":  transporter.LogLevel_LOG_INFO,
+	"debug": transporter.LogLevel_LOG_DEBUG,
+}
+
+var commandStates = map[transporter.CommandState]string{
+	transporter.CommandState_COMMAND_ACKNOWLEDGED: collections.CommandAcknowledged,
+	transporter.CommandState_COMMAND_SUCCEEDED:    collections.CommandSucceeded,
+	transporter.CommandState_COMMAND_FAILED:       collections.CommandFailed,
+}
+
+// commandFinished are the command states a command does not leave anymore.
+var commandFinished = []string{collections.CommandSucceeded, collections.CommandFailed, collections.CommandTimedOut}
+
+type commandParams struct {
+	Seconds uint32 `json:"seconds"`
+	Beep    bool   `json:"beep"`
+	Level   string `json:"level"`
+}
+
+// deviceCommand builds the protobuf command of a device_commands record.
+func deviceCommand(record *core.Record) (*transporter.DeviceCommand, error) {
+	var params commandParams
+	if raw := record.GetString("params"); raw != "" && raw != "null" {
+		if err := json.Unmarshal([]byte(raw), &params); err != nil {
+			return nil, fmt.Errorf("invalid params: %w", err)
+		}
+	}
+
+	command := &transporter.DeviceCommand{Id: record.Id}
+	switch record.GetString("command") {
+	case collections.CommandReboot:
+		command.Command = &transporter.DeviceCommand_Reboot{Reboot: &transporter.Reboot{}}
+	case collections.CommandIdentify:
+		if params.Seconds == 0 {
+			params.Seconds = 10
+		}
+		if params.Seconds > maxIdentifySeconds {
+			return nil, fmt.Errorf("seconds must be at most %d", maxIdentifySeconds)
+		}
+		command.Command = &transporter.DeviceCommand_Identify{
+			Identify: &transporter.Identify{Seconds: params.Seconds, Beep: params.Beep},
+		}
+	case collections.CommandHealthReport:
+		command.Command = &transporter.DeviceCommand_HealthReport{HealthReport: &transporter.HealthReportRequest{}}
+	case collections.CommandConfigDump:
+		command.Command = &transporter.DeviceCommand_ConfigDump{ConfigDump: &transporter.ConfigDumpRequest{}}
+	case collections.CommandSetLogLevel:
+		level, ok := logLevels[params.Level]
+		if !ok {
+			return nil, fmt.Errorf("unknown log level %q", params.Level)
+		}
+		command.Command = &transporter.DeviceCommand_SetLogLevel{SetLogLevel: &transporter.SetLogLevel{Level: level}}
+	default:
+		return nil, fmt.Errorf("unknown command %q", record.GetString("command"))
+	}
+
+	return command, nil
+}
+
+func (a *Arduino) commandValidateHook(e *core.RecordEvent) error {
+	if _, err := deviceCommand(e.Record); err != nil {
+		return err
+	}
+
+	device, err := e.App.FindRecordById(collections.DevicesCollectionName, e.Record.GetString("device"))
+	if err == nil && !supportsFeature(device, featureCommands) {
+		return errors.New("device does not support commands")
+	}
+
+	return e.Next()
+}
+
+func (a *Arduino) commandHook(e *core.RecordEvent) error {
+	record := e.Record
+	deviceId := record.GetString("device")
+
+	command, err := deviceCommand(record)
+	if err != nil {
+		a.app.Logger().Error("failed to build device command", slog.String("error", err.Error()))
+		return e.Next()
+	}
+
+	topic := fmt.Sprintf("arduino/%s/command", deviceId)
+	topic, payload, err := a.marshal(deviceId, topic, command)
+	if err != nil {
+		a.app.Logger().Error("failed to marshal device command", slog.String("error", err.Error()))
+		return e.Next()
+	}
+
+	if err := a.publish(topic, payload, false, 1); err != nil {
+		a.app.Logger().Error("failed to publish device command", slog.String("error", err.Error()))
+		return e.Next()
+	}
+
+	a.app.Logger().Info("published device command", slog.String("topic", topic), slog.String("command", record.GetString("command")), slog.String("device_id", deviceId))
+	return e.Next()
+}
+
+func (a *Arduino) CommandResult(msg *registry.Message) {
+	deviceId := msg.DeviceId
+
+	var d transporter.CommandResult
+	if err := msg.Decode(&d); err != nil {
+		a.app.Logger().Error("failed to unmarshal command result", slog.String("error", err.Error()))
+		return
+	}
+
+	record, err := a.app.FindRecordById(collections.DeviceCommandsCollectionName, d.Id)
+	if err != nil || record.GetString("device") != deviceId {
+		a.app.Logger().Error("failed to find device command", slog.String("device_id", deviceId), slog.String("command_id", d.Id))
+		return
+	}
+
+	if slices.Contains(commandFinished, record.GetString("status")) {
+		return
+	}
+
+	status, ok := commandStates[d.State]
+	if !ok {
+		a.app.Logger().Error("unknown command state", slog.String("device_id", deviceId), slog.Int("state", int(d.State)))
+		return
+	}
+
+	var result proto.Message
+	switch r := d.Result.(type) {
+	case *transporter.CommandResult_Health:
+		result = r.Health
+	case *transporter.CommandResult_Config:
+		result = r.Config
+	}
+	if result != nil {
+		raw, err := protojson.Marshal(result)
+		if err != nil {
+			a.app.Logger().Error("failed to marshal command result", slog.String("error", err.Error()))
+			return
+		}
+		record.Set("result", types.JSONRaw(raw))
+	}
+
+	record.Set("status", status)
+	record.Set("error", d.Error)
+	if err := a.app.Save(record); err != nil {
+		a.app.Logger().Error("failed to save command result", slog.String("error", err.Error()))
+	}
+}
+
+// expireCommands times out the commands a device did not finish in time.
+func (a *Arduino) expireCommands() {
+	records, err := a.app.FindRecordsByFilter(
+		collections.DeviceCommandsCollectionName,
+		"(status = {:sent} || status = {:acknowledged}) && timestamp < {:before}",
+		"",
+		0,
+		0,
+		dbx.Params{
+			"sent":         collections.CommandSent,
+			"acknowledged": collections.CommandAcknowledged,
+			"before":       types.NowDateTime().Add(-commandTimeout).String(),
+		},
+	)
+	if err != nil {
+		a.app.Logger().Error("failed to find expired commands", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, record := range records {
+		record.Set("status", collections.CommandTimedOut)
+		if err := a.app.Save(record); err != nil {
+			a.app.Logger().Error("failed to expire command", slog.String("command_id", record.Id), slog.String("error", err.Error()))
+		}
+	}
+}
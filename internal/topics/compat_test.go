@@ -0,0 +1,340 @@
+package topics
+
+import (
+	"slices"
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/pocketbase/core"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// legacyMessage returns an empty message of the type as boards built before
+// a change know it. edit turns the current descriptor into the old one.
+func legacyMessage(t *testing.T, m proto.Message, edit func(d *descriptorpb.DescriptorProto)) *dynamicpb.Message {
+	t.Helper()
+
+	desc := m.ProtoReflect().Descriptor()
+	d := protodesc.ToDescriptorProto(desc)
+	edit(d)
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("legacy/" + desc.ParentFile().Path()),
+		Package:     proto.String("legacy"),
+		Syntax:      proto.String("proto3"),
+		Dependency:  []string{desc.ParentFile().Path()},
+		MessageType: []*descriptorpb.DescriptorProto{d},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dynamicpb.NewMessage(file.Messages().Get(0))
+}
+
+// withoutFields drops the fields added since, with their synthetic oneofs.
+func withoutFields(numbers ...int32) func(d *descriptorpb.DescriptorProto) {
+	return func(d *descriptorpb.DescriptorProto) {
+		d.Field = slices.DeleteFunc(d.Field, func(f *descriptorpb.FieldDescriptorProto) bool {
+			return slices.Contains(numbers, f.GetNumber())
+		})
+	}
+}
+
+func setField(m *dynamicpb.Message, name string, v protoreflect.Value) {
+	m.Set(m.Descriptor().Fields().ByName(protoreflect.Name(name)), v)
+}
+
+func getField(m *dynamicpb.Message, name string) protoreflect.Value {
+	return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(name)))
+}
+
+func TestRelayStateWireCompatibility(t *testing.T) {
+	// version was added with the shadow
+	legacy := func() *dynamicpb.Message {
+		return legacyMessage(t, &transporter.RelayState{}, withoutFields(4))
+	}
+
+	t.Run("old board to server", func(t *testing.T) {
+		old := legacy()
+		setField(old, "type", protoreflect.ValueOfEnum(protoreflect.EnumNumber(transporter.RelayType_LOW_DUTY)))
+		setField(old, "port", protoreflect.ValueOfUint32(3))
+		setField(old, "state", protoreflect.ValueOfEnum(protoreflect.EnumNumber(transporter.RelayStateType_ON)))
+		payload, err := proto.Marshal(old)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got transporter.RelayState
+		if err := proto.Unmarshal(payload, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := &transporter.RelayState{Type: transporter.RelayType_LOW_DUTY, Port: 3, State: transporter.RelayStateType_ON}
+		if !proto.Equal(&got, want) {
+			t.Errorf("decoded %v, want %v", &got, want)
+		}
+	})
+
+	t.Run("server to old board", func(t *testing.T) {
+		payload, err := proto.Marshal(&transporter.RelayState{
+			Type:    transporter.RelayType_HEAVY_DUTY,
+			Port:    2,
+			State:   transporter.RelayStateType_ON,
+			Version: 7,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		old := legacy()
+		if err := proto.Unmarshal(payload, old); err != nil {
+			t.Fatal(err)
+		}
+		if got := getField(old, "type").Enum(); got != protoreflect.EnumNumber(transporter.RelayType_HEAVY_DUTY) {
+			t.Errorf("type = %v", got)
+		}
+		if got := getField(old, "port").Uint(); got != 2 {
+			t.Errorf("port = %d", got)
+		}
+		if got := getField(old, "state").Enum(); got != protoreflect.EnumNumber(transporter.RelayStateType_ON) {
+			t.Errorf("state = %v", got)
+		}
+		// the version is skipped as an unknown field
+		if num, _, n := protowire.ConsumeTag(old.GetUnknown()); n < 0 || num != 4 {
+			t.Errorf("unknown fields %x, want the version", old.GetUnknown())
+		}
+	})
+
+	t.Run("unversioned messages are unchanged", func(t *testing.T) {
+		old := legacy()
+		setField(old, "port", protoreflect.ValueOfUint32(5))
+		want, err := proto.MarshalOptions{Deterministic: true}.Marshal(old)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := proto.MarshalOptions{Deterministic: true}.Marshal(&transporter.RelayState{Port: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("encoded %x, old boards encode %x", got, want)
+		}
+	})
+}
+
+func TestDeviceHealthWireCompatibility(t *testing.T) {
+	// free_heap was a plain field before it became optional
+	legacy := legacyMessage(t, &transporter.DeviceHealth{}, func(d *descriptorpb.DescriptorProto) {
+		for _, f := range d.Field {
+			if f.GetNumber() == 2 {
+				f.Proto3Optional = nil
+				f.OneofIndex = nil
+			}
+		}
+		d.OneofDecl = slices.DeleteFunc(d.OneofDecl, func(o *descriptorpb.OneofDescriptorProto) bool {
+			return o.GetName() == "_free_heap"
+		})
+	})
+
+	tests := []struct {
+		name     string
+		freeHeap uint32
+		want     *uint32
+	}{
+		{name: "reported", freeHeap: 20480, want: proto.Uint32(20480)},
+		// boards that cannot measure it send the default, which is not
+		// encoded
+		{name: "not measured", freeHeap: 0, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := legacy.New().Interface().(*dynamicpb.Message)
+			setField(old, "uptime", protoreflect.ValueOfUint32(60))
+			setField(old, "free_heap", protoreflect.ValueOfUint32(tt.freeHeap))
+			payload, err := proto.Marshal(old)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got transporter.DeviceHealth
+			if err := proto.Unmarshal(payload, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Uptime != 60 {
+				t.Errorf("uptime = %d", got.Uptime)
+			}
+			if (got.FreeHeap == nil) != (tt.want == nil) || (tt.want != nil && *got.FreeHeap != *tt.want) {
+				t.Errorf("free heap = %v, want %v", got.FreeHeap, tt.want)
+			}
+		})
+	}
+}
+
+// compatCollections are the collections the relay and config handlers and
+// hooks use.
+var compatCollections = []collections.CollectionDefiner{
+	&collections.Devices{},
+	&collections.Relay{},
+	&collections.UserPortLables{},
+	&collections.RelayEvents{},
+	&collections.DeviceShadows{},
+	&collections.ClimateConfig{},
+	&collections.LDRConfig{},
+	&collections.MotionConfig{},
+}
+
+func TestLegacyPayloadsThroughHandlers(t *testing.T) {
+	a, _ := newTestArduino(t, compatCollections...)
+
+	device := newDevice(0, capabilities{})
+	if err := a.app.SaveNoValidate(device); err != nil {
+		t.Fatal(err)
+	}
+	relay := core.NewRecord(a.getCollection(collections.RelayCollectionName))
+	relay.Id = relayLowDuty
+	if err := a.app.SaveNoValidate(relay); err != nil {
+		t.Fatal(err)
+	}
+	port := core.NewRecord(a.getCollection(collections.UserPortLablesCollectionName))
+	port.Set("device", device.Id)
+	port.Set("relay", relayLowDuty)
+	port.Set("port", 3)
+	port.Set("lable", "lamp")
+	if err := a.app.SaveNoValidate(port); err != nil {
+		t.Fatal(err)
+	}
+
+	// a relay report of a board that does not know the shadow version
+	old := legacyMessage(t, &transporter.RelayState{}, withoutFields(4))
+	setField(old, "type", protoreflect.ValueOfEnum(protoreflect.EnumNumber(transporter.RelayType_LOW_DUTY)))
+	setField(old, "port", protoreflect.ValueOfUint32(3))
+	setField(old, "state", protoreflect.ValueOfEnum(protoreflect.EnumNumber(transporter.RelayStateType_ON)))
+	relayState, err := proto.Marshal(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Relay(&registry.Message{
+		Family:   a,
+		DeviceId: device.Id,
+		Packet:   packets.Packet{TopicName: "arduino/device1/relay", Payload: relayState, Origin: device.Id},
+	})
+
+	port, err = a.app.FindRecordById(collections.UserPortLablesCollectionName, port.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !port.GetBool("state") {
+		t.Error("relay report of an old board was not applied")
+	}
+
+	// a full report of an old board, with relay states without a version
+	// and a config of the first protocol
+	config, err := proto.Marshal(&transporter.FullConfig{
+		Climates: []*transporter.Climate{{Id: 1, Dht22Port: 4, AqiPort: 5}},
+		Ldrs:     []*transporter.LDR{{Id: 2, Port: 6}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload []byte
+	payload = protowire.AppendTag(payload, 2, protowire.BytesType)
+	payload = protowire.AppendBytes(payload, relayState)
+	payload = protowire.AppendTag(payload, 3, protowire.BytesType)
+	payload = protowire.AppendBytes(payload, config)
+	a.ShadowReport(&registry.Message{
+		Family:   a,
+		DeviceId: device.Id,
+		Packet:   packets.Packet{TopicName: "arduino/device1/shadow/reported", Payload: payload, Origin: device.Id},
+	})
+
+	shadow, err := a.app.FindFirstRecordByData(collections.DeviceShadowsCollectionName, "device", device.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, reported := shadowStates(shadow)
+	if !reported.Relays[relayKey(relayLowDuty, 3)] {
+		t.Errorf("reported relays %v", reported.Relays)
+	}
+	if got := reported.Sensors[sensorKey(sensorClimate, 1)]; got.Dht22Port != 4 || got.AqiPort != 5 {
+		t.Errorf("reported climate %+v", got)
+	}
+	if got := reported.Sensors[sensorKey(sensorLDR, 2)]; got.Port != 6 {
+		t.Errorf("reported ldr %+v", got)
+	}
+}
+
+func TestTopicsByProtocolVersion(t *testing.T) {
+	a, published := newTestArduino(t, compatCollections...)
+	a.RegisterHooks()
+
+	relay := core.NewRecord(a.getCollection(collections.RelayCollectionName))
+	relay.Id = relayLowDuty
+	if err := a.app.SaveNoValidate(relay); err != nil {
+		t.Fatal(err)
+	}
+
+	shadow := []string{featureShadow}
+	tests := []struct {
+		name     string
+		protocol int
+		features []string
+		want     []string
+	}{
+		{name: "legacy", protocol: 0, want: []string{"arduino/device1/config", "arduino/device1/relay"}},
+		// the shadow needs protocol version 2
+		{name: "protocol 1 with shadow", protocol: 1, features: shadow, want: []string{"arduino/device1/config", "arduino/device1/relay"}},
+		{name: "protocol 2 without shadow", protocol: 2, features: []string{}, want: []string{"arduino/device1/config", "arduino/device1/relay"}},
+		{name: "protocol 2 with shadow", protocol: 2, features: shadow, want: []string{"arduino/device1/shadow/delta"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newDevice(tt.protocol, capabilities{
+				Sensors:  []string{sensorClimate},
+				Relays:   []string{relayLowDuty},
+				Features: tt.features,
+			})
+			if err := a.app.SaveNoValidate(device); err != nil {
+				t.Fatal(err)
+			}
+			defer a.app.Delete(device)
+			published()
+
+			climate := core.NewRecord(a.getCollection(collections.ClimateConfigCollectionName))
+			climate.Set("device", device.Id)
+			climate.Set("sensor_id", 1)
+			climate.Set("dht22_port", 4)
+			if err := a.app.SaveNoValidate(climate); err != nil {
+				t.Fatal(err)
+			}
+
+			port := core.NewRecord(a.getCollection(collections.UserPortLablesCollectionName))
+			port.Set("device", device.Id)
+			port.Set("relay", relayLowDuty)
+			port.Set("port", 1)
+			port.Set("lable", "lamp")
+			if err := a.app.SaveNoValidate(port); err != nil {
+				t.Fatal(err)
+			}
+			port.Set("state", true)
+			if err := a.app.SaveNoValidate(port); err != nil {
+				t.Fatal(err)
+			}
+
+			// the shadow sends a delta for every change
+			if got := slices.Compact(published()); !slices.Equal(got, tt.want) {
+				t.Errorf("published on %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
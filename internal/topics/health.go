@@ -0,0 +1,203 @@
+package topics
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+const (
+	// lowRSSI is the WiFi signal in dBm below which the connection gets
+	// unreliable.
+	lowRSSI = -80
+	// lowFreeHeap is the free memory in bytes below which the board is
+	// likely to crash.
+	lowFreeHeap = 4096
+	// rebootLimit reboots within rebootWindow are reported as frequent.
+	rebootLimit  = 3
+	rebootWindow = time.Hour
+	// offlineAfter without a heartbeat a device is marked offline.
+	offlineAfter = 5 * time.Minute
+)
+
+const (
+	deviceOnline  = "online"
+	deviceOffline = "offline"
+)
+
+func (a *Arduino) Health(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
+
+	var d transporter.DeviceHealth
+	if err := msg.Decode(&d); err != nil {
+		a.app.Logger().Error("failed to unmarshal device health", slog.String("error", err.Error()))
+		a.reject(deviceId, 0, pk, fmt.Errorf("malformed payload: %w", err))
+		return
+	}
+
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	if err != nil {
+		a.reject(deviceId, 0, pk, errUnknownDevice)
+		return
+	}
+
+	// the uptime only goes back when the board restarted
+	var previous struct {
+		Uptime uint32 `json:"uptime"`
+	}
+	rebooted := device.UnmarshalJSONField("health", &previous) == nil && d.Uptime < previous.Uptime
+
+	health := map[string]any{
+		"uptime":            d.Uptime,
+		"rssi":              d.Rssi,
+		"firmware_version":  d.FirmwareVersion,
+		"reset_reason":      d.ResetReason,
+		"messages_sent":     d.MessagesSent,
+		"messages_received": d.MessagesReceived,
+		"reconnects":        d.Reconnects,
+	}
+	if d.FreeHeap != nil {
+		health["free_heap"] = d.GetFreeHeap()
+	}
+
+	// heartbeats arrive every few seconds, a targeted update keeps them out
+	// of the device hooks and the caches they drop
+	encoded, err := json.Marshal(health)
+	if err != nil {
+		a.app.Logger().Error("failed to encode device health", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+	_, err = a.app.NonconcurrentDB().NewQuery(fmt.Sprintf(
+		"UPDATE {{%s}} SET [[health]] = {:health}, [[last_seen]] = {:seen}, [[device_status]] = {:status}, "+
+			"[[firmware_version]] = CASE WHEN {:firmware} = '' THEN [[firmware_version]] ELSE {:firmware} END WHERE [[id]] = {:id}",
+		collections.DevicesCollectionName,
+	)).Bind(dbx.Params{
+		"health":   string(encoded),
+		"seen":     types.NowDateTime().String(),
+		"status":   deviceOnline,
+		"firmware": d.FirmwareVersion,
+		"id":       deviceId,
+	}).Execute()
+	if err != nil {
+		a.app.Logger().Error("failed to save device health", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	record := core.NewRecord(a.getCollection(collections.DeviceHealthCollectionName))
+	record.Load(health)
+	record.Set("device", deviceId)
+	record.Set("rebooted", rebooted)
+	if !a.ingest.Enqueue(record) {
+		a.app.Logger().Debug("device health dropped", slog.String("device_id", deviceId))
+	}
+
+	if err := a.checkHealth(deviceId, &d, rebooted); err != nil {
+		a.app.Logger().Error("failed to check device health", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+	}
+}
+
+func (a *Arduino) checkHealth(deviceId string, d *transporter.DeviceHealth, rebooted bool) error {
+	if err := a.Alert(
+		deviceId,
+		collections.AlertLowRSSI,
+		d.Rssi < lowRSSI,
+		float64(d.Rssi),
+		fmt.Sprintf("WiFi signal is weak (%d dBm)", d.Rssi),
+	); err != nil {
+		return err
+	}
+
+	// boards that cannot measure their free memory do not send it
+	if d.FreeHeap != nil {
+		if err := a.Alert(
+			deviceId,
+			collections.AlertLowMemory,
+			d.GetFreeHeap() < lowFreeHeap,
+			float64(d.GetFreeHeap()),
+			fmt.Sprintf("free memory is low (%d bytes)", d.GetFreeHeap()),
+		); err != nil {
+			return err
+		}
+	}
+
+	// the current heartbeat is still queued, so it is counted separately
+	reboots, err := a.app.CountRecords(
+		collections.DeviceHealthCollectionName,
+		dbx.HashExp{"device": deviceId, "rebooted": true},
+		dbx.NewExp("timestamp > {:since}", dbx.Params{"since": types.NowDateTime().Add(-rebootWindow).String()}),
+	)
+	if err != nil {
+		return err
+	}
+	if rebooted {
+		reboots++
+	}
+
+	return a.Alert(
+		deviceId,
+		collections.AlertFrequentReboots,
+		reboots >= rebootLimit,
+		float64(reboots),
+		fmt.Sprintf("rebooted %d times within %s (last reset reason: %s)", reboots, rebootWindow, d.ResetReason),
+	)
+}
+
+// Alert raises an alert of the kind for the device when active, unless one is
+// already open, and resolves the open alert when not active.
+func (a *Arduino) Alert(deviceId string, kind string, active bool, value float64, message string) error {
+	open, _ := a.app.FindFirstRecordByFilter(
+		collections.DeviceAlertsCollectionName,
+		"device = {:device} && kind = {:kind} && resolved = ''",
+		dbx.Params{"device": deviceId, "kind": kind},
+	)
+
+	switch {
+	case active && open == nil:
+		alert := core.NewRecord(a.getCollection(collections.DeviceAlertsCollectionName))
+		alert.Set("device", deviceId)
+		alert.Set("kind", kind)
+		alert.Set("value", value)
+		alert.Set("message", message)
+		if err := a.app.Save(alert); err != nil {
+			return err
+		}
+
+		a.app.Logger().Warn("device alert", slog.String("device_id", deviceId), slog.String("kind", kind), slog.String("message", message))
+	case !active && open != nil:
+		open.Set("resolved", types.NowDateTime())
+		return a.app.Save(open)
+	}
+
+	return nil
+}
+
+// markOffline marks the devices that stopped sending heartbeats as offline.
+func (a *Arduino) markOffline() {
+	devices, err := a.app.FindRecordsByFilter(
+		collections.DevicesCollectionName,
+		"device_status = {:online} && last_seen < {:since}",
+		"",
+		0,
+		0,
+		dbx.Params{"online": deviceOnline, "since": types.NowDateTime().Add(-offlineAfter).String()},
+	)
+	if err != nil {
+		a.app.Logger().Error("failed to find offline devices", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, device := range devices {
+		device.Set("device_status", deviceOffline)
+		if err := a.app.Save(device); err != nil {
+			a.app.Logger().Error("failed to mark device offline", slog.String("device_id", device.Id), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package topics
+
+import (
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/ingest"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/pocketbase/core"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHealthSkipsDeviceHooks(t *testing.T) {
+	a, _ := newTestArduino(t,
+		&collections.Devices{},
+		&collections.DeviceHealth{},
+		&collections.DeviceAlerts{},
+	)
+	a.ingest = ingest.NewQueue(a.app, ingest.Options{})
+
+	device := newDevice(0, capabilities{})
+	device.Set("firmware_version", "1.0.0")
+	if err := a.app.SaveNoValidate(device); err != nil {
+		t.Fatal(err)
+	}
+
+	var updates int
+	a.app.OnRecordAfterUpdateSuccess(collections.DevicesCollectionName).BindFunc(func(e *core.RecordEvent) error {
+		updates++
+		return e.Next()
+	})
+
+	heartbeat := func(d *transporter.DeviceHealth) {
+		t.Helper()
+		payload, err := proto.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		a.Health(&registry.Message{
+			Family:   a,
+			DeviceId: device.Id,
+			Packet:   packets.Packet{TopicName: "arduino/device1/health", Payload: payload, Origin: device.Id},
+		})
+	}
+
+	heartbeat(&transporter.DeviceHealth{Uptime: 60, Rssi: -50, FreeHeap: proto.Uint32(20480)})
+	// boards only send their firmware version now and then
+	heartbeat(&transporter.DeviceHealth{Uptime: 120, Rssi: -50, FreeHeap: proto.Uint32(20480), FirmwareVersion: "1.1.0"})
+	heartbeat(&transporter.DeviceHealth{Uptime: 180, Rssi: -50, FreeHeap: proto.Uint32(20480)})
+
+	if updates != 0 {
+		t.Errorf("device hooks ran %d times for heartbeats", updates)
+	}
+
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, device.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := device.GetString("device_status"); got != deviceOnline {
+		t.Errorf("device status = %q", got)
+	}
+	if device.GetDateTime("last_seen").IsZero() {
+		t.Error("last seen not set")
+	}
+	if got := device.GetString("firmware_version"); got != "1.1.0" {
+		t.Errorf("firmware version = %q", got)
+	}
+	var health struct {
+		Uptime uint32 `json:"uptime"`
+	}
+	if err := device.UnmarshalJSONField("health", &health); err != nil || health.Uptime != 180 {
+		t.Errorf("health uptime = %d, %v", health.Uptime, err)
+	}
+}
@@ -0,0 +1,103 @@
+package topics
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/ingest"
+	"coderero.dev/iot/smaas-server/internal/metrics"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/pocketbase/pocketbase/core"
+	"google.golang.org/protobuf/proto"
+)
+
+// BenchmarkIngest publishes climate messages on the broker with the Arduino
+// handlers subscribed. It compares writing every reading on its own, as the
+// handlers did before the queue, with writing them in batches.
+func BenchmarkIngest(b *testing.B) {
+	for _, bench := range []struct {
+		name      string
+		batchSize int
+	}{
+		{name: "save", batchSize: 1},
+		{name: "queue", batchSize: ingest.DefaultBatchSize},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			app := core.NewBaseApp(core.BaseAppConfig{DataDir: b.TempDir()})
+			if err := app.Bootstrap(); err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { app.ResetBootstrapState() })
+
+			defs := []collections.CollectionDefiner{
+				&collections.Devices{},
+				&collections.ClimateConfig{},
+				&collections.Readings{},
+			}
+			for _, c := range defs {
+				if err := app.SaveNoValidate(c.Schema()); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			device := newDevice(0, capabilities{})
+			if err := app.SaveNoValidate(device); err != nil {
+				b.Fatal(err)
+			}
+			climate := core.NewRecord((&collections.ClimateConfig{}).Schema())
+			climate.Set("device", device.Id)
+			climate.Set("sensor_id", 1)
+			if err := app.SaveNoValidate(climate); err != nil {
+				b.Fatal(err)
+			}
+
+			server := mqtt.New(&mqtt.Options{
+				InlineClient: true,
+				Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+			})
+			if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+				b.Fatal(err)
+			}
+			if err := server.Serve(); err != nil {
+				b.Fatal(err)
+			}
+			b.Cleanup(func() { server.Close() })
+
+			d := &transporter.ClimateData{Id: 1, Temperature: 21.5, Humidity: 40, Aqi: 50}
+			payload, err := proto.Marshal(d)
+			if err != nil {
+				b.Fatal(err)
+			}
+			// every climate message is stored as one reading per metric
+			readings := uint64(b.N * len(climateMetrics(d)))
+
+			queue := ingest.NewQueue(app, ingest.Options{QueueSize: int(readings) + 1, BatchSize: bench.batchSize})
+			reg := metrics.NewRegistry()
+			a := NewArduino(defs, app, server, queue, reg, false)
+			handlers := registry.New(app, server)
+			if err := handlers.Register(a); err != nil {
+				b.Fatal(err)
+			}
+			queue.Start()
+			b.ResetTimer()
+
+			for range b.N {
+				if err := server.Publish("arduino/device1/climate", payload, false, 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+			// includes writing the readings, not only queueing them
+			queue.Stop()
+
+			b.StopTimer()
+			if written := queue.Stats().Written; written != readings {
+				b.Fatalf("written = %d, want %d", written, readings)
+			}
+		})
+	}
+}
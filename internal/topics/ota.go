@@ -0,0 +1,349 @@
+package topics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var otaStates = map[transporter.OtaState]string{
+	transporter.OtaState_OTA_PENDING:     collections.OtaPending,
+	transporter.OtaState_OTA_DOWNLOADING: collections.OtaDownloading,
+	transporter.OtaState_OTA_INSTALLING:  collections.OtaInstalling,
+	transporter.OtaState_OTA_SUCCEEDED:   collections.OtaSucceeded,
+	transporter.OtaState_OTA_FAILED:      collections.OtaFailed,
+}
+
+// otaFinished are the update states a device does not leave anymore.
+var otaFinished = []string{collections.OtaSucceeded, collections.OtaFailed, collections.OtaCancelled}
+
+// defaultFailureThreshold is the failure threshold of rollouts created
+// without one.
+const defaultFailureThreshold = 20
+
+// minRolloutSample is the number of succeeded and failed updates a rollout
+// needs before it is halted, so a single early failure does not halt it.
+// Smaller rollouts need all of their updates.
+const minRolloutSample = 5
+
+func (a *Arduino) OtaStatus(msg *registry.Message) {
+	deviceId := msg.DeviceId
+
+	var d transporter.OtaStatus
+	if err := msg.Decode(&d); err != nil {
+		a.app.Logger().Error("failed to unmarshal ota status", slog.String("error", err.Error()))
+		return
+	}
+
+	update, err := a.app.FindRecordById(collections.OtaUpdatesCollectionName, d.Id)
+	if err != nil || update.GetString("device") != deviceId {
+		a.app.Logger().Error("failed to find ota update", slog.String("device_id", deviceId), slog.String("update_id", d.Id))
+		return
+	}
+
+	if slices.Contains(otaFinished, update.GetString("status")) {
+		return
+	}
+
+	status, ok := otaStates[d.State]
+	if !ok {
+		a.app.Logger().Error("unknown ota state", slog.String("device_id", deviceId), slog.Int("state", int(d.State)))
+		return
+	}
+
+	update.Set("status", status)
+	update.Set("progress", min(int(d.Progress), 100))
+	update.Set("error", d.Error)
+	if status == collections.OtaSucceeded {
+		update.Set("progress", 100)
+	}
+	if err := a.app.Save(update); err != nil {
+		a.app.Logger().Error("failed to save ota update", slog.String("error", err.Error()))
+		return
+	}
+
+	if status == collections.OtaSucceeded {
+		if err := a.setFirmwareVersion(update, d.Version); err != nil {
+			a.app.Logger().Error("failed to save firmware version", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		}
+	}
+
+	if slices.Contains(otaFinished, status) {
+		if err := a.checkRollout(update.GetString("rollout")); err != nil {
+			a.app.Logger().Error("failed to check rollout", slog.String("rollout", update.GetString("rollout")), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// firmwareChecksumHook stores the size and SHA-256 of an uploaded firmware
+// file. A checksum given with the upload must match the file.
+func (a *Arduino) firmwareChecksumHook(e *core.RecordEvent) error {
+	files := e.Record.GetUnsavedFiles("file")
+	if len(files) == 0 {
+		return e.Next()
+	}
+
+	f, err := files[0].Reader.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	if given := strings.ToLower(e.Record.GetString("sha256")); given != "" && given != sum && e.Record.IsNew() {
+		return errors.New("sha256 does not match the uploaded file")
+	}
+
+	e.Record.Set("sha256", sum)
+	e.Record.Set("size", size)
+
+	return e.Next()
+}
+
+// rolloutDefaultsHook sets the failure threshold of rollouts created without
+// one, through the API or in code.
+func (a *Arduino) rolloutDefaultsHook(e *core.RecordEvent) error {
+	if e.Record.GetInt("failure_threshold") == 0 {
+		e.Record.Set("failure_threshold", defaultFailureThreshold)
+	}
+
+	return e.Next()
+}
+
+func (a *Arduino) rolloutHook(e *core.RecordEvent) error {
+	if e.Record.GetString("status") == collections.RolloutRunning {
+		if err := a.startRollout(e.App, e.Record); err != nil {
+			a.app.Logger().Error("failed to start rollout", slog.String("rollout", e.Record.Id), slog.String("error", err.Error()))
+		}
+	}
+
+	return e.Next()
+}
+
+// startRollout sends the firmware to the targets of the rollout that did
+// not get it yet. It is run again whenever the rollout is (re)started, so
+// raising the percentage extends a rollout to more devices.
+func (a *Arduino) startRollout(app core.App, rollout *core.Record) error {
+	firmware, err := app.FindRecordById(collections.FirmwareCollectionName, rollout.GetString("firmware"))
+	if err != nil {
+		return err
+	}
+
+	targets, err := a.rolloutTargets(app, rollout, firmware)
+	if err != nil {
+		return err
+	}
+
+	sum, err := hex.DecodeString(firmware.GetString("sha256"))
+	if err != nil {
+		return err
+	}
+
+	for _, device := range targets {
+		existing, _ := app.FindFirstRecordByFilter(
+			collections.OtaUpdatesCollectionName,
+			"rollout = {:rollout} && device = {:device}",
+			dbx.Params{"rollout": rollout.Id, "device": device.Id},
+		)
+		if existing != nil || device.GetString("firmware_version") == firmware.GetString("version") {
+			continue
+		}
+
+		update := core.NewRecord(a.getCollection(collections.OtaUpdatesCollectionName))
+		update.Set("rollout", rollout.Id)
+		update.Set("device", device.Id)
+		update.Set("status", collections.OtaPending)
+		if err := app.Save(update); err != nil {
+			return err
+		}
+
+		a.publishOta(device.Id, &transporter.OtaCommand{
+			Payload: &transporter.OtaCommand_Update{
+				Update: &transporter.OtaUpdate{
+					Id:      update.Id,
+					Version: firmware.GetString("version"),
+					Url:     firmwareURL(app, firmware),
+					Sha256:  sum,
+					Size:    uint32(firmware.GetInt("size")),
+				},
+			},
+		})
+	}
+
+	return nil
+}
+
+// rolloutTargets returns the listed devices, or when none are listed a stable
+// share of the devices with the hardware of the firmware. Devices of other
+// hardware are never targeted.
+func (a *Arduino) rolloutTargets(app core.App, rollout *core.Record, firmware *core.Record) ([]*core.Record, error) {
+	hardware := firmware.GetString("hardware")
+
+	if ids := rollout.GetStringSlice("devices"); len(ids) > 0 {
+		devices, err := app.FindRecordsByIds(collections.DevicesCollectionName, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		return slices.DeleteFunc(devices, func(device *core.Record) bool {
+			return device.GetString("hardware") != hardware || !supportsFeature(device, featureOta)
+		}), nil
+	}
+
+	devices, err := app.FindRecordsByFilter(
+		collections.DevicesCollectionName,
+		"hardware = {:hardware}",
+		"",
+		0,
+		0,
+		dbx.Params{"hardware": hardware},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	percentage := uint32(rollout.GetInt("percentage"))
+	return slices.DeleteFunc(devices, func(device *core.Record) bool {
+		h := fnv.New32a()
+		h.Write([]byte(rollout.Id + device.Id))
+		return h.Sum32()%100 >= percentage || !supportsFeature(device, featureOta)
+	}), nil
+}
+
+// checkRollout halts the rollout once the share of failed updates among the
+// succeeded and failed ones exceeds its failure threshold, and completes it
+// once every device finished. Pending updates do not count, so the first
+// failures of a large rollout are not diluted by the devices that did not
+// start yet, and neither do cancelled ones.
+func (a *Arduino) checkRollout(rolloutId string) error {
+	rollout, err := a.app.FindRecordById(collections.OtaRolloutsCollectionName, rolloutId)
+	if err != nil {
+		return err
+	}
+
+	if rollout.GetString("status") != collections.RolloutRunning {
+		return nil
+	}
+
+	updates, err := a.app.FindAllRecords(collections.OtaUpdatesCollectionName, dbx.HashExp{"rollout": rollout.Id})
+	if err != nil {
+		return err
+	}
+
+	var failed, succeeded, cancelled int
+	for _, update := range updates {
+		switch update.GetString("status") {
+		case collections.OtaFailed:
+			failed++
+		case collections.OtaSucceeded:
+			succeeded++
+		case collections.OtaCancelled:
+			cancelled++
+		}
+	}
+	decided := failed + succeeded
+	sample := min(minRolloutSample, len(updates)-cancelled)
+
+	switch {
+	case decided > 0 && decided >= sample && failed*100 > rollout.GetInt("failure_threshold")*decided:
+		rollout.Set("status", collections.RolloutHalted)
+		if err := a.app.Save(rollout); err != nil {
+			return err
+		}
+		a.app.Logger().Warn("rollout halted", slog.String("rollout", rollout.Id), slog.Int("failed", failed), slog.Int("succeeded", succeeded))
+
+		return a.cancelUpdates(updates)
+	case decided+cancelled == len(updates):
+		rollout.Set("status", collections.RolloutCompleted)
+		return a.app.Save(rollout)
+	}
+
+	return nil
+}
+
+func (a *Arduino) cancelUpdates(updates []*core.Record) error {
+	for _, update := range updates {
+		if slices.Contains(otaFinished, update.GetString("status")) {
+			continue
+		}
+
+		update.Set("status", collections.OtaCancelled)
+		if err := a.app.Save(update); err != nil {
+			return err
+		}
+
+		a.publishOta(update.GetString("device"), &transporter.OtaCommand{
+			Payload: &transporter.OtaCommand_Cancel{
+				Cancel: &transporter.OtaCancel{Id: update.Id},
+			},
+		})
+	}
+
+	return nil
+}
+
+func (a *Arduino) setFirmwareVersion(update *core.Record, version string) error {
+	if version == "" {
+		rollout, err := a.app.FindRecordById(collections.OtaRolloutsCollectionName, update.GetString("rollout"))
+		if err != nil {
+			return err
+		}
+		firmware, err := a.app.FindRecordById(collections.FirmwareCollectionName, rollout.GetString("firmware"))
+		if err != nil {
+			return err
+		}
+		version = firmware.GetString("version")
+	}
+
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, update.GetString("device"))
+	if err != nil {
+		return err
+	}
+
+	device.Set("firmware_version", version)
+	return a.app.Save(device)
+}
+
+func (a *Arduino) publishOta(deviceId string, command *transporter.OtaCommand) {
+	topic := fmt.Sprintf("arduino/%s/ota", deviceId)
+
+	topic, payload, err := a.marshal(deviceId, topic, command)
+	if err != nil {
+		a.app.Logger().Error("failed to marshal ota command", slog.String("error", err.Error()))
+		return
+	}
+	if err := a.publish(topic, payload, false, 1); err != nil {
+		a.app.Logger().Error("failed to publish ota command", slog.String("error", err.Error()))
+		return
+	}
+
+	a.app.Logger().Info("published ota command", slog.String("topic", topic), slog.String("device_id", deviceId))
+}
+
+// firmwareURL is the public download URL of the firmware file.
+func firmwareURL(app core.App, firmware *core.Record) string {
+	return fmt.Sprintf(
+		"%s/api/files/%s/%s",
+		strings.TrimRight(app.Settings().Meta.AppURL, "/"),
+		firmware.BaseFilesPath(),
+		firmware.GetString("file"),
+	)
+}
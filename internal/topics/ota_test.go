@@ -0,0 +1,157 @@
+package topics
+
+import (
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/metrics"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestCheckRollout(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	for _, c := range []collections.CollectionDefiner{
+		&collections.Devices{},
+		&collections.Firmware{},
+		&collections.OtaRollouts{},
+		&collections.OtaUpdates{},
+	} {
+		if err := app.SaveNoValidate(c.Schema()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the rollout and the updates are saved with their relations checked
+	firmware := core.NewRecord((&collections.Firmware{}).Schema())
+	if err := app.SaveNoValidate(firmware); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Arduino{
+		app:           app,
+		mqttServer:    mqtt.New(&mqtt.Options{InlineClient: true}),
+		publishErrors: metrics.NewRegistry().Counter("publish_errors", "", "topic"),
+	}
+	app.OnRecordCreate(collections.OtaRolloutsCollectionName).BindFunc(a.rolloutDefaultsHook)
+
+	tests := []struct {
+		name string
+		// threshold of the rollout, the default when 0
+		threshold int
+		updates   map[string]int
+		want      string
+	}{
+		{
+			name:    "first failure",
+			updates: map[string]int{collections.OtaFailed: 1, collections.OtaPending: 9},
+			want:    collections.RolloutRunning,
+		},
+		{
+			name:    "below the threshold",
+			updates: map[string]int{collections.OtaFailed: 1, collections.OtaSucceeded: 4, collections.OtaPending: 5},
+			want:    collections.RolloutRunning,
+		},
+		{
+			name:    "above the threshold",
+			updates: map[string]int{collections.OtaFailed: 2, collections.OtaSucceeded: 3, collections.OtaPending: 5},
+			want:    collections.RolloutHalted,
+		},
+		{
+			name:      "own threshold",
+			threshold: 50,
+			updates:   map[string]int{collections.OtaFailed: 2, collections.OtaSucceeded: 3, collections.OtaPending: 5},
+			want:      collections.RolloutRunning,
+		},
+		{
+			name:    "cancelled updates do not dilute failures",
+			updates: map[string]int{collections.OtaFailed: 2, collections.OtaSucceeded: 3, collections.OtaCancelled: 10},
+			want:    collections.RolloutHalted,
+		},
+		{
+			name:    "small rollout failed",
+			updates: map[string]int{collections.OtaFailed: 2},
+			want:    collections.RolloutHalted,
+		},
+		{
+			name:    "completed",
+			updates: map[string]int{collections.OtaFailed: 1, collections.OtaSucceeded: 9},
+			want:    collections.RolloutCompleted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rollout := core.NewRecord((&collections.OtaRollouts{}).Schema())
+			rollout.Set("firmware", firmware.Id)
+			rollout.Set("status", collections.RolloutRunning)
+			rollout.Set("failure_threshold", tt.threshold)
+			if err := app.SaveNoValidate(rollout); err != nil {
+				t.Fatal(err)
+			}
+
+			var pending []*core.Record
+			for status, count := range tt.updates {
+				for range count {
+					device := core.NewRecord((&collections.Devices{}).Schema())
+					if err := app.SaveNoValidate(device); err != nil {
+						t.Fatal(err)
+					}
+
+					update := core.NewRecord((&collections.OtaUpdates{}).Schema())
+					update.Set("rollout", rollout.Id)
+					update.Set("device", device.Id)
+					update.Set("status", status)
+					if err := app.SaveNoValidate(update); err != nil {
+						t.Fatal(err)
+					}
+					if status == collections.OtaPending {
+						pending = append(pending, update)
+					}
+				}
+			}
+
+			if err := a.checkRollout(rollout.Id); err != nil {
+				t.Fatal(err)
+			}
+
+			rollout, err := app.FindRecordById(collections.OtaRolloutsCollectionName, rollout.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rollout.GetString("status"); got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+
+			// a halted rollout cancels the updates that did not finish
+			for _, update := range pending {
+				update, err := app.FindRecordById(collections.OtaUpdatesCollectionName, update.Id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if halted := tt.want == collections.RolloutHalted; halted != (update.GetString("status") == collections.OtaCancelled) {
+					t.Errorf("update %s with the rollout %s", update.GetString("status"), tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRolloutDefaults(t *testing.T) {
+	a := &Arduino{}
+	for threshold, want := range map[int]int{0: defaultFailureThreshold, 5: 5} {
+		rollout := core.NewRecord((&collections.OtaRollouts{}).Schema())
+		rollout.Set("failure_threshold", threshold)
+		if err := a.rolloutDefaultsHook(recordEvent(nil, rollout)); err != nil {
+			t.Fatal(err)
+		}
+		if got := rollout.GetInt("failure_threshold"); got != want {
+			t.Errorf("failure threshold %d = %d, want %d", threshold, got, want)
+		}
+	}
+}
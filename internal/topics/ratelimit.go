@@ -0,0 +1,34 @@
+package topics
+
+import (
+	"fmt"
+	"log/slog"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// RecordIncident counts a rate limit incident of the broker on the device.
+// Clients that are not devices, like unprovisioned boards, are only logged
+// by the broker. The count is incremented in the database, as the incidents
+// of a device are recorded concurrently.
+func (a *Arduino) RecordIncident(deviceId string, limit string, incident string) {
+	result, err := a.app.NonconcurrentDB().NewQuery(fmt.Sprintf(
+		"UPDATE {{%s}} SET [[rate_limit_incidents]] = [[rate_limit_incidents]] + 1, "+
+			"[[rate_limited_at]] = {:at}, [[rate_limit_reason]] = {:reason} WHERE [[id]] = {:id}",
+		collections.DevicesCollectionName,
+	)).Bind(dbx.Params{
+		"at":     types.NowDateTime().String(),
+		"reason": limit + ": " + incident,
+		"id":     deviceId,
+	}).Execute()
+	if err != nil {
+		a.app.Logger().Error("failed to record rate limit incident", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	if updated, _ := result.RowsAffected(); updated > 0 {
+		a.app.Logger().Warn("device rate limited", slog.String("device_id", deviceId), slog.String("limit", limit), slog.String("incident", incident))
+	}
+}
@@ -0,0 +1,72 @@
+package topics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// RelayOrigin is what switched a relay port, the users through the API or
+// the device reporting a switch of its own.
+type RelayOrigin string
+
+const (
+	RelayOriginUser   RelayOrigin = "user"
+	RelayOriginDevice RelayOrigin = "device"
+)
+
+type relayOriginKey struct{}
+
+// WithRelayOrigin marks the relay port saves done with the returned context
+// as coming from the given origin. Saves without an origin are treated as
+// user changes.
+func WithRelayOrigin(ctx context.Context, origin RelayOrigin) context.Context {
+	return context.WithValue(ctx, relayOriginKey{}, origin)
+}
+
+func relayOriginFrom(ctx context.Context) RelayOrigin {
+	if ctx != nil {
+		if origin, ok := ctx.Value(relayOriginKey{}).(RelayOrigin); ok {
+			return origin
+		}
+	}
+	return RelayOriginUser
+}
+
+// relayHistoryHook accumulates the port runtime and records every state
+// transition in the relay_events collection.
+func (a *Arduino) relayHistoryHook(e *core.RecordEvent) error {
+	record := e.Record
+	previous := record.Original().GetBool("state")
+	state := record.GetBool("state")
+	if previous == state {
+		return e.Next()
+	}
+
+	now := time.Now()
+	if state {
+		record.Set("last_on", now)
+	} else if lastOn := record.GetDateTime("last_on"); !lastOn.IsZero() {
+		record.Set("runtime", record.GetInt("runtime")+int(now.Sub(lastOn.Time()).Seconds()))
+		record.Set("last_on", "")
+	}
+
+	if err := e.Next(); err != nil {
+		return err
+	}
+
+	event := core.NewRecord(a.getCollection(collections.RelayEventsCollectionName))
+	event.Set("device", record.GetString("device"))
+	event.Set("port", record.Id)
+	event.Set("state", state)
+	event.Set("origin", string(relayOriginFrom(e.Context)))
+
+	if err := e.App.Save(event); err != nil {
+		a.app.Logger().Error("failed to save relay event", slog.String("port", record.Id), slog.String("error", err.Error()))
+	}
+
+	return nil
+}
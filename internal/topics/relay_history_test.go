@@ -0,0 +1,61 @@
+package topics
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestRelayHistoryOrigin(t *testing.T) {
+	a, _ := newTestArduino(t, compatCollections...)
+	a.app.OnRecordUpdateExecute(collections.UserPortLablesCollectionName).BindFunc(a.relayHistoryHook)
+
+	device := newDevice(0, capabilities{})
+	if err := a.app.SaveNoValidate(device); err != nil {
+		t.Fatal(err)
+	}
+	relay := core.NewRecord(a.getCollection(collections.RelayCollectionName))
+	relay.Id = relayLowDuty
+	if err := a.app.SaveNoValidate(relay); err != nil {
+		t.Fatal(err)
+	}
+	port := core.NewRecord(a.getCollection(collections.UserPortLablesCollectionName))
+	port.Set("device", device.Id)
+	port.Set("relay", relayLowDuty)
+	port.Set("port", 1)
+	port.Set("lable", "lamp")
+	if err := a.app.Save(port); err != nil {
+		t.Fatal(err)
+	}
+
+	// saves without an origin are changes of the users
+	port.Set("state", true)
+	if err := a.app.Save(port); err != nil {
+		t.Fatal(err)
+	}
+	port, err := a.app.FindRecordById(collections.UserPortLablesCollectionName, port.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port.Set("state", false)
+	if err := a.app.SaveWithContext(WithRelayOrigin(context.Background(), RelayOriginDevice), port); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := a.app.FindRecordsByFilter(collections.RelayEventsCollectionName, "port = {:port}", "", 0, 0, dbx.Params{"port": port.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var origins []string
+	for _, event := range events {
+		origins = append(origins, event.GetString("origin"))
+	}
+	slices.Sort(origins)
+	if want := []string{string(RelayOriginDevice), string(RelayOriginUser)}; !slices.Equal(origins, want) {
+		t.Errorf("relay event origins %v, want %v", origins, want)
+	}
+}
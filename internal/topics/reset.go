@@ -0,0 +1,124 @@
+package topics
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// decommissionHook turns the deletion of a device into a decommission. The
+// device is reset and only marked as deleted, so it can be restored until
+// it is purged. Deleting a decommissioned device purges it right away.
+func (a *Arduino) decommissionHook(e *core.RecordRequestEvent) error {
+	device := e.Record
+	if !device.GetDateTime("deleted_at").IsZero() {
+		return e.Next()
+	}
+
+	device.Set("deleted_at", types.NowDateTime())
+	device.Set("reset_nonce", security.RandomString(16))
+	device.Set("reset_acknowledged", "")
+	if err := e.App.Save(device); err != nil {
+		return e.BadRequestError("failed to decommission device", err)
+	}
+
+	a.publishFactoryReset(device)
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// factoryResetHook resets a device that did not acknowledge a reset yet and
+// revokes its credentials before it is purged.
+func (a *Arduino) factoryResetHook(e *core.RecordEvent) error {
+	device := e.Record
+	if device.GetDateTime("reset_acknowledged").IsZero() {
+		if device.GetString("reset_nonce") == "" {
+			device.Set("reset_nonce", security.RandomString(16))
+		}
+		a.publishFactoryReset(device)
+	}
+
+	// the provisioning record loses its device once it is deleted
+	a.revokeCredentials(e.App, device.Id)
+
+	return e.Next()
+}
+
+func (a *Arduino) FactoryResetAck(msg *registry.Message) {
+	deviceId := msg.DeviceId
+
+	var d transporter.FactoryResetAck
+	if err := msg.Decode(&d); err != nil {
+		a.app.Logger().Error("failed to unmarshal factory reset ack", slog.String("error", err.Error()))
+		return
+	}
+
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	if err != nil {
+		a.app.Logger().Error("failed to find device", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	if device.GetDateTime("deleted_at").IsZero() || d.Nonce == "" || d.Nonce != device.GetString("reset_nonce") {
+		a.app.Logger().Error("unexpected factory reset ack", slog.String("device_id", deviceId))
+		return
+	}
+
+	device.Set("reset_acknowledged", types.NowDateTime())
+	if err := a.app.Save(device); err != nil {
+		a.app.Logger().Error("failed to save factory reset ack", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	a.app.Logger().Info("factory reset acknowledged", slog.String("device_id", deviceId))
+	a.revokeCredentials(a.app, deviceId)
+}
+
+func (a *Arduino) publishFactoryReset(device *core.Record) {
+	topic := fmt.Sprintf("arduino/%s/factory_reset", device.Id)
+
+	topic, payload, err := a.marshal(device.Id, topic, &transporter.FactoryReset{
+		Nonce: device.GetString("reset_nonce"),
+	})
+	if err != nil {
+		a.app.Logger().Error("failed to marshal factory reset", slog.String("error", err.Error()))
+		return
+	}
+	if err := a.publish(topic, payload, false, 1); err != nil {
+		a.app.Logger().Error("failed to publish factory reset", slog.String("error", err.Error()))
+		return
+	}
+
+	a.app.Logger().Info("published factory reset", slog.String("topic", topic), slog.String("device_id", device.Id))
+}
+
+// revokeCredentials drops the MQTT password issued to the device and
+// disconnects it. A restored device has to be provisioned again.
+func (a *Arduino) revokeCredentials(app core.App, deviceId string) {
+	provisioning, err := app.FindFirstRecordByFilter(
+		collections.ProvisioningCollectionName,
+		"device = {:device}",
+		dbx.Params{"device": deviceId},
+	)
+	if err == nil {
+		provisioning.Set("password", "")
+		if err := app.Save(provisioning); err != nil {
+			a.app.Logger().Error("failed to revoke device credentials", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		}
+	}
+
+	for _, cl := range a.mqttServer.Clients.GetAll() {
+		if string(cl.Properties.Username) == deviceId {
+			_ = a.mqttServer.DisconnectClient(cl, packets.ErrNotAuthorized)
+		}
+	}
+}
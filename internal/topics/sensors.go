@@ -0,0 +1,435 @@
+package topics
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"google.golang.org/protobuf/proto"
+)
+
+// legacyTelemetry copies the readings stored before the generic sensor
+// model into the readings collection, per sensor type and metric.
+var legacyTelemetry = map[string][]struct {
+	collection string
+	metric     string
+	column     string
+}{
+	sensorClimate: {
+		{collections.ClimateCollectionName, "temperature", "temperature"},
+		{collections.ClimateCollectionName, "humidity", "humidity"},
+		{collections.ClimateCollectionName, "air_quality", "air_quality"},
+	},
+	sensorLDR: {
+		{collections.LDRCollectionName, "light", "ldr_value"},
+	},
+}
+
+// sensorType is a sensor type of the catalog.
+type sensorType struct {
+	id      string
+	name    string
+	metrics []collections.SensorMetric
+	ports   []collections.SensorPort
+}
+
+func findSensorType(app core.App, filter string, params dbx.Params) (*sensorType, error) {
+	record, err := app.FindFirstRecordByFilter(collections.SensorTypesCollectionName, filter, params)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &sensorType{id: record.Id, name: record.GetString("name")}
+	if err := record.UnmarshalJSONField("metrics", &t.metrics); err != nil {
+		return nil, fmt.Errorf("sensor type %s has invalid metrics: %w", t.name, err)
+	}
+	if err := record.UnmarshalJSONField("ports", &t.ports); err != nil {
+		return nil, fmt.Errorf("sensor type %s has invalid ports: %w", t.name, err)
+	}
+
+	return t, nil
+}
+
+func (a *Arduino) Reading(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
+
+	var d transporter.SensorReading
+	if err := msg.Decode(&d); err != nil {
+		a.app.Logger().Error("failed to unmarshal sensor reading", slog.String("error", err.Error()))
+		a.reject(deviceId, 0, pk, fmt.Errorf("malformed payload: %w", err))
+		return
+	}
+
+	if err := a.validateReading(deviceId, &d); err != nil {
+		a.reject(deviceId, int(d.Id), pk, err)
+		return
+	}
+
+	a.storeReading(deviceId, d.Type, int(d.Id), d.Metrics)
+}
+
+// validateReading checks the reading against the sensor config and the
+// metrics of its sensor type.
+func (a *Arduino) validateReading(deviceId string, d *transporter.SensorReading) error {
+	if !a.deviceExists(deviceId) {
+		return errUnknownDevice
+	}
+
+	t, err := findSensorType(a.app, "name = {:name}", dbx.Params{"name": d.Type})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("unknown sensor type %q", d.Type)
+		}
+		return err
+	}
+
+	_, err = a.app.FindFirstRecordByFilter(
+		collections.SensorsCollectionName,
+		"device = {:device} && type = {:type} && sensor_id = {:sensor}",
+		dbx.Params{
+			"device": deviceId,
+			"type":   t.id,
+			"sensor": d.Id,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s sensor %d has no %s record", d.Type, d.Id, collections.SensorsCollectionName)
+		}
+		return err
+	}
+
+	return checkMetrics(d.Type, t.metrics, d.Metrics)
+}
+
+// storeReading queues one readings record per metric.
+func (a *Arduino) storeReading(deviceId string, kind string, sensorId int, metrics []*transporter.Metric) {
+	for _, m := range metrics {
+		record := core.NewRecord(a.getCollection(collections.ReadingsCollectionName))
+		record.Set("device", deviceId)
+		record.Set("type", kind)
+		record.Set("sensor_id", sensorId)
+		record.Set("metric", m.Name)
+		record.Set("value", m.Value)
+
+		if !a.ingest.Enqueue(record) {
+			a.app.Logger().Debug("sensor reading dropped", slog.String("device_id", deviceId), slog.String("type", kind))
+		}
+	}
+}
+
+// sensorValidateHook checks the ports of a sensor against its sensor type.
+// Mirrors of legacy configs were checked by their config collection.
+func (a *Arduino) sensorValidateHook(e *core.RecordEvent) error {
+	record := e.Record
+	if record.GetString("legacy_config") != "" {
+		return e.Next()
+	}
+
+	t, err := findSensorType(e.App, "id = {:id}", dbx.Params{"id": record.GetString("type")})
+	if err != nil {
+		return fmt.Errorf("unknown sensor type: %w", err)
+	}
+
+	device, err := e.App.FindRecordById(collections.DevicesCollectionName, record.GetString("device"))
+	if err == nil && !supportsSensor(device, t.name) {
+		return fmt.Errorf("device does not support %s sensors", t.name)
+	}
+
+	var ports map[string]int
+	if err := record.UnmarshalJSONField("ports", &ports); err != nil {
+		return fmt.Errorf("ports must map port names to port numbers: %w", err)
+	}
+	for name, port := range ports {
+		if !slices.ContainsFunc(t.ports, func(p collections.SensorPort) bool { return p.Name == name }) {
+			return fmt.Errorf("%s sensors have no port %q", t.name, name)
+		}
+		if port < 0 {
+			return fmt.Errorf("port %s must not be negative", name)
+		}
+	}
+	for _, p := range t.ports {
+		if _, ok := ports[p.Name]; p.Required && !ok {
+			return fmt.Errorf("%s sensors require the %s port", t.name, p.Name)
+		}
+	}
+
+	others, err := e.App.FindRecordsByFilter(
+		collections.SensorsCollectionName,
+		"device = {:device} && id != {:id}",
+		"",
+		0,
+		0,
+		dbx.Params{"device": record.GetString("device"), "id": record.Id},
+	)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		var used map[string]int
+		_ = other.UnmarshalJSONField("ports", &used)
+		for name, port := range ports {
+			for _, otherPort := range used {
+				if port == otherPort {
+					return fmt.Errorf("port %d of %s is already used by sensor %s", port, name, other.GetString("lable"))
+				}
+			}
+		}
+	}
+
+	return e.Next()
+}
+
+// sensorConfigHook sends the config of a generic sensor to devices that
+// announced the sensors feature. Everything else is configured through the
+// legacy config collections.
+func (a *Arduino) sensorConfigHook(e *core.RecordEvent) error {
+	if err := e.Next(); err != nil {
+		return err
+	}
+
+	record := e.Record
+	deviceId := record.GetString("device")
+	if record.GetString("legacy_config") != "" || !a.deviceSupportsSensors(deviceId) {
+		return nil
+	}
+
+	t, err := findSensorType(a.app, "id = {:id}", dbx.Params{"id": record.GetString("type")})
+	if err != nil {
+		a.app.Logger().Error("failed to find sensor type", slog.String("error", err.Error()))
+		return nil
+	}
+
+	c := &transporter.SensorConfig{
+		Id:       uint32(record.GetInt("sensor_id")),
+		Type:     t.name,
+		Ports:    map[string]uint32{},
+		Settings: map[string]float64{},
+	}
+	var ports map[string]int
+	_ = record.UnmarshalJSONField("ports", &ports)
+	for name, port := range ports {
+		c.Ports[name] = uint32(port)
+	}
+	_ = record.UnmarshalJSONField("settings", &c.Settings)
+
+	a.publishSensor(deviceId, fmt.Sprintf("arduino/%s/sensor/config", deviceId), c)
+	return nil
+}
+
+func (a *Arduino) sensorRemoveHook(e *core.RecordEvent) error {
+	if err := e.Next(); err != nil {
+		return err
+	}
+
+	record := e.Record
+	deviceId := record.GetString("device")
+	if record.GetString("legacy_config") != "" || !a.deviceSupportsSensors(deviceId) {
+		return nil
+	}
+
+	t, err := findSensorType(a.app, "id = {:id}", dbx.Params{"id": record.GetString("type")})
+	if err != nil {
+		a.app.Logger().Error("failed to find sensor type", slog.String("error", err.Error()))
+		return nil
+	}
+
+	a.publishSensor(deviceId, fmt.Sprintf("arduino/%s/sensor/remove", deviceId), &transporter.SensorRemoval{
+		Id:   uint32(record.GetInt("sensor_id")),
+		Type: t.name,
+	})
+	return nil
+}
+
+func (a *Arduino) publishSensor(deviceId string, topic string, m proto.Message) {
+	topic, payload, err := a.marshal(deviceId, topic, m)
+	if err != nil {
+		a.app.Logger().Error("failed to marshal sensor config", slog.String("error", err.Error()))
+		return
+	}
+	if err := a.publish(topic, payload, false, 1); err != nil {
+		a.app.Logger().Error("failed to publish sensor config", slog.String("error", err.Error()))
+		return
+	}
+
+	a.app.Logger().Info("published sensor config", slog.String("topic", topic), slog.String("device_id", deviceId))
+}
+
+// deviceSupportsSensors reports whether a device announced the sensors
+// feature. Unlike the other features, boards that never announced their
+// capabilities do not get generic sensor configs.
+func (a *Arduino) deviceSupportsSensors(deviceId string) bool {
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	if err != nil {
+		return false
+	}
+	c, ok := deviceCapabilities(device)
+	return ok && slices.Contains(c.Features, featureSensors)
+}
+
+// legacySensorHook keeps the mirror of a legacy sensor config in the
+// sensors collection up to date.
+func (a *Arduino) legacySensorHook(e *core.RecordEvent) error {
+	if err := e.Next(); err != nil {
+		return err
+	}
+
+	if _, err := mirrorLegacySensor(e.App, e.Record); err != nil {
+		a.app.Logger().Error("failed to mirror sensor config", slog.String("config", e.Record.Id), slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+func (a *Arduino) legacySensorRemoveHook(e *core.RecordEvent) error {
+	if err := e.Next(); err != nil {
+		return err
+	}
+
+	if err := deleteLegacySensor(e.App, e.Record); err != nil {
+		a.app.Logger().Error("failed to remove sensor mirror", slog.String("config", e.Record.Id), slog.String("error", err.Error()))
+	}
+	return nil
+}
+
+// legacySensorType maps a legacy config collection to its sensor type.
+func legacySensorType(collection string) string {
+	switch collection {
+	case collections.ClimateConfigCollectionName:
+		return sensorClimate
+	case collections.LDRConfigCollectionName:
+		return sensorLDR
+	case collections.MotionConfigCollectionName:
+		return sensorMotion
+	}
+	return ""
+}
+
+// mirrorLegacySensor creates or updates the sensors record of a legacy
+// sensor config. It reports whether the record was created.
+func mirrorLegacySensor(app core.App, config *core.Record) (bool, error) {
+	kind := legacySensorType(config.Collection().Name)
+	t, err := findSensorType(app, "name = {:name}", dbx.Params{"name": kind})
+	if err != nil {
+		return false, fmt.Errorf("sensor type %s: %w", kind, err)
+	}
+
+	created := false
+	record, err := app.FindFirstRecordByFilter(
+		collections.SensorsCollectionName,
+		"legacy_config = {:config}",
+		dbx.Params{"config": config.Id},
+	)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return false, err
+		}
+		sensors, err := app.FindCollectionByNameOrId(collections.SensorsCollectionName)
+		if err != nil {
+			return false, err
+		}
+		record = core.NewRecord(sensors)
+		record.Set("legacy_config", config.Id)
+		created = true
+	}
+
+	ports := map[string]int{}
+	settings := map[string]float64{}
+	switch kind {
+	case sensorClimate:
+		ports["dht22"] = config.GetInt("dht22_port")
+		ports["aqi"] = config.GetInt("aqi_port")
+		if config.GetBool("has_buzzer") {
+			ports["buzzer"] = config.GetInt("buzzer_port")
+		}
+	case sensorLDR:
+		ports["signal"] = config.GetInt("port")
+	case sensorMotion:
+		ports["signal"] = config.GetInt("port")
+		ports["relay"] = config.GetInt("relay_port")
+		settings["relay_type"] = float64(config.GetInt("relay_type"))
+	}
+
+	record.Set("device", config.GetString("device"))
+	record.Set("type", t.id)
+	record.Set("sensor_id", config.GetInt("sensor_id"))
+	record.Set("lable", config.GetString("lable"))
+	record.Set("room", config.GetString("room"))
+	record.Set("ports", ports)
+	record.Set("settings", settings)
+
+	return created, app.Save(record)
+}
+
+func deleteLegacySensor(app core.App, config *core.Record) error {
+	record, err := app.FindFirstRecordByFilter(
+		collections.SensorsCollectionName,
+		"legacy_config = {:config}",
+		dbx.Params{"config": config.Id},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	return app.Delete(record)
+}
+
+// MigrateSensors mirrors the legacy climate, LDR and motion configs into the
+// sensors collection and copies the readings of newly mirrored sensors into
+// the readings collection. It runs on every start and skips what was
+// migrated before.
+func MigrateSensors(app core.App) error {
+	return app.RunInTransaction(func(txApp core.App) error {
+		for _, collection := range []string{
+			collections.ClimateConfigCollectionName,
+			collections.LDRConfigCollectionName,
+			collections.MotionConfigCollectionName,
+		} {
+			configs, err := txApp.FindAllRecords(collection)
+			if err != nil {
+				return err
+			}
+
+			kind := legacySensorType(collection)
+			for _, config := range configs {
+				created, err := mirrorLegacySensor(txApp, config)
+				if err != nil {
+					return err
+				}
+				if !created {
+					continue
+				}
+
+				for _, t := range legacyTelemetry[kind] {
+					_, err := txApp.DB().NewQuery(fmt.Sprintf(
+						"INSERT INTO {{%s}} ([[device]], [[type]], [[sensor_id]], [[metric]], [[value]], [[timestamp]]) "+
+							"SELECT [[device]], {:type}, [[sensor_id]], {:metric}, [[%s]], [[timestamp]] FROM {{%s}} "+
+							"WHERE [[device]] = {:device} AND [[sensor_id]] = {:sensor}",
+						collections.ReadingsCollectionName, t.column, t.collection,
+					)).Bind(dbx.Params{
+						"type":   kind,
+						"metric": t.metric,
+						"device": config.GetString("device"),
+						"sensor": config.GetInt("sensor_id"),
+					}).Execute()
+					if err != nil {
+						return err
+					}
+				}
+
+				app.Logger().Info("migrated sensor", slog.String("type", kind), slog.String("device_id", config.GetString("device")), slog.Int("sensor_id", config.GetInt("sensor_id")))
+			}
+		}
+
+		return nil
+	})
+}
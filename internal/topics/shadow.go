@@ -0,0 +1,500 @@
+package topics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"coderero.dev/iot/smaas-server/internal/registry"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	relayLowDuty   = "relaylowduty001"
+	relayHeavyDuty = "relayheavyduty1"
+)
+
+const (
+	sensorClimate = "climate"
+	sensorLDR     = "ldr"
+	sensorMotion  = "motion"
+)
+
+// errStaleReport is returned for reports of a shadow version older than
+// the one the device already reported.
+var errStaleReport = errors.New("stale shadow report")
+
+// shadowState is the desired or reported section of a shadow. Relays are
+// keyed by relay and port, sensors by kind and sensor id.
+type shadowState struct {
+	Relays  map[string]bool         `json:"relays"`
+	Sensors map[string]sensorConfig `json:"sensors"`
+}
+
+type sensorConfig struct {
+	Port      uint32 `json:"port,omitempty"`
+	Dht22Port uint32 `json:"dht22_port,omitempty"`
+	AqiPort   uint32 `json:"aqi_port,omitempty"`
+	HasBuzzer bool   `json:"has_buzzer,omitempty"`
+	// BuzzerPort is only used by climate sensors with a buzzer
+	BuzzerPort uint32 `json:"buzzer_port,omitempty"`
+	RelayPort  uint32 `json:"relay_port,omitempty"`
+	RelayType  int    `json:"relay_type,omitempty"`
+}
+
+// shadowDelta is what the device has to change to reach the desired state.
+type shadowDelta struct {
+	Relays  map[string]bool         `json:"relays"`
+	Sensors map[string]sensorConfig `json:"sensors"`
+	Removed []string                `json:"removed"`
+}
+
+func newShadowState() shadowState {
+	return shadowState{
+		Relays:  map[string]bool{},
+		Sensors: map[string]sensorConfig{},
+	}
+}
+
+func (d shadowDelta) empty() bool {
+	return len(d.Relays) == 0 && len(d.Sensors) == 0 && len(d.Removed) == 0
+}
+
+func relayKey(relayId string, port int) string {
+	return fmt.Sprintf("%s:%d", relayId, port)
+}
+
+func sensorKey(kind string, id int) string {
+	return fmt.Sprintf("%s:%d", kind, id)
+}
+
+func splitKey(key string) (string, uint32) {
+	name, id, _ := strings.Cut(key, ":")
+	n, _ := strconv.ParseUint(id, 10, 32)
+	return name, uint32(n)
+}
+
+func relayIdOf(t transporter.RelayType) string {
+	if t == transporter.RelayType_LOW_DUTY {
+		return relayLowDuty
+	}
+	return relayHeavyDuty
+}
+
+func relayTypeOf(relayId string) transporter.RelayType {
+	if relayId == relayLowDuty {
+		return transporter.RelayType_LOW_DUTY
+	}
+	return transporter.RelayType_HEAVY_DUTY
+}
+
+// computeDelta returns the relays and sensors whose desired state differs
+// from the reported one, and the sensors the device has to remove.
+func computeDelta(desired, reported shadowState) shadowDelta {
+	delta := shadowDelta{
+		Relays:  map[string]bool{},
+		Sensors: map[string]sensorConfig{},
+		Removed: []string{},
+	}
+
+	for key, state := range desired.Relays {
+		if current, ok := reported.Relays[key]; !ok || current != state {
+			delta.Relays[key] = state
+		}
+	}
+	for key, config := range desired.Sensors {
+		if current, ok := reported.Sensors[key]; !ok || current != config {
+			delta.Sensors[key] = config
+		}
+	}
+	for key := range reported.Sensors {
+		if _, ok := desired.Sensors[key]; !ok {
+			delta.Removed = append(delta.Removed, key)
+		}
+	}
+	sort.Strings(delta.Removed)
+
+	return delta
+}
+
+// desiredState builds the desired state of a device from its relay ports
+// and sensor configs.
+func (a *Arduino) desiredState(deviceId string) (shadowState, error) {
+	state := newShadowState()
+	params := dbx.HashExp{"device": deviceId}
+
+	ports, err := a.app.FindAllRecords(collections.UserPortLablesCollectionName, params)
+	if err != nil {
+		return state, err
+	}
+	for _, port := range ports {
+		state.Relays[relayKey(port.GetString("relay"), port.GetInt("port"))] = port.GetBool("state")
+	}
+
+	climates, err := a.app.FindAllRecords(collections.ClimateConfigCollectionName, params)
+	if err != nil {
+		return state, err
+	}
+	for _, record := range climates {
+		state.Sensors[sensorKey(sensorClimate, record.GetInt("sensor_id"))] = sensorConfig{
+			Dht22Port:  uint32(record.GetInt("dht22_port")),
+			AqiPort:    uint32(record.GetInt("aqi_port")),
+			HasBuzzer:  record.GetBool("has_buzzer"),
+			BuzzerPort: uint32(record.GetInt("buzzer_port")),
+		}
+	}
+
+	ldrs, err := a.app.FindAllRecords(collections.LDRConfigCollectionName, params)
+	if err != nil {
+		return state, err
+	}
+	for _, record := range ldrs {
+		state.Sensors[sensorKey(sensorLDR, record.GetInt("sensor_id"))] = sensorConfig{
+			Port: uint32(record.GetInt("port")),
+		}
+	}
+
+	motions, err := a.app.FindAllRecords(collections.MotionConfigCollectionName, params)
+	if err != nil {
+		return state, err
+	}
+	for _, record := range motions {
+		state.Sensors[sensorKey(sensorMotion, record.GetInt("sensor_id"))] = sensorConfig{
+			Port:      uint32(record.GetInt("port")),
+			RelayPort: uint32(record.GetInt("relay_port")),
+			RelayType: record.GetInt("relay_type"),
+		}
+	}
+
+	return state, nil
+}
+
+// findShadow returns the shadow of a device, or a new unsaved one.
+func (a *Arduino) findShadow(deviceId string) (*core.Record, error) {
+	shadow, err := a.app.FindFirstRecordByData(collections.DeviceShadowsCollectionName, "device", deviceId)
+	if err == nil {
+		return shadow, nil
+	}
+
+	if _, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId); err != nil {
+		return nil, err
+	}
+
+	shadow = core.NewRecord(a.getCollection(collections.DeviceShadowsCollectionName))
+	shadow.Set("device", deviceId)
+	shadow.Set("reported", newShadowState())
+	return shadow, nil
+}
+
+func shadowStates(shadow *core.Record) (shadowState, shadowState) {
+	desired, reported := newShadowState(), newShadowState()
+	_ = shadow.UnmarshalJSONField("desired", &desired)
+	_ = shadow.UnmarshalJSONField("reported", &reported)
+	return desired, reported
+}
+
+// syncShadow rebuilds the desired state of a device and raises the version
+// when it changed. It reports whether the device has to be sent the delta.
+func (a *Arduino) syncShadow(deviceId string) (bool, error) {
+	a.shadowMu.Lock()
+	defer a.shadowMu.Unlock()
+
+	shadow, err := a.findShadow(deviceId)
+	if err != nil {
+		return false, err
+	}
+
+	desired, err := a.desiredState(deviceId)
+	if err != nil {
+		return false, err
+	}
+
+	previous, reported := shadowStates(shadow)
+	if !shadow.IsNew() && reflect.DeepEqual(previous, desired) {
+		return false, nil
+	}
+
+	delta := computeDelta(desired, reported)
+	shadow.Set("desired", desired)
+	shadow.Set("delta", delta)
+	shadow.Set("version", shadow.GetInt("version")+1)
+	if err := a.app.Save(shadow); err != nil {
+		return false, err
+	}
+
+	return !delta.empty(), nil
+}
+
+// updateReported applies a report of a device to its shadow. Reports of an
+// older version than the last reported one are rejected with
+// errStaleReport, reports without a version are always applied.
+func (a *Arduino) updateReported(deviceId string, version uint64, apply func(*shadowState)) error {
+	a.shadowMu.Lock()
+	defer a.shadowMu.Unlock()
+
+	shadow, err := a.findShadow(deviceId)
+	if err != nil {
+		return err
+	}
+
+	if version != 0 && version < uint64(shadow.GetInt("reported_version")) {
+		return errStaleReport
+	}
+
+	desired, reported := shadowStates(shadow)
+	apply(&reported)
+
+	shadow.Set("reported", reported)
+	shadow.Set("delta", computeDelta(desired, reported))
+	if version != 0 {
+		shadow.Set("reported_version", version)
+	}
+
+	return a.app.Save(shadow)
+}
+
+func (a *Arduino) ShadowReport(msg *registry.Message) {
+	pk, deviceId := msg.Packet, msg.DeviceId
+
+	var d transporter.ShadowReport
+	if err := msg.Decode(&d); err != nil {
+		a.app.Logger().Error("failed to unmarshal shadow report", slog.String("error", err.Error()))
+		a.reject(deviceId, 0, pk, fmt.Errorf("malformed payload: %w", err))
+		return
+	}
+
+	err := a.updateReported(deviceId, d.Version, func(reported *shadowState) {
+		*reported = newShadowState()
+		for _, relay := range d.Relays {
+			reported.Relays[relayKey(relayIdOf(relay.Type), int(relay.Port))] = relay.State == transporter.RelayStateType_ON
+		}
+		for _, c := range d.Config.GetClimates() {
+			reported.Sensors[sensorKey(sensorClimate, int(c.Id))] = sensorConfig{
+				Dht22Port:  c.Dht22Port,
+				AqiPort:    c.AqiPort,
+				HasBuzzer:  c.HasBuzzers,
+				BuzzerPort: c.BuzzerPort,
+			}
+		}
+		for _, l := range d.Config.GetLdrs() {
+			reported.Sensors[sensorKey(sensorLDR, int(l.Id))] = sensorConfig{Port: l.Port}
+		}
+		for _, m := range d.Config.GetMotions() {
+			relayType := 2
+			if m.RelayType == transporter.RelayType_LOW_DUTY {
+				relayType = 1
+			}
+			reported.Sensors[sensorKey(sensorMotion, int(m.Id))] = sensorConfig{
+				Port:      m.Port,
+				RelayPort: m.RelayPort,
+				RelayType: relayType,
+			}
+		}
+	})
+	if err != nil {
+		a.app.Logger().Error("failed to update reported shadow", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	a.app.Logger().Info("shadow reported", slog.String("device_id", deviceId), slog.Uint64("version", d.Version))
+}
+
+// shadowHook keeps the desired state in sync with the relay ports and
+// sensor configs and sends the delta to the device.
+func (a *Arduino) shadowHook(e *core.RecordEvent) error {
+	if err := e.Next(); err != nil {
+		return err
+	}
+
+	deviceId := e.Record.GetString("device")
+	if deviceId == "" {
+		return nil
+	}
+
+	changed, err := a.syncShadow(deviceId)
+	if err != nil {
+		// the shadow is removed together with its device
+		if _, findErr := a.app.FindRecordById(collections.DevicesCollectionName, deviceId); findErr == nil {
+			a.app.Logger().Error("failed to sync shadow", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		}
+		return nil
+	}
+
+	if changed {
+		a.PushShadowDelta(deviceId)
+	}
+
+	return nil
+}
+
+// PushShadowDelta sends the device what it still has to change to reach the
+// desired state, if it announced the shadow.
+func (a *Arduino) PushShadowDelta(deviceId string) {
+	device, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	if err != nil {
+		a.app.Logger().Error("failed to find device", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+	// devices that never announced the shadow get the per message topics
+	if !usesShadow(device) {
+		return
+	}
+
+	if _, err := a.syncShadow(deviceId); err != nil {
+		a.app.Logger().Error("failed to sync shadow", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	shadow, err := a.app.FindFirstRecordByData(collections.DeviceShadowsCollectionName, "device", deviceId)
+	if err != nil {
+		a.app.Logger().Error("failed to find shadow", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	var delta shadowDelta
+	if err := shadow.UnmarshalJSONField("delta", &delta); err != nil {
+		a.app.Logger().Error("failed to read shadow delta", slog.String("device_id", deviceId), slog.String("error", err.Error()))
+		return
+	}
+
+	version := uint64(shadow.GetInt("version"))
+	d := &transporter.ShadowDelta{
+		Version: version,
+		Config:  &transporter.FullConfig{},
+	}
+
+	relays := make([]string, 0, len(delta.Relays))
+	for key := range delta.Relays {
+		relays = append(relays, key)
+	}
+	sort.Strings(relays)
+	for _, key := range relays {
+		relayId, port := splitKey(key)
+		state := transporter.RelayStateType_OFF
+		if delta.Relays[key] {
+			state = transporter.RelayStateType_ON
+		}
+		d.Relays = append(d.Relays, &transporter.RelayState{
+			Type:    relayTypeOf(relayId),
+			Port:    port,
+			State:   state,
+			Version: version,
+		})
+	}
+
+	for key, config := range delta.Sensors {
+		kind, id := splitKey(key)
+		switch kind {
+		case sensorClimate:
+			d.Config.Climates = append(d.Config.Climates, &transporter.Climate{
+				Id:         id,
+				Dht22Port:  config.Dht22Port,
+				AqiPort:    config.AqiPort,
+				HasBuzzers: config.HasBuzzer,
+				BuzzerPort: config.BuzzerPort,
+			})
+		case sensorLDR:
+			d.Config.Ldrs = append(d.Config.Ldrs, &transporter.LDR{Id: id, Port: config.Port})
+		case sensorMotion:
+			relayType := transporter.RelayType_HEAVY_DUTY
+			if config.RelayType == 1 {
+				relayType = transporter.RelayType_LOW_DUTY
+			}
+			d.Config.Motions = append(d.Config.Motions, &transporter.Motion{
+				Id:        id,
+				Port:      config.Port,
+				RelayPort: config.RelayPort,
+				RelayType: relayType,
+			})
+		}
+	}
+
+	for _, key := range delta.Removed {
+		kind, id := splitKey(key)
+		switch kind {
+		case sensorClimate:
+			d.Removed = append(d.Removed, &transporter.ConfigRemoval{
+				Payload: &transporter.ConfigRemoval_Climate{Climate: &transporter.ClimateRemoval{Id: id}},
+			})
+		case sensorLDR:
+			d.Removed = append(d.Removed, &transporter.ConfigRemoval{
+				Payload: &transporter.ConfigRemoval_Ldr{Ldr: &transporter.LDRRemoval{Id: id}},
+			})
+		case sensorMotion:
+			d.Removed = append(d.Removed, &transporter.ConfigRemoval{
+				Payload: &transporter.ConfigRemoval_Motion{Motion: &transporter.MotionRemoval{Id: id}},
+			})
+		}
+	}
+
+	topic := fmt.Sprintf("arduino/%s/shadow/delta", deviceId)
+	topic, payload, err := a.marshal(deviceId, topic, d)
+	if err != nil {
+		a.app.Logger().Error("failed to marshal shadow delta", slog.String("error", err.Error()))
+		return
+	}
+
+	if err := a.publish(topic, payload, false, 1); err != nil {
+		a.app.Logger().Error("failed to publish shadow delta", slog.String("error", err.Error()))
+		return
+	}
+
+	a.app.Logger().Info("published shadow delta", slog.String("topic", topic), slog.String("device_id", deviceId), slog.Uint64("version", version))
+}
+
+// ShadowHook sends a device its shadow delta when it subscribes to the delta
+// topic, which is the first thing a device does after connecting. Devices
+// subscribing to the delta topic with the JSON suffix get it as JSON there.
+// Other clients subscribing, like the shared credentials, do not trigger
+// it.
+type ShadowHook struct {
+	mqtt.HookBase
+	arduino *Arduino
+}
+
+func NewShadowHook(arduino *Arduino) *ShadowHook {
+	return &ShadowHook{arduino: arduino}
+}
+
+func (h *ShadowHook) ID() string {
+	return "smaas-shadow"
+}
+
+func (h *ShadowHook) Provides(b byte) bool {
+	return bytes.Contains([]byte{
+		mqtt.OnSubscribed,
+	}, []byte{b})
+}
+
+func (h *ShadowHook) OnSubscribed(cl *mqtt.Client, pk packets.Packet, reasonCodes []byte) {
+	for i, sub := range pk.Filters {
+		if i >= len(reasonCodes) || reasonCodes[i] >= packets.ErrUnspecifiedError.Code {
+			continue
+		}
+
+		deviceId, ok := strings.CutPrefix(sub.Filter, "arduino/")
+		if !ok {
+			continue
+		}
+		deviceId, asJSON := strings.CutSuffix(deviceId, jsonSuffix)
+		deviceId, ok = strings.CutSuffix(deviceId, "/shadow/delta")
+		if !ok || deviceId == "" || deviceId != string(cl.Properties.Username) {
+			continue
+		}
+
+		// the delta is sent after the subscription is acknowledged, on the
+		// topic the device subscribed to
+		h.arduino.setJSONSuffix(deviceId, asJSON)
+		go h.arduino.PushShadowDelta(deviceId)
+	}
+}
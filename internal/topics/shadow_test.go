@@ -0,0 +1,107 @@
+package topics
+
+import (
+	"sync"
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/metrics"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// newTestArduino returns an Arduino on a fresh app with the collections and
+// a broker without listeners, and the topics the server published on.
+func newTestArduino(t *testing.T, defs ...collections.CollectionDefiner) (*Arduino, func() []string) {
+	t.Helper()
+
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	for _, c := range defs {
+		if err := app.SaveNoValidate(c.Schema()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := mqtt.New(&mqtt.Options{InlineClient: true})
+	var mu sync.Mutex
+	var topics []string
+	err := server.Subscribe("arduino/#", 1, func(cl *mqtt.Client, sub packets.Subscription, pk packets.Packet) {
+		mu.Lock()
+		defer mu.Unlock()
+		topics = append(topics, pk.TopicName)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Arduino{
+		app:           app,
+		collections:   defs,
+		mqttServer:    server,
+		publishErrors: metrics.NewRegistry().Counter("publish_errors", "", "topic"),
+	}
+	return a, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		published := topics
+		topics = nil
+		return published
+	}
+}
+
+func TestPushShadowDelta(t *testing.T) {
+	a, published := newTestArduino(t,
+		&collections.Devices{},
+		&collections.DeviceShadows{},
+		&collections.UserPortLables{},
+		&collections.ClimateConfig{},
+		&collections.LDRConfig{},
+		&collections.MotionConfig{},
+	)
+
+	tests := []struct {
+		name   string
+		device *core.Record
+		want   bool
+	}{
+		// boards that never announced anything get the legacy topics only
+		{name: "legacy", device: newDevice(0, capabilities{})},
+		{name: "without shadow", device: newDevice(2, capabilities{Features: []string{featureOta}})},
+		{name: "shadow", device: newDevice(2, capabilities{Features: []string{featureShadow}}), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := a.app.SaveNoValidate(tt.device); err != nil {
+				t.Fatal(err)
+			}
+			defer a.app.Delete(tt.device)
+
+			port := core.NewRecord(a.getCollection(collections.UserPortLablesCollectionName))
+			port.Set("device", tt.device.Id)
+			port.Set("relay", relayLowDuty)
+			port.Set("port", 1)
+			port.Set("state", true)
+			if err := a.app.SaveNoValidate(port); err != nil {
+				t.Fatal(err)
+			}
+			defer a.app.Delete(port)
+
+			a.PushShadowDelta(tt.device.Id)
+
+			got := published()
+			if pushed := len(got) > 0; pushed != tt.want {
+				t.Fatalf("published %v, want a delta %v", got, tt.want)
+			}
+			if tt.want && got[0] != "arduino/device1/shadow/delta" {
+				t.Errorf("published on %s", got[0])
+			}
+		})
+	}
+}
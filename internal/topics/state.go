@@ -0,0 +1,190 @@
+package topics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	mqtt "github.com/mochi-mqtt/server/v2"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// stateRoot is the root of the retained last-known state topics, which are
+// only published by the server.
+const stateRoot = "state"
+
+// relayState is the payload of state/{device_id}/relay/{port}.
+type relayState struct {
+	Relay   string `json:"relay"`
+	Port    int    `json:"port"`
+	State   bool   `json:"state"`
+	Lable   string `json:"lable"`
+	Updated string `json:"updated"`
+}
+
+// sensorState is the payload of state/{device_id}/{type}/{sensor_id}, with
+// the latest value of every metric of the sensor.
+type sensorState struct {
+	Type     string             `json:"type"`
+	SensorId int                `json:"sensor_id"`
+	Metrics  map[string]float64 `json:"metrics"`
+	Updated  string             `json:"updated"`
+}
+
+func stateTopic(deviceId string, parts ...string) string {
+	return strings.Join(append([]string{stateRoot, deviceId}, parts...), "/")
+}
+
+func relayStateTopic(deviceId string, port int) string {
+	return stateTopic(deviceId, "relay", fmt.Sprint(port))
+}
+
+func sensorStateTopic(deviceId string, kind string, sensorId int) string {
+	return stateTopic(deviceId, kind, fmt.Sprint(sensorId))
+}
+
+func (a *Arduino) publishState(topic string, v any) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		a.app.Logger().Error("failed to marshal state", slog.String("topic", topic), slog.String("error", err.Error()))
+		return
+	}
+
+	// the broker keeps the last message of every topic for new subscribers
+	if err := a.publish(topic, payload, true, 0); err != nil {
+		a.app.Logger().Error("failed to publish state", slog.String("topic", topic), slog.String("error", err.Error()))
+	}
+}
+
+// clearState removes the retained message of a topic.
+func (a *Arduino) clearState(topic string) {
+	if err := a.publish(topic, nil, true, 0); err != nil {
+		a.app.Logger().Error("failed to clear state", slog.String("topic", topic), slog.String("error", err.Error()))
+	}
+}
+
+func (a *Arduino) publishRelayState(record *core.Record) {
+	a.publishState(relayStateTopic(record.GetString("device"), record.GetInt("port")), relayState{
+		Relay:   record.GetString("relay"),
+		Port:    record.GetInt("port"),
+		State:   record.GetBool("state"),
+		Lable:   record.GetString("lable"),
+		Updated: types.NowDateTime().String(),
+	})
+}
+
+// PublishRelayStates publishes the state of the relay ports of every device
+// that is not decommissioned, for the ports changed before the state topics
+// were kept.
+func (a *Arduino) PublishRelayStates() {
+	records, err := a.app.FindRecordsByFilter(
+		collections.UserPortLablesCollectionName,
+		"device.deleted_at = ''",
+		"",
+		0,
+		0,
+	)
+	if err != nil {
+		a.app.Logger().Error("failed to find relay ports", slog.String("error", err.Error()))
+		return
+	}
+
+	for _, record := range records {
+		a.publishRelayState(record)
+	}
+}
+
+func (a *Arduino) relayStateHook(e *core.RecordEvent) error {
+	a.publishRelayState(e.Record)
+	return e.Next()
+}
+
+func (a *Arduino) relayStateRemoveHook(e *core.RecordEvent) error {
+	a.clearState(relayStateTopic(e.Record.GetString("device"), e.Record.GetInt("port")))
+	return e.Next()
+}
+
+// readingStateHook adds a stored reading to the state of its sensor. The
+// metrics of a reading are stored one by one, so the state is merged with
+// the retained message.
+func (a *Arduino) readingStateHook(e *core.RecordEvent) error {
+	record := e.Record
+	topic := sensorStateTopic(record.GetString("device"), record.GetString("type"), record.GetInt("sensor_id"))
+
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+
+	state := sensorState{
+		Type:     record.GetString("type"),
+		SensorId: record.GetInt("sensor_id"),
+		Metrics:  map[string]float64{},
+	}
+	if pk, ok := a.mqttServer.Topics.Retained.Get(topic); ok {
+		var retained sensorState
+		if err := json.Unmarshal(pk.Payload, &retained); err == nil && retained.Metrics != nil {
+			state.Metrics = retained.Metrics
+		}
+	}
+
+	state.Metrics[record.GetString("metric")] = record.GetFloat("value")
+	state.Updated = record.GetDateTime("timestamp").String()
+	a.publishState(topic, state)
+
+	return e.Next()
+}
+
+func (a *Arduino) sensorStateRemoveHook(e *core.RecordEvent) error {
+	record := e.Record
+	t, err := findSensorType(e.App, "id = {:id}", dbx.Params{"id": record.GetString("type")})
+	if err != nil {
+		a.app.Logger().Error("failed to find sensor type", slog.String("error", err.Error()))
+		return e.Next()
+	}
+
+	a.clearState(sensorStateTopic(record.GetString("device"), t.name, record.GetInt("sensor_id")))
+	return e.Next()
+}
+
+// deviceStateHook clears every state topic of a device once it is
+// decommissioned or deleted.
+func (a *Arduino) deviceStateHook(e *core.RecordEvent) error {
+	device := e.Record
+	if e.Type == core.ModelEventTypeUpdate && device.GetDateTime("deleted_at").IsZero() {
+		return e.Next()
+	}
+
+	for _, pk := range a.mqttServer.Topics.Messages(stateTopic(device.Id, "#")) {
+		a.clearState(pk.TopicName)
+	}
+	return e.Next()
+}
+
+// StateHook publishes the relay states once the broker has loaded the
+// retained messages it stored.
+type StateHook struct {
+	mqtt.HookBase
+	arduino *Arduino
+}
+
+func NewStateHook(arduino *Arduino) *StateHook {
+	return &StateHook{arduino: arduino}
+}
+
+func (h *StateHook) ID() string {
+	return "smaas-state"
+}
+
+func (h *StateHook) Provides(b byte) bool {
+	return bytes.Contains([]byte{
+		mqtt.OnStarted,
+	}, []byte{b})
+}
+
+func (h *StateHook) OnStarted() {
+	h.arduino.PublishRelayStates()
+}
@@ -0,0 +1,157 @@
+package topics
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+var errUnknownDevice = errors.New("unknown device")
+
+// climateMetrics are the metrics of a climate message as stored in readings.
+func climateMetrics(d *transporter.ClimateData) []*transporter.Metric {
+	return []*transporter.Metric{
+		{Name: "temperature", Value: float64(d.Temperature)},
+		{Name: "humidity", Value: float64(d.Humidity)},
+		{Name: "air_quality", Value: float64(d.Aqi)},
+	}
+}
+
+// ldrMetrics are the metrics of an LDR message as stored in readings.
+func ldrMetrics(d *transporter.LDRData) []*transporter.Metric {
+	return []*transporter.Metric{
+		{Name: "light", Value: float64(d.Value)},
+	}
+}
+
+func (a *Arduino) validateClimate(deviceId string, d *transporter.ClimateData) error {
+	if err := a.validateSensor(deviceId, collections.ClimateConfigCollectionName, int(d.Id)); err != nil {
+		return err
+	}
+
+	return checkMetrics(sensorClimate, defaultMetrics(sensorClimate), climateMetrics(d))
+}
+
+func (a *Arduino) validateLDR(deviceId string, d *transporter.LDRData) error {
+	if err := a.validateSensor(deviceId, collections.LDRConfigCollectionName, int(d.Id)); err != nil {
+		return err
+	}
+
+	return checkMetrics(sensorLDR, defaultMetrics(sensorLDR), ldrMetrics(d))
+}
+
+// defaultMetrics returns the metrics of a default sensor type. The legacy
+// messages are checked against them, as their sensors are not configured
+// in the catalog.
+func defaultMetrics(kind string) []collections.SensorMetric {
+	for _, t := range collections.DefaultSensorTypes {
+		if t.TypeName == kind {
+			return t.Metrics
+		}
+	}
+	return nil
+}
+
+// checkMetrics checks the values of a reading against the metrics of its
+// sensor type.
+func checkMetrics(kind string, metrics []collections.SensorMetric, values []*transporter.Metric) error {
+	if len(values) == 0 {
+		return fmt.Errorf("reading has no metrics")
+	}
+
+	seen := make(map[string]bool, len(values))
+	for _, m := range values {
+		i := slices.IndexFunc(metrics, func(metric collections.SensorMetric) bool { return metric.Name == m.Name })
+		if i < 0 {
+			return fmt.Errorf("%s sensors have no metric %q", kind, m.Name)
+		}
+		if seen[m.Name] {
+			return fmt.Errorf("metric %s is reported twice", m.Name)
+		}
+		seen[m.Name] = true
+
+		metric := metrics[i]
+		if math.IsNaN(m.Value) || math.IsInf(m.Value, 0) {
+			return fmt.Errorf("%s is not a number", m.Name)
+		}
+		if m.Value < metric.Min || m.Value > metric.Max {
+			return fmt.Errorf("%s %.2f out of range [%g, %g]", m.Name, m.Value, metric.Min, metric.Max)
+		}
+	}
+
+	return nil
+}
+
+// validateSensor checks that the device exists and has the sensor registered
+// in the given config collection.
+func (a *Arduino) validateSensor(deviceId string, configCollection string, sensorId int) error {
+	if !a.deviceExists(deviceId) {
+		return errUnknownDevice
+	}
+
+	_, err := a.app.FindFirstRecordByFilter(
+		configCollection,
+		"device = {:device} && sensor_id = {:sensor}",
+		dbx.Params{
+			"device": deviceId,
+			"sensor": sensorId,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("sensor %d has no %s record", sensorId, configCollection)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// reject quarantines the raw message in the rejected_messages collection.
+func (a *Arduino) reject(deviceId string, sensorId int, pk packets.Packet, reason error) {
+	a.app.Logger().Warn(
+		"rejected device message",
+		slog.String("topic", pk.TopicName),
+		slog.String("device_id", deviceId),
+		slog.String("reason", reason.Error()),
+	)
+
+	record := core.NewRecord(a.getCollection(collections.RejectedMessagesCollectionName))
+	if !errors.Is(reason, errUnknownDevice) && a.deviceExists(deviceId) {
+		record.Set("device", deviceId)
+	}
+	record.Set("sensor_id", sensorId)
+	record.Set("topic", pk.TopicName)
+	record.Set("payload", base64.StdEncoding.EncodeToString(pk.Payload))
+	record.Set("reason", reason.Error())
+
+	if !a.ingest.Enqueue(record) {
+		a.app.Logger().Debug("rejected message dropped", slog.String("device_id", deviceId))
+	}
+}
+
+// deviceExists reports whether the device exists, even if decommissioned.
+// Devices found are cached as they are needed for every message, and dropped
+// again by deviceCacheHook. Misses are not cached, any client id can be sent.
+func (a *Arduino) deviceExists(deviceId string) bool {
+	if _, ok := a.devices.Load(deviceId); ok {
+		return true
+	}
+
+	if _, err := a.app.FindRecordById(collections.DevicesCollectionName, deviceId); err != nil {
+		return false
+	}
+
+	a.devices.Store(deviceId, struct{}{})
+	return true
+}
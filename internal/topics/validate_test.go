@@ -0,0 +1,104 @@
+package topics
+
+import (
+	"math"
+	"testing"
+
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"coderero.dev/iot/smaas-server/internal/proto/transporter"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestCheckMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		kind    string
+		metrics []*transporter.Metric
+		err     bool
+	}{
+		{
+			name:    "climate",
+			kind:    sensorClimate,
+			metrics: climateMetrics(&transporter.ClimateData{Temperature: -40, Humidity: 100, Aqi: 500}),
+		},
+		{
+			name:    "climate too hot",
+			kind:    sensorClimate,
+			metrics: climateMetrics(&transporter.ClimateData{Temperature: 80.5, Humidity: 50}),
+			err:     true,
+		},
+		{
+			name:    "climate humidity not a number",
+			kind:    sensorClimate,
+			metrics: climateMetrics(&transporter.ClimateData{Temperature: 20, Humidity: float32(math.NaN())}),
+			err:     true,
+		},
+		{
+			name:    "climate air quality out of range",
+			kind:    sensorClimate,
+			metrics: climateMetrics(&transporter.ClimateData{Temperature: 20, Humidity: 50, Aqi: 501}),
+			err:     true,
+		},
+		{name: "ldr", kind: sensorLDR, metrics: ldrMetrics(&transporter.LDRData{Value: 1023})},
+		{name: "ldr out of range", kind: sensorLDR, metrics: ldrMetrics(&transporter.LDRData{Value: 1024}), err: true},
+		{name: "no metrics", kind: sensorLDR, err: true},
+		{name: "unknown metric", kind: sensorLDR, metrics: []*transporter.Metric{{Name: "temperature", Value: 20}}, err: true},
+		{
+			name:    "metric reported twice",
+			kind:    sensorLDR,
+			metrics: []*transporter.Metric{{Name: "light", Value: 1}, {Name: "light", Value: 2}},
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMetrics(tt.kind, defaultMetrics(tt.kind), tt.metrics)
+			if (err != nil) != tt.err {
+				t.Errorf("checkMetrics = %v, want error %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDeviceExistsIsCached(t *testing.T) {
+	app := core.NewBaseApp(core.BaseAppConfig{DataDir: t.TempDir()})
+	if err := app.Bootstrap(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { app.ResetBootstrapState() })
+
+	if err := app.SaveNoValidate((&collections.Devices{}).Schema()); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &Arduino{app: app}
+	if a.deviceExists("device1") {
+		t.Fatal("unknown device exists")
+	}
+	// any client id can be sent, misses are not cached
+	if _, ok := a.devices.Load("device1"); ok {
+		t.Fatal("unknown device cached")
+	}
+
+	device := newDevice(0, capabilities{})
+	if err := app.SaveNoValidate(device); err != nil {
+		t.Fatal(err)
+	}
+	if !a.deviceExists("device1") {
+		t.Fatal("created device does not exist")
+	}
+
+	if err := app.Delete(device); err != nil {
+		t.Fatal(err)
+	}
+	if !a.deviceExists("device1") {
+		t.Fatal("device looked up again before the hook dropped it from the cache")
+	}
+	if err := a.deviceCacheHook(recordEvent(app, device)); err != nil {
+		t.Fatal(err)
+	}
+	if a.deviceExists("device1") {
+		t.Fatal("deleted device exists")
+	}
+}
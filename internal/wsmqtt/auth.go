@@ -0,0 +1,55 @@
+package wsmqtt
+
+import (
+	"coderero.dev/iot/smaas-server/internal/collections"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const usersCollectionId = "_pb_users_auth_"
+
+// Dashboards authenticates browser clients with their PocketBase auth token
+// and gives them the devices they can view through the API.
+type Dashboards struct {
+	app core.App
+}
+
+func NewDashboards(app core.App) *Dashboards {
+	return &Dashboards{app: app}
+}
+
+// AuthenticateToken returns the user of an auth token. Only users log in,
+// superusers use the shared credentials.
+func (d *Dashboards) AuthenticateToken(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+
+	record, err := d.app.FindAuthRecordByToken(token, core.TokenTypeAuth)
+	if err != nil || record.Collection().Id != usersCollectionId {
+		return "", false
+	}
+
+	return record.Id, true
+}
+
+// CanView applies the view rule of the devices collection, so owners, home
+// owners and members see the same devices as in the API.
+func (d *Dashboards) CanView(userId string, deviceId string) bool {
+	user, err := d.app.FindRecordById(usersCollectionId, userId)
+	if err != nil {
+		return false
+	}
+
+	device, err := d.app.FindRecordById(collections.DevicesCollectionName, deviceId)
+	if err != nil {
+		return false
+	}
+
+	ok, err := d.app.CanAccessRecord(device, &core.RequestInfo{
+		Context: core.RequestInfoContextRealtime,
+		Method:  "GET",
+		Auth:    user,
+	}, device.Collection().ViewRule)
+
+	return err == nil && ok
+}
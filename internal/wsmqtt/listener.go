@@ -0,0 +1,152 @@
+package wsmqtt
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+var ErrInvalidMessage = errors.New("message type not binary")
+
+// Listener is a WebSocket listener of the broker that does not listen on an
+// address of its own. Its Handler is mounted on the PocketBase HTTP server.
+type Listener struct {
+	id       string
+	path     string
+	upgrader websocket.Upgrader
+	log      *slog.Logger
+
+	mu        sync.RWMutex
+	establish listeners.EstablishFn
+	closed    bool
+}
+
+func NewListener(id string, path string) *Listener {
+	return &Listener{
+		id:   id,
+		path: path,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{"mqtt"},
+			// browsers authenticate with a token in the connect packet, not
+			// with cookies, so every origin may connect
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func (l *Listener) ID() string {
+	return l.id
+}
+
+func (l *Listener) Address() string {
+	return l.path
+}
+
+func (l *Listener) Protocol() string {
+	return "ws"
+}
+
+func (l *Listener) Init(log *slog.Logger) error {
+	l.log = log
+	return nil
+}
+
+// Serve only keeps the callback, the connections come in through Handler.
+func (l *Listener) Serve(establish listeners.EstablishFn) {
+	l.mu.Lock()
+	l.establish = establish
+	l.mu.Unlock()
+}
+
+func (l *Listener) Close(closeClients listeners.CloseFn) {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+
+	closeClients(l.id)
+}
+
+// Handler upgrades the request and hands the connection to the broker.
+func (l *Listener) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.mu.RLock()
+		establish, closed := l.establish, l.closed
+		l.mu.RUnlock()
+		if establish == nil || closed {
+			http.Error(w, "MQTT broker is not running", http.StatusServiceUnavailable)
+			return
+		}
+
+		c, err := l.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+
+		// the timeouts of the HTTP server would end every connection after a
+		// few minutes, the broker sets its own read deadlines
+		_ = c.UnderlyingConn().SetDeadline(time.Time{})
+
+		if err := establish(l.id, &conn{Conn: c.UnderlyingConn(), c: c}); err != nil {
+			l.log.Warn("", "error", err)
+		}
+	})
+}
+
+// conn is a WebSocket connection satisfying net.Conn, with every MQTT
+// packet in binary messages.
+type conn struct {
+	net.Conn
+	c *websocket.Conn
+
+	// reader of the current message, if any
+	r io.Reader
+}
+
+func (ws *conn) Read(p []byte) (int, error) {
+	if ws.r == nil {
+		op, r, err := ws.c.NextReader()
+		if err != nil {
+			return 0, err
+		}
+		if op != websocket.BinaryMessage {
+			return 0, ErrInvalidMessage
+		}
+		ws.r = r
+	}
+
+	var n int
+	for n < len(p) {
+		br, err := ws.r.Read(p[n:])
+		n += br
+		if err != nil {
+			// any error ends the current message
+			ws.r = nil
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+func (ws *conn) Write(p []byte) (int, error) {
+	if err := ws.c.WriteMessage(websocket.BinaryMessage, p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (ws *conn) Close() error {
+	return ws.Conn.Close()
+}